@@ -0,0 +1,23 @@
+package client
+
+import (
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// Conclude creates a `ucan/conclude` invocation that submits the passed
+// receipt to the audience, typically the service that delegated the task the
+// receipt was issued for. The receipt and the task are attached to the
+// invocation so it can be sent using [Execute].
+func Conclude(issuer ucan.Signer, audience ucan.Principal, rcpt receipt.AnyReceipt, options ...delegation.Option) (invocation.IssuedInvocation, error) {
+	return invocation.InvokeWithBlocks(
+		issuer,
+		audience,
+		ucan.NewCapability("ucan/conclude", issuer.DID().String(), sdm.ConcludeModel{Receipt: rcpt.Root().Link()}),
+		rcpt.Export(),
+		options...,
+	)
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// DryRun creates a `ucanto/dry-run` invocation that asks the audience whether
+// the passed invocation would be authorized, without executing it. The
+// invocation and its proofs are attached so it can be sent using [Execute].
+// The receipt for the dry run has the resolved authorization of the invocation
+// (see [sdm.DryRunOkModel]) or the error it would fail with.
+func DryRun(issuer ucan.Signer, audience ucan.Principal, inv invocation.Invocation, options ...delegation.Option) (invocation.IssuedInvocation, error) {
+	return invocation.InvokeWithBlocks(
+		issuer,
+		audience,
+		ucan.NewCapability("ucanto/dry-run", issuer.DID().String(), sdm.DryRunModel{Invocation: inv.Link()}),
+		inv.Export(),
+		options...,
+	)
+}
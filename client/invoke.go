@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime/schema"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/result"
+	fdm "github.com/storacha/go-ucanto/core/result/failure/datamodel"
+)
+
+// Invoke executes the passed invocation over the passed connection and binds
+// the result in the receipt to the Go types O and X using the passed schema
+// types.
+//
+// An error result that does not conform to the error type, such as a failure
+// to authorize the invocation, is returned as an error wrapping a
+// [fdm.FailureModel]. If X is [fdm.FailureModel] then error results are always
+// bound to it, and the error type may be nil.
+func Invoke[O, X any](ctx context.Context, conn Connection, inv invocation.Invocation, okType, errorType schema.Type) (result.Result[O, X], receipt.AnyReceipt, error) {
+	resp, err := Execute(ctx, []invocation.Invocation{inv}, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	rcptlnk, ok := resp.Get(inv.Link())
+	if !ok {
+		return nil, nil, fmt.Errorf("receipt not found for invocation: %s", inv.Link())
+	}
+	rcpt, err := receipt.NewAnyReceiptReader().Read(rcptlnk, resp.Blocks())
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading receipt: %w", err)
+	}
+
+	o, x := result.Unwrap(rcpt.Out())
+	if x == nil {
+		out, err := ipld.Rebind[O](o, okType)
+		if err != nil {
+			return nil, rcpt, fmt.Errorf("binding ok result: %w", err)
+		}
+		return result.Ok[O, X](out), rcpt, nil
+	}
+
+	var out X
+	if fm, ok := any(&out).(*fdm.FailureModel); ok {
+		*fm = fdm.Bind(x)
+		return result.Error[O](out), rcpt, nil
+	}
+	if errorType != nil {
+		if out, err := ipld.Rebind[X](x, errorType); err == nil {
+			return result.Error[O](out), rcpt, nil
+		}
+	}
+	f := fdm.Bind(x)
+	if f.Name != nil {
+		return nil, rcpt, fmt.Errorf("invocation failed: %s: %w", *f.Name, f)
+	}
+	return nil, rcpt, fmt.Errorf("invocation failed: %w", f)
+}
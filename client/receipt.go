@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/storacha/go-ucanto/core/car"
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal"
+	"github.com/storacha/go-ucanto/principal/ed25519/verifier"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// ReceiptAbility is the ability that authorizes a signer to issue receipts on
+// behalf of a service, for invocations of any ability.
+const ReceiptAbility = "ucan/receipt"
+
+// MaxReceiptSize is the maximum size in bytes of a receipt archive that will
+// be read by [FetchReceipt].
+const MaxReceiptSize = 4 << 20
+
+// FetchReceipt fetches the receipt for the passed invocation from a receipts
+// endpoint, as served by server.NewReceiptHandler. The invocation CID is
+// appended to the passed endpoint URL e.g. https://example.com/receipt/{cid}.
+// If client is nil then [http.DefaultClient] is used.
+//
+// It returns false if the server does not have a receipt for the invocation,
+// for example because it has not yet been executed.
+func FetchReceipt(ctx context.Context, client *http.Client, endpoint *url.URL, invocation ucan.Link) (receipt.AnyReceipt, bool, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.JoinPath(invocation.String()).String(), nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("creating HTTP request: %w", err)
+	}
+	req.Header.Set("Accept", car.ContentType)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("doing HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("fetching receipt for invocation %s: unexpected status: %d", invocation, res.StatusCode)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, MaxReceiptSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("reading receipt archive: %w", err)
+	}
+	if len(b) > MaxReceiptSize {
+		return nil, false, fmt.Errorf("receipt archive exceeds maximum size of %d bytes", MaxReceiptSize)
+	}
+
+	rcpt, err := receipt.Extract(b)
+	if err != nil {
+		return nil, false, fmt.Errorf("extracting receipt: %w", err)
+	}
+	if rcpt.Ran().Link().String() != invocation.String() {
+		return nil, false, fmt.Errorf("expected receipt for invocation %s, got %s", invocation, rcpt.Ran().Link())
+	}
+	return rcpt, true, nil
+}
+
+// VerifyReceipt verifies that the passed receipt was issued by the passed
+// service. The receipt must be signed by the service, or by a signer whose
+// authority to issue receipts on behalf of the service is proven by a chain of
+// delegations attached to the receipt as proofs. The chain must be rooted in a
+// delegation issued by the service, and every delegation in it must be valid
+// at the current time and delegate a capability on the service DID that
+// includes [ReceiptAbility] or the ability of the invocation the receipt was
+// issued for, such as "*". If the invocation is not included in the receipt
+// only [ReceiptAbility] or "*" are accepted.
+//
+// Signers other than the service must be did:key principals.
+func VerifyReceipt(rcpt receipt.AnyReceipt, service principal.Verifier) error {
+	if rcpt.Issuer() == nil || rcpt.Issuer().DID() == service.DID() {
+		return verifyReceiptSignature(rcpt, service)
+	}
+
+	issuer := rcpt.Issuer().DID()
+	vfr, err := verifier.Parse(issuer.String())
+	if err != nil {
+		return fmt.Errorf("parsing receipt issuer: %w", err)
+	}
+	if err := verifyReceiptSignature(rcpt, vfr); err != nil {
+		return err
+	}
+
+	var can ucan.Ability
+	if inv, ok := rcpt.Ran().Invocation(); ok && len(inv.Capabilities()) > 0 {
+		can = inv.Capabilities()[0].Can()
+	}
+
+	br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(rcpt.Blocks()))
+	if err != nil {
+		return fmt.Errorf("reading receipt blocks: %w", err)
+	}
+
+	var errs []error
+	for _, prf := range rcpt.Proofs() {
+		dlg, ok := prf.Delegation()
+		if !ok {
+			continue
+		}
+		err := verifyReceiptAuthority(dlg, issuer, service, can, br)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("receipt issuer %s is not authorized to issue receipts for %s: %w", issuer, service.DID(), errors.Join(errs...))
+}
+
+func verifyReceiptSignature(rcpt receipt.AnyReceipt, vfr principal.Verifier) error {
+	ok, err := rcpt.VerifySignature(vfr)
+	if err != nil {
+		return fmt.Errorf("verifying receipt signature: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("receipt signature does not verify as issuer %s", vfr.DID())
+	}
+	return nil
+}
+
+// verifyReceiptAuthority verifies that the passed delegation is part of a
+// chain that delegates authority to issue receipts for the service to the
+// passed audience.
+func verifyReceiptAuthority(dlg delegation.Delegation, audience did.DID, service principal.Verifier, can ucan.Ability, br blockstore.BlockReader) error {
+	if dlg.Audience().DID() != audience {
+		return fmt.Errorf("delegation %s audience %s is not %s", dlg.Link(), dlg.Audience().DID(), audience)
+	}
+	if err := validator.NotExpiredNotTooEarly(dlg); err != nil {
+		return err
+	}
+	if !delegatesService(dlg, service.DID(), can) {
+		return fmt.Errorf("delegation %s does not delegate a capability on %s", dlg.Link(), service.DID())
+	}
+
+	if dlg.Issuer().DID() == service.DID() {
+		if _, err := validator.VerifySignature(dlg, service); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	vfr, err := verifier.Parse(dlg.Issuer().DID().String())
+	if err != nil {
+		return fmt.Errorf("parsing delegation issuer: %w", err)
+	}
+	if _, err := validator.VerifySignature(dlg, vfr); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, prf := range delegation.NewProofsView(dlg.Proofs(), br) {
+		pdlg, ok := prf.Delegation()
+		if !ok {
+			errs = append(errs, fmt.Errorf("proof %s not found", prf.Link()))
+			continue
+		}
+		err := verifyReceiptAuthority(pdlg, dlg.Issuer().DID(), service, can, br)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("delegation %s is not issued by %s and has no proofs", dlg.Link(), service.DID())
+	}
+	return errors.Join(errs...)
+}
+
+// delegatesService returns true if the passed delegation delegates receipt
+// issuance on the service, or the passed ability if it is not empty.
+func delegatesService(dlg delegation.Delegation, service did.DID, can ucan.Ability) bool {
+	for _, cap := range dlg.Capabilities() {
+		if cap.With() != service.String() {
+			continue
+		}
+		if validator.ResolveAbility(cap.Can(), ReceiptAbility) != "" {
+			return true
+		}
+		if can != "" && validator.ResolveAbility(cap.Can(), can) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// Revoke creates a `ucan/revoke` invocation that revokes the passed delegation.
+// The issuer must be an issuer in the proof chain of the delegation. The
+// delegation and its proofs are attached to the invocation so it can be sent
+// using [Execute].
+func Revoke(issuer ucan.Signer, audience ucan.Principal, dlg delegation.Delegation, options ...delegation.Option) (invocation.IssuedInvocation, error) {
+	return invocation.InvokeWithBlocks(
+		issuer,
+		audience,
+		ucan.NewCapability("ucan/revoke", issuer.DID().String(), sdm.RevokeModel{Ucan: dlg.Link()}),
+		dlg.Export(),
+		options...,
+	)
+}
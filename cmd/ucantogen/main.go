@@ -0,0 +1,39 @@
+// Command ucantogen generates Go code for UCAN capabilities from an IPLD schema
+// and a capability manifest. See package codegen for the manifest format and
+// the code that is generated.
+//
+// It is intended to be run from a go:generate directive, for example:
+//
+//	//go:generate go run github.com/storacha/go-ucanto/cmd/ucantogen -manifest capabilities.json -out capabilities_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/storacha/go-ucanto/codegen"
+)
+
+func main() {
+	manifest := flag.String("manifest", "capabilities.json", "path of the capability manifest")
+	out := flag.String("out", "capabilities_gen.go", "path of the generated Go file")
+	flag.Parse()
+
+	if err := run(*manifest, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "ucantogen: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(manifest, out string) error {
+	m, schema, err := codegen.LoadManifest(manifest)
+	if err != nil {
+		return err
+	}
+	src, err := codegen.Generate(m, schema)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
@@ -0,0 +1,618 @@
+// Package codegen generates Go code for UCAN capabilities from an IPLD schema
+// and a [Manifest] describing the capabilities. For each capability it
+// generates:
+//
+//   - Go types for the caveats, ok and error schema types and the types they
+//     reference, with ToIPLD methods.
+//   - A capability parser, declaring the result types of the capability with
+//     validator.WithResultTypes.
+//   - A server option registering a typed handler for the capability.
+//   - A client function that invokes the capability and returns its decoded
+//     result.
+//
+// The ucantogen command (cmd/ucantogen) runs the generator, typically from a
+// go:generate directive.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	ipldprime "github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/schema"
+)
+
+// Header is the first line of generated files.
+const Header = "// Code generated by ucantogen. DO NOT EDIT."
+
+var imports = map[string]string{
+	"context":    "context",
+	"fmt":        "fmt",
+	"ipldprime":  "github.com/ipld/go-ipld-prime",
+	"ipldschema": "github.com/ipld/go-ipld-prime/schema",
+	"client":     "github.com/storacha/go-ucanto/client",
+	"delegation": "github.com/storacha/go-ucanto/core/delegation",
+	"ipld":       "github.com/storacha/go-ucanto/core/ipld",
+	"receipt":    "github.com/storacha/go-ucanto/core/receipt",
+	"result":     "github.com/storacha/go-ucanto/core/result",
+	"failure":    "github.com/storacha/go-ucanto/core/result/failure",
+	"fdm":        "github.com/storacha/go-ucanto/core/result/failure/datamodel",
+	"ok":         "github.com/storacha/go-ucanto/core/result/ok",
+	"udm":        "github.com/storacha/go-ucanto/core/result/ok/datamodel",
+	"schema":     "github.com/storacha/go-ucanto/core/schema",
+	"server":     "github.com/storacha/go-ucanto/server",
+	"ucan":       "github.com/storacha/go-ucanto/ucan",
+	"validator":  "github.com/storacha/go-ucanto/validator",
+}
+
+// Generate generates Go code for the capabilities in the passed manifest, whose
+// types are defined by the passed IPLD schema. The code is formatted.
+func Generate(m Manifest, schemaBytes []byte) ([]byte, error) {
+	if !token.IsIdentifier(m.Package) {
+		return nil, fmt.Errorf("invalid package name: %q", m.Package)
+	}
+	if len(m.Capabilities) == 0 {
+		return nil, fmt.Errorf("no capabilities in manifest")
+	}
+	ts, err := ipldprime.LoadSchemaBytes(schemaBytes)
+	if err != nil {
+		return nil, fmt.Errorf("loading schema: %w", err)
+	}
+
+	g := generator{
+		ts:       ts,
+		imports:  map[string]struct{}{},
+		idents:   map[string]string{},
+		enqueued: map[string]struct{}{},
+		builders: map[string]struct{}{},
+		failures: map[string]struct{}{},
+	}
+	for _, c := range m.Capabilities {
+		if err := g.capability(c); err != nil {
+			return nil, fmt.Errorf("capability %s: %w", c.Name, err)
+		}
+	}
+	for _, c := range m.Capabilities {
+		for _, name := range []string{c.Caveats, c.Ok} {
+			if _, ok := g.failures[name]; ok {
+				return nil, fmt.Errorf("capability %s: error type %s cannot be used as caveats or ok type", c.Name, name)
+			}
+		}
+	}
+	if err := g.types(); err != nil {
+		return nil, err
+	}
+	g.typeSystem(schemaBytes)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n\npackage %s\n\nimport (\n", Header, m.Package)
+	aliases := make([]string, 0, len(g.imports))
+	for alias := range g.imports {
+		aliases = append(aliases, alias)
+	}
+	slices.SortFunc(aliases, func(a, b string) int { return strings.Compare(imports[a], imports[b]) })
+	for i, alias := range aliases {
+		path := imports[alias]
+		if i > 0 && !strings.Contains(imports[aliases[i-1]], ".") && strings.Contains(path, ".") {
+			out.WriteString("\n")
+		}
+		if path == alias || strings.HasSuffix(path, "/"+alias) {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&out, "\t%s %q\n", alias, path)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(g.decls.Bytes())
+	out.Write(g.body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	ts      *schema.TypeSystem
+	imports map[string]struct{}
+	// idents are the generated package level identifiers, and what they were
+	// generated for
+	idents map[string]string
+	// queue holds the named schema types to generate Go types for
+	queue    []schema.Type
+	enqueued map[string]struct{}
+	// builders are the types that need a ToIPLD method
+	builders map[string]struct{}
+	// failures are the types that need Name and Error methods
+	failures map[string]struct{}
+	// decls are the type declarations, body the rest of the code
+	decls bytes.Buffer
+	body  bytes.Buffer
+}
+
+// use records that the generated code uses the package imported with the
+// passed alias, and returns the alias.
+func (g *generator) use(alias string) string {
+	g.imports[alias] = struct{}{}
+	return alias
+}
+
+func (g *generator) declare(ident, what string) error {
+	if prev, ok := g.idents[ident]; ok {
+		return fmt.Errorf("identifier %s for %s conflicts with %s", ident, what, prev)
+	}
+	g.idents[ident] = what
+	return nil
+}
+
+func (g *generator) lookup(name string) (schema.Type, error) {
+	t := g.ts.TypeByName(name)
+	if t == nil {
+		return nil, fmt.Errorf("type not found in schema: %s", name)
+	}
+	return t, nil
+}
+
+// resultType enqueues the named type of a capability caveats or result and
+// returns the Go expressions of the type and its schema type. If name is empty
+// the type is ok.Unit.
+func (g *generator) resultType(name string) (string, string, error) {
+	if name == "" {
+		return g.use("ok") + ".Unit", g.use("udm") + ".UnitType()", nil
+	}
+	t, err := g.lookup(name)
+	if err != nil {
+		return "", "", err
+	}
+	if isPrelude(t) || !token.IsExported(name) {
+		return "", "", fmt.Errorf("type %s must be an exported type defined by the schema", name)
+	}
+	g.enqueue(t)
+	g.builders[name] = struct{}{}
+	return name, name + "Type()", nil
+}
+
+func (g *generator) capability(c Capability) error {
+	if !token.IsIdentifier(c.Name) || !token.IsExported(c.Name) {
+		return fmt.Errorf("invalid capability name: %q", c.Name)
+	}
+	if c.Can == "" {
+		return fmt.Errorf("missing ability")
+	}
+	what := fmt.Sprintf("capability %s", c.Can)
+	for _, ident := range []string{c.Name, "With" + c.Name + "Handler", "Invoke" + c.Name} {
+		if err := g.declare(ident, what); err != nil {
+			return err
+		}
+	}
+
+	with, err := g.resource(c.With)
+	if err != nil {
+		return err
+	}
+	caveats, caveatsType, err := g.resultType(c.Caveats)
+	if err != nil {
+		return err
+	}
+	okType, okSchemaType, err := g.resultType(c.Ok)
+	if err != nil {
+		return err
+	}
+
+	var srvErrType, cliErrType string
+	errSchemaType := "nil"
+	if c.Error == "" {
+		srvErrType = g.use("failure") + ".IPLDBuilderFailure"
+		cliErrType = g.use("fdm") + ".FailureModel"
+	} else {
+		t, err := g.lookup(c.Error)
+		if err != nil {
+			return err
+		}
+		if err := checkFailure(t); err != nil {
+			return err
+		}
+		_, errSchemaType, err = g.resultType(c.Error)
+		if err != nil {
+			return err
+		}
+		srvErrType, cliErrType = c.Error, c.Error
+		g.failures[c.Error] = struct{}{}
+	}
+
+	b := &g.body
+	fmt.Fprintf(b, "\n// %s is the %q capability.\n", c.Name, c.Can)
+	fmt.Fprintf(b, "var %s = %s.NewCapability(\n", c.Name, g.use("validator"))
+	fmt.Fprintf(b, "\t%q,\n\t%s,\n", c.Can, with)
+	fmt.Fprintf(b, "\t%s.Struct[%s](%s, nil),\n", g.use("schema"), caveats, caveatsType)
+	fmt.Fprintf(b, "\tvalidator.DefaultDerives,\n")
+	fmt.Fprintf(b, "\tvalidator.WithResultTypes(%s, %s),\n)\n", okSchemaType, errSchemaType)
+
+	fmt.Fprintf(b, "\n// With%sHandler configures the handler for %q invocations.\n", c.Name, c.Can)
+	fmt.Fprintf(b, "func With%sHandler(handler %s.HandlerFunc[%s, %s, %s]) server.Option {\n", c.Name, g.use("server"), caveats, okType, srvErrType)
+	fmt.Fprintf(b, "\treturn server.WithServiceMethod(%s.Can(), server.Provide(%s, handler))\n}\n", c.Name, c.Name)
+
+	nbParam, nbArg := fmt.Sprintf(", nb %s", caveats), "nb"
+	if c.Caveats == "" {
+		nbParam, nbArg = "", "ok.Unit{}"
+	}
+	fmt.Fprintf(b, "\n// Invoke%s invokes %q on the service of the passed connection\n", c.Name, c.Can)
+	fmt.Fprintf(b, "// and returns the result, along with the receipt it was read from.\n")
+	fmt.Fprintf(b, "func Invoke%s(ctx %s.Context, conn %s.Connection, issuer %s.Signer, with ucan.Resource%s, options ...%s.Option) (%s.Result[%s, %s], %s.AnyReceipt, error) {\n",
+		c.Name, g.use("context"), g.use("client"), g.use("ucan"), nbParam, g.use("delegation"), g.use("result"), okType, cliErrType, g.use("receipt"))
+	fmt.Fprintf(b, "\tinv, err := %s.Invoke(issuer, conn.ID(), with, %s, options...)\n", c.Name, nbArg)
+	fmt.Fprintf(b, "\tif err != nil {\n\t\treturn nil, nil, err\n\t}\n")
+	if c.Error == "" {
+		fmt.Fprintf(b, "\treturn client.Invoke[%s, %s](ctx, conn, inv, %s, %s)\n}\n", okType, cliErrType, okSchemaType, errSchemaType)
+		return nil
+	}
+	fmt.Fprintf(b, "\tres, rcpt, err := client.Invoke[%s, %s](ctx, conn, inv, %s, %s)\n", okType, modelName(c.Error), okSchemaType, errSchemaType)
+	fmt.Fprintf(b, "\tif err != nil {\n\t\treturn nil, rcpt, err\n\t}\n")
+	fmt.Fprintf(b, "\treturn result.MapError(res, %s.failure), rcpt, nil\n}\n", modelName(c.Error))
+	return nil
+}
+
+// resource returns the expression for the reader of the capability resource.
+func (g *generator) resource(with string) (string, error) {
+	switch {
+	case with == "" || with == "did":
+		return g.use("schema") + ".DIDString()", nil
+	case strings.HasPrefix(with, "did:") && len(with) > len("did:"):
+		method := strings.TrimPrefix(with, "did:")
+		return fmt.Sprintf("%s.DIDString(schema.WithMethod(%q))", g.use("schema"), method), nil
+	}
+	return "", fmt.Errorf("unsupported resource: %q", with)
+}
+
+// checkFailure checks that the passed type can be used as a failure, i.e. it
+// is a struct with name and message string fields.
+func checkFailure(t schema.Type) error {
+	st, ok := t.(*schema.TypeStruct)
+	if !ok {
+		return fmt.Errorf("error type %s is not a struct", t.Name())
+	}
+	for _, name := range []string{"name", "message"} {
+		f := st.Field(name)
+		if f == nil || f.Type().TypeKind() != schema.TypeKind_String || f.IsNullable() {
+			return fmt.Errorf("error type %s must have a %s string field", t.Name(), name)
+		}
+	}
+	if st.Field("message").IsOptional() {
+		return fmt.Errorf("error type %s must have a required message field", t.Name())
+	}
+	return nil
+}
+
+func (g *generator) enqueue(t schema.Type) {
+	if _, ok := g.enqueued[t.Name()]; ok {
+		return
+	}
+	g.enqueued[t.Name()] = struct{}{}
+	g.queue = append(g.queue, t)
+}
+
+// types generates the Go types for the enqueued schema types, and the types
+// they reference.
+func (g *generator) types() error {
+	for len(g.queue) > 0 {
+		t := g.queue[0]
+		g.queue = g.queue[1:]
+		if err := g.declare(t.Name(), "type "+t.Name()); err != nil {
+			return err
+		}
+		def, err := g.definition(t)
+		if err != nil {
+			return fmt.Errorf("type %s: %w", t.Name(), err)
+		}
+		name := t.Name()
+		d := &g.decls
+		fmt.Fprintf(d, "\ntype %s %s\n", name, def)
+		if _, ok := g.failures[name]; ok {
+			if err := g.failure(t.(*schema.TypeStruct)); err != nil {
+				return fmt.Errorf("type %s: %w", t.Name(), err)
+			}
+			continue
+		}
+		if et, ok := t.(*schema.TypeEnum); ok {
+			fmt.Fprintf(d, "\nconst (\n")
+			for _, m := range et.Members() {
+				ident := name + exportName(m)
+				if err := g.declare(ident, "type "+name); err != nil {
+					return err
+				}
+				fmt.Fprintf(d, "\t%s %s = %q\n", ident, name, m)
+			}
+			fmt.Fprintf(d, ")\n")
+		}
+
+		if _, ok := g.builders[name]; ok {
+			if err := g.declare(name+"Type", "type "+name); err != nil {
+				return err
+			}
+			fmt.Fprintf(d, "\n// %sType returns the schema type of [%s].\n", name, name)
+			fmt.Fprintf(d, "func %sType() %s.Type {\n\treturn typeSystem.TypeByName(%q)\n}\n", name, g.use("ipldschema"), name)
+			fmt.Fprintf(d, "\nfunc (x %s) ToIPLD() (%s.Node, error) {\n\treturn ipld.WrapWithRecovery(&x, %sType())\n}\n", name, g.use("ipld"), name)
+		}
+	}
+	return nil
+}
+
+// failure generates the methods of a failure type, and the model type it is
+// converted to for binding.
+func (g *generator) failure(t *schema.TypeStruct) error {
+	name, model := t.Name(), modelName(t.Name())
+	for _, ident := range []string{name + "Type", model} {
+		if err := g.declare(ident, "type "+name); err != nil {
+			return err
+		}
+	}
+	def, err := g.structDefinition(t, schemaFieldName)
+	if err != nil {
+		return err
+	}
+	var toModel, fromModel strings.Builder
+	for _, f := range t.Fields() {
+		field, _ := g.fieldName(t, &f)
+		bound := exportName(f.Name())
+		fmt.Fprintf(&toModel, "\t\t%s: x.%s,\n", bound, field)
+		fmt.Fprintf(&fromModel, "\t\t%s: m.%s,\n", field, bound)
+	}
+	nameField, _ := g.fieldName(t, t.Field("name"))
+	msgField, _ := g.fieldName(t, t.Field("message"))
+
+	d := &g.decls
+	fmt.Fprintf(d, "\n// %sType returns the schema type of [%s].\n", name, name)
+	fmt.Fprintf(d, "func %sType() %s.Type {\n\treturn typeSystem.TypeByName(%q)\n}\n", name, g.use("ipldschema"), name)
+	fmt.Fprintf(d, "\nfunc (x %s) ToIPLD() (%s.Node, error) {\n", name, g.use("ipld"))
+	fmt.Fprintf(d, "\tm := %s{\n%s\t}\n", model, toModel.String())
+	fmt.Fprintf(d, "\treturn ipld.WrapWithRecovery(&m, %sType())\n}\n", name)
+	if t.Field("name").IsOptional() {
+		fmt.Fprintf(d, "\nfunc (x %s) Name() string {\n\tif x.%s == nil {\n\t\treturn \"\"\n\t}\n\treturn *x.%s\n}\n", name, nameField, nameField)
+	} else {
+		fmt.Fprintf(d, "\nfunc (x %s) Name() string {\n\treturn x.%s\n}\n", name, nameField)
+	}
+	fmt.Fprintf(d, "\nfunc (x %s) Error() string {\n\treturn x.%s\n}\n", name, msgField)
+	fmt.Fprintf(d, "\n// %s is bound to the schema type of [%s].\n", model, name)
+	fmt.Fprintf(d, "type %s %s\n", model, def)
+	fmt.Fprintf(d, "\nfunc (m %s) failure() %s {\n\treturn %s{\n%s\t}\n}\n", model, name, name, fromModel.String())
+	return nil
+}
+
+func (g *generator) typeSystem(schemaBytes []byte) {
+	b := &g.body
+	fmt.Fprintf(b, "\nvar typeSystem = mustLoadTypeSystem()\n")
+	fmt.Fprintf(b, "\nfunc mustLoadTypeSystem() *%s.TypeSystem {\n", g.use("ipldschema"))
+	fmt.Fprintf(b, "\tts, err := %s.LoadSchemaBytes([]byte(%s))\n", g.use("ipldprime"), quote(string(schemaBytes)))
+	fmt.Fprintf(b, "\tif err != nil {\n\t\tpanic(%s.Errorf(\"loading schema: %%w\", err))\n\t}\n\treturn ts\n}\n", g.use("fmt"))
+}
+
+func quote(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// definition returns the Go type definition for a named schema type, the
+// shape of which is determined by what bindnode binds the type to.
+func (g *generator) definition(t schema.Type) (string, error) {
+	switch t := t.(type) {
+	case *schema.TypeBool, *schema.TypeInt, *schema.TypeFloat, *schema.TypeString, *schema.TypeBytes:
+		return scalars[t.TypeKind()], nil
+	case *schema.TypeAny:
+		return "= " + g.use("ipld") + ".Node", nil
+	case *schema.TypeLink:
+		return "= " + g.use("ipld") + ".Link", nil
+	case *schema.TypeEnum:
+		return "string", nil
+	case *schema.TypeList, *schema.TypeMap:
+		return g.inline(t)
+	case *schema.TypeStruct:
+		return g.structDefinition(t, g.fieldName)
+	case *schema.TypeUnion:
+		var sb strings.Builder
+		sb.WriteString("struct {\n")
+		for _, m := range t.Members() {
+			typ, err := g.ref(m)
+			if err != nil {
+				return "", fmt.Errorf("member %s: %w", m.Name(), err)
+			}
+			fmt.Fprintf(&sb, "\t%s *%s\n", exportName(m.Name()), typ)
+		}
+		sb.WriteString("}")
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("unsupported type kind: %s", t.TypeKind())
+}
+
+// structDefinition returns the Go type definition for a struct, naming the
+// fields with the passed function.
+func (g *generator) structDefinition(t *schema.TypeStruct, fieldName func(t *schema.TypeStruct, f *schema.StructField) (string, error)) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("struct {\n")
+	seen := map[string]struct{}{}
+	for _, f := range t.Fields() {
+		name, err := fieldName(t, &f)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := seen[name]; ok {
+			return "", fmt.Errorf("field %s conflicts with another field", f.Name())
+		}
+		seen[name] = struct{}{}
+		typ, err := g.ref(f.Type())
+		if err != nil {
+			return "", fmt.Errorf("field %s: %w", f.Name(), err)
+		}
+		if f.IsNullable() {
+			typ = "*" + typ
+		}
+		if f.IsOptional() {
+			typ = "*" + typ
+		}
+		fmt.Fprintf(&sb, "\t%s %s\n", name, typ)
+	}
+	sb.WriteString("}")
+	return sb.String(), nil
+}
+
+// fieldName returns the name of the Go field for a struct field. bindnode
+// requires Go field names to match the schema, so fields of failure types that
+// would conflict with a generated method are suffixed with "Field", and the
+// failure type is converted to a model type for binding (see [modelName]).
+func (g *generator) fieldName(t *schema.TypeStruct, f *schema.StructField) (string, error) {
+	name := exportName(f.Name())
+	if _, ok := g.failures[t.Name()]; ok {
+		if slices.Contains([]string{"Name", "Error", "ToIPLD"}, name) {
+			name += "Field"
+		}
+		return name, nil
+	}
+	if _, ok := g.builders[t.Name()]; ok && name == "ToIPLD" {
+		return "", fmt.Errorf("field %s conflicts with the ToIPLD method", f.Name())
+	}
+	return name, nil
+}
+
+// schemaFieldName returns the name of the Go field bound to a struct field.
+func schemaFieldName(t *schema.TypeStruct, f *schema.StructField) (string, error) {
+	return exportName(f.Name()), nil
+}
+
+// modelName returns the name of the unexported type bound to the schema type
+// of a failure type.
+func modelName(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	return string(r) + "Model"
+}
+
+var scalars = map[schema.TypeKind]string{
+	schema.TypeKind_Bool:   "bool",
+	schema.TypeKind_Int:    "int64",
+	schema.TypeKind_Float:  "float64",
+	schema.TypeKind_String: "string",
+	schema.TypeKind_Bytes:  "[]byte",
+}
+
+// ref returns the Go type used to reference the passed schema type, enqueueing
+// named types to be generated.
+func (g *generator) ref(t schema.Type) (string, error) {
+	if isAnonymous(t) {
+		return g.inline(t)
+	}
+	if isPrelude(t) {
+		switch t.TypeKind() {
+		case schema.TypeKind_Any, schema.TypeKind_Map, schema.TypeKind_List:
+			return g.use("ipld") + ".Node", nil
+		case schema.TypeKind_Link:
+			return g.use("ipld") + ".Link", nil
+		}
+		return scalars[t.TypeKind()], nil
+	}
+	if _, ok := g.failures[t.Name()]; ok {
+		return "", fmt.Errorf("error type %s cannot be referenced by other types", t.Name())
+	}
+	g.enqueue(t)
+	return t.Name(), nil
+}
+
+// inline returns the Go type of a list, map or link type.
+func (g *generator) inline(t schema.Type) (string, error) {
+	switch t := t.(type) {
+	case *schema.TypeList:
+		v, err := g.ref(t.ValueType())
+		if err != nil {
+			return "", err
+		}
+		if t.ValueIsNullable() {
+			v = "*" + v
+		}
+		return "[]" + v, nil
+	case *schema.TypeMap:
+		if t.KeyType().TypeKind() != schema.TypeKind_String {
+			return "", fmt.Errorf("map keys must be strings")
+		}
+		k, err := g.ref(t.KeyType())
+		if err != nil {
+			return "", err
+		}
+		v, err := g.ref(t.ValueType())
+		if err != nil {
+			return "", err
+		}
+		if t.ValueIsNullable() {
+			v = "*" + v
+		}
+		return fmt.Sprintf("struct {\n\tKeys []%s\n\tValues map[%s]%s\n}", k, k, v), nil
+	case *schema.TypeLink:
+		return g.use("ipld") + ".Link", nil
+	}
+	return g.ref(t)
+}
+
+// prelude are the types implicitly available in every IPLD schema.
+var prelude = map[string]schema.TypeKind{
+	"Bool":   schema.TypeKind_Bool,
+	"Int":    schema.TypeKind_Int,
+	"Float":  schema.TypeKind_Float,
+	"String": schema.TypeKind_String,
+	"Bytes":  schema.TypeKind_Bytes,
+	"Any":    schema.TypeKind_Any,
+	"Map":    schema.TypeKind_Map,
+	"List":   schema.TypeKind_List,
+	"Link":   schema.TypeKind_Link,
+}
+
+func isPrelude(t schema.Type) bool {
+	k, ok := prelude[t.Name()]
+	return ok && k == t.TypeKind()
+}
+
+// isAnonymous reports whether the type is an inline definition. Schemas loaded
+// from DSL name inline definitions after their kind and parameters, e.g.
+// List__String.
+func isAnonymous(t schema.Type) bool {
+	switch t := t.(type) {
+	case *schema.TypeMap:
+		return t.IsAnonymous() || strings.HasPrefix(t.Name(), "Map__")
+	case *schema.TypeList:
+		return t.IsAnonymous() || strings.HasPrefix(t.Name(), "List__")
+	case *schema.TypeLink:
+		return strings.HasPrefix(t.Name(), "Link__")
+	}
+	return false
+}
+
+// exportName converts a schema field or type name to an exported Go
+// identifier, e.g. "content_type" to "ContentType".
+func exportName(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' {
+			upper = true
+			continue
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	s := sb.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
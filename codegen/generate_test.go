@@ -0,0 +1,81 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("example is up to date", func(t *testing.T) {
+		dir := filepath.Join("..", "examples", "upload")
+		m, schema, err := LoadManifest(filepath.Join(dir, "capabilities.json"))
+		require.NoError(t, err)
+		out, err := Generate(m, schema)
+		require.NoError(t, err)
+		existing, err := os.ReadFile(filepath.Join(dir, "capabilities_gen.go"))
+		require.NoError(t, err)
+		require.Equal(t, string(existing), string(out), "run go generate in examples/upload")
+	})
+
+	schema := []byte(`
+type Caveats struct {
+  root Link
+}
+
+type Ok struct {
+  size Int
+}
+
+type Fail struct {
+  name String
+  message String
+}
+
+type NotAFailure struct {
+  message String
+}
+`)
+
+	generate := func(caps ...Capability) error {
+		_, err := Generate(Manifest{Package: "test", Capabilities: caps}, schema)
+		return err
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		out, err := Generate(Manifest{Package: "test", Capabilities: []Capability{
+			{Name: "Add", Can: "test/add", Caveats: "Caveats", Ok: "Ok", Error: "Fail"},
+			{Name: "Remove", Can: "test/remove", With: "did:key"},
+		}}, schema)
+		require.NoError(t, err)
+		require.Contains(t, string(out), Header)
+		require.Contains(t, string(out), "func InvokeAdd(")
+		require.Contains(t, string(out), "func WithRemoveHandler(")
+		require.NotContains(t, string(out), "func NotAFailureType()")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := generate(Capability{Name: "Add", Can: "test/add", Caveats: "Missing"})
+		require.ErrorContains(t, err, "type not found in schema: Missing")
+
+		err = generate(Capability{Name: "Add", Can: "test/add", With: "https"})
+		require.ErrorContains(t, err, "unsupported resource")
+
+		err = generate(Capability{Name: "Add", Can: "test/add", Error: "NotAFailure"})
+		require.ErrorContains(t, err, "must have a name string field")
+
+		err = generate(Capability{Name: "Add", Can: "test/add"}, Capability{Name: "Add", Can: "test/remove"})
+		require.ErrorContains(t, err, "conflicts with")
+
+		err = generate(Capability{Name: "Add", Can: "test/add", Ok: "Fail", Error: "Fail"})
+		require.ErrorContains(t, err, "cannot be used as caveats or ok type")
+
+		err = generate(Capability{Name: "add", Can: "test/add"})
+		require.ErrorContains(t, err, "invalid capability name")
+
+		_, err = Generate(Manifest{Package: "test"}, schema)
+		require.ErrorContains(t, err, "no capabilities")
+	})
+}
@@ -0,0 +1,80 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Manifest describes the capabilities to generate code for.
+//
+//	{
+//	  "package": "upload",
+//	  "schema": "upload.ipldsch",
+//	  "capabilities": [
+//	    {
+//	      "name": "Add",
+//	      "can": "upload/add",
+//	      "with": "did:key",
+//	      "caveats": "AddCaveats",
+//	      "ok": "AddOk",
+//	      "error": "AddError"
+//	    }
+//	  ]
+//	}
+type Manifest struct {
+	// Package is the name of the Go package of the generated code.
+	Package string `json:"package"`
+	// Schema is the path of the IPLD schema file that defines the types
+	// referenced by the capabilities, relative to the manifest.
+	Schema       string       `json:"schema"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// Capability describes a capability to generate code for.
+type Capability struct {
+	// Name is the Go name of the capability, used to name the generated
+	// capability parser, handler option and client function.
+	Name string `json:"name"`
+	// Can is the ability, for example "upload/add".
+	Can string `json:"can"`
+	// With is the resource the capability may be invoked on, "did" for any DID
+	// or "did:<method>" for a DID of a specific method. Defaults to "did".
+	With string `json:"with,omitempty"`
+	// Caveats is the name of the schema type of the caveats. If empty, the
+	// capability has no caveats.
+	Caveats string `json:"caveats,omitempty"`
+	// Ok is the name of the schema type of a successful result. If empty, a
+	// successful result has no data.
+	Ok string `json:"ok,omitempty"`
+	// Error is the name of the schema type of a failure result, which must
+	// have a "name" and a "message" string field. Its name field is generated
+	// as NameField, since failures have a Name method. If empty, failures are
+	// generic.
+	Error string `json:"error,omitempty"`
+}
+
+// LoadManifest reads a manifest and the schema file it references.
+func LoadManifest(path string) (Manifest, []byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Manifest{}, nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+	if m.Schema == "" {
+		return Manifest{}, nil, fmt.Errorf("missing schema in manifest: %s", path)
+	}
+	schemaPath := m.Schema
+	if !filepath.IsAbs(schemaPath) {
+		schemaPath = filepath.Join(filepath.Dir(path), schemaPath)
+	}
+	schema, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("reading schema: %w", err)
+	}
+	return m, schema, nil
+}
@@ -0,0 +1,76 @@
+package codegen_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/examples/upload"
+	"github.com/storacha/go-ucanto/server"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedUpload(t *testing.T) {
+	srv := helpers.Must(server.NewServer(
+		fixtures.Service,
+		upload.WithAddHandler(func(ctx context.Context, cap ucan.Capability[upload.AddCaveats], inv invocation.Invocation, ictx server.InvocationContext) (result.Result[upload.AddOk, upload.AddError], fx.Effects, error) {
+			if cap.Nb().Shards == nil {
+				return result.Error[upload.AddOk](upload.AddError{NameField: "NoShardsError", Message: "no shards"}), nil, nil
+			}
+			return result.Ok[upload.AddOk, upload.AddError](upload.AddOk{Root: cap.Nb().Root, Status: upload.StatusQueued}), nil, nil
+		}),
+		upload.WithListHandler(func(ctx context.Context, cap ucan.Capability[ok.Unit], inv invocation.Invocation, ictx server.InvocationContext) (result.Result[upload.ListOk, failure.IPLDBuilderFailure], fx.Effects, error) {
+			cursor := "next"
+			return result.Ok[upload.ListOk, failure.IPLDBuilderFailure](upload.ListOk{
+				Results: []upload.Upload{{Root: helpers.RandomCID(), Size: 138}},
+				Cursor:  &cursor,
+			}), nil, nil
+		}),
+	))
+	conn := helpers.Must(client.NewConnection(fixtures.Service, srv))
+	space := fixtures.Alice.DID().String()
+
+	t.Run("ok", func(t *testing.T) {
+		root := helpers.RandomCID()
+		shards := []ipld.Link{helpers.RandomCID()}
+		res, rcpt, err := upload.InvokeAdd(t.Context(), conn, fixtures.Alice, space, upload.AddCaveats{Root: root, Shards: &shards})
+		require.NoError(t, err)
+		require.Equal(t, fixtures.Service.DID(), rcpt.Issuer().DID())
+		o, x := result.Unwrap(res)
+		require.Empty(t, x)
+		require.Equal(t, root.String(), o.Root.String())
+		require.Equal(t, upload.StatusQueued, o.Status)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		res, _, err := upload.InvokeAdd(t.Context(), conn, fixtures.Alice, space, upload.AddCaveats{Root: helpers.RandomCID()})
+		require.NoError(t, err)
+		_, x := result.Unwrap(res)
+		require.Equal(t, "NoShardsError", x.Name())
+		require.Equal(t, "no shards", x.Error())
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, _, err := upload.InvokeAdd(t.Context(), conn, fixtures.Bob, space, upload.AddCaveats{Root: helpers.RandomCID()})
+		require.ErrorContains(t, err, "Unauthorized")
+	})
+
+	t.Run("no caveats", func(t *testing.T) {
+		res, _, err := upload.InvokeList(t.Context(), conn, fixtures.Alice, space)
+		require.NoError(t, err)
+		o, x := result.Unwrap(res)
+		require.Nil(t, x.Name)
+		require.Len(t, o.Results, 1)
+		require.Equal(t, int64(138), o.Results[0].Size)
+		require.Equal(t, "next", *o.Cursor)
+	})
+}
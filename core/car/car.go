@@ -2,6 +2,7 @@ package car
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
@@ -78,7 +79,46 @@ func (cb carBlock) Length() uint64 {
 	return cb.length
 }
 
-func Decode(reader io.Reader) ([]ipld.Link, iter.Seq2[ipld.Block, error], error) {
+var (
+	// ErrTooManyBlocks is the error yielded when decoding a CAR with more blocks
+	// than allowed by [WithMaxBlocks].
+	ErrTooManyBlocks = errors.New("too many blocks")
+	// ErrBlockTooLarge is the error yielded when decoding a CAR with a block
+	// larger than allowed by [WithMaxBlockSize].
+	ErrBlockTooLarge = errors.New("block too large")
+)
+
+// DecodeOption is an option configuring how a CAR is decoded.
+type DecodeOption func(cfg *decodeConfig)
+
+type decodeConfig struct {
+	maxBlocks    int
+	maxBlockSize uint64
+}
+
+// WithMaxBlocks limits the number of blocks in the CAR. Decoding a CAR with
+// more blocks yields [ErrTooManyBlocks] once the limit is reached. Zero means
+// no limit.
+func WithMaxBlocks(n int) DecodeOption {
+	return func(cfg *decodeConfig) {
+		cfg.maxBlocks = n
+	}
+}
+
+// WithMaxBlockSize limits the size in bytes of each block in the CAR, including
+// its CID. Decoding a CAR with a larger block yields [ErrBlockTooLarge] before
+// the block is read. Zero means no limit.
+func WithMaxBlockSize(n uint64) DecodeOption {
+	return func(cfg *decodeConfig) {
+		cfg.maxBlockSize = n
+	}
+}
+
+func Decode(reader io.Reader, options ...DecodeOption) ([]ipld.Link, iter.Seq2[ipld.Block, error], error) {
+	cfg := decodeConfig{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
 	br := bufio.NewReader(reader)
 
 	h, err := ipldcar.ReadHeader(br)
@@ -100,7 +140,7 @@ func Decode(reader io.Reader) ([]ipld.Link, iter.Seq2[ipld.Block, error], error)
 		roots = append(roots, cidlink.Link{Cid: r})
 	}
 
-	r := &blkReader{br, offset}
+	r := &blkReader{br: br, offset: offset, maxBlocks: cfg.maxBlocks, maxBlockSize: cfg.maxBlockSize}
 	return roots, func(yield func(ipld.Block, error) bool) {
 		for {
 			blk, err := r.next()
@@ -115,11 +155,33 @@ func Decode(reader io.Reader) ([]ipld.Link, iter.Seq2[ipld.Block, error], error)
 }
 
 type blkReader struct {
-	br     *bufio.Reader
-	offset uint64
+	br           *bufio.Reader
+	offset       uint64
+	count        int
+	maxBlocks    int
+	maxBlockSize uint64
 }
 
 func (r *blkReader) next() (CarBlock, error) {
+	if r.maxBlocks > 0 || r.maxBlockSize > 0 {
+		// peek at the length of the next section so that limits are enforced
+		// before it is read
+		buf, err := r.br.Peek(varint.MaxLenUvarint63)
+		if len(buf) == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		if r.maxBlocks > 0 && r.count >= r.maxBlocks {
+			return nil, fmt.Errorf("%w: exceeds limit of %d", ErrTooManyBlocks, r.maxBlocks)
+		}
+		if size, _, err := varint.FromUvarint(buf); err == nil && r.maxBlockSize > 0 && size > r.maxBlockSize {
+			return nil, fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrBlockTooLarge, size, r.maxBlockSize)
+		}
+	}
+	r.count++
+
 	cid, bytes, err := util.ReadNode(r.br)
 	if err != nil {
 		return nil, err
@@ -2,6 +2,7 @@ package car
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"testing"
@@ -112,3 +113,46 @@ func TestEncodeCAR(t *testing.T) {
 		t.Fatal("failed to round trip")
 	}
 }
+
+func TestDecodeCARLimits(t *testing.T) {
+	fbytes, err := os.ReadFile(fixtures[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(options ...DecodeOption) (int, error) {
+		_, blocks, err := Decode(bytes.NewReader(fbytes), options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, err := range blocks {
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		return n, nil
+	}
+
+	n, err := count(WithMaxBlocks(len(fixtures[0].blocks)), WithMaxBlockSize(1<<20))
+	if err != nil {
+		t.Fatalf("decoding within limits: %s", err)
+	}
+	if n != len(fixtures[0].blocks) {
+		t.Fatalf("incorrect number of blocks: %d, expected: %d", n, len(fixtures[0].blocks))
+	}
+
+	n, err = count(WithMaxBlocks(2))
+	if !errors.Is(err, ErrTooManyBlocks) {
+		t.Fatalf("expected ErrTooManyBlocks, got: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("unexpected number of blocks before error: %d, expected: 2", n)
+	}
+
+	_, err = count(WithMaxBlockSize(64))
+	if !errors.Is(err, ErrBlockTooLarge) {
+		t.Fatalf("expected ErrBlockTooLarge, got: %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package invocation
 
 import (
+	"fmt"
+	"iter"
+
 	"github.com/storacha/go-ucanto/core/dag/blockstore"
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/core/ipld"
@@ -35,3 +38,28 @@ type IssuedInvocation interface {
 func Invoke[C ucan.CaveatBuilder](issuer ucan.Signer, audience ucan.Principal, capability ucan.Capability[C], options ...delegation.Option) (IssuedInvocation, error) {
 	return delegation.Delegate(issuer, audience, []ucan.Capability[C]{capability}, options...)
 }
+
+// InvokeCapabilities creates an invocation of several capabilities that must
+// all be proven, such as the capabilities of a group.
+func InvokeCapabilities[C ucan.CaveatBuilder](issuer ucan.Signer, audience ucan.Principal, capabilities []ucan.Capability[C], options ...delegation.Option) (IssuedInvocation, error) {
+	return delegation.Delegate(issuer, audience, capabilities, options...)
+}
+
+// InvokeWithBlocks creates an invocation of the passed capability with the
+// passed blocks attached, such as the blocks of a receipt or delegation linked
+// from its caveats, so that they are sent along with the invocation.
+func InvokeWithBlocks[C ucan.CaveatBuilder](issuer ucan.Signer, audience ucan.Principal, capability ucan.Capability[C], blocks iter.Seq2[ipld.Block, error], options ...delegation.Option) (IssuedInvocation, error) {
+	inv, err := Invoke(issuer, audience, capability, options...)
+	if err != nil {
+		return nil, fmt.Errorf("creating invocation: %w", err)
+	}
+	for b, err := range blocks {
+		if err != nil {
+			return nil, fmt.Errorf("reading attached blocks: %w", err)
+		}
+		if err := inv.Attach(b); err != nil {
+			return nil, fmt.Errorf("attaching block %s: %w", b.Link(), err)
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,33 @@
+package ipld
+
+import (
+	"errors"
+
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/node/bindnode"
+	"github.com/ipld/go-ipld-prime/schema"
+)
+
+// Validate checks that the passed Node conforms to the representation of the
+// passed schema type.
+func Validate(nd datamodel.Node, typ schema.Type) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if asStr, ok := r.(string); ok {
+				err = errors.New(asStr)
+			} else if asErr, ok := r.(error); ok {
+				err = asErr
+			} else {
+				err = errors.New("unknown panic validating node")
+			}
+		}
+	}()
+
+	if typedNode, ok := nd.(schema.TypedNode); ok {
+		nd = typedNode.Representation()
+	}
+
+	np := bindnode.Prototype(nil, typ)
+	nb := np.Representation().NewBuilder()
+	return nb.AssignNode(nd)
+}
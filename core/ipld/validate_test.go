@@ -0,0 +1,56 @@
+package ipld
+
+import (
+	"testing"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	ts, err := ipld.LoadSchemaBytes([]byte(`
+		type Result struct {
+			status String
+			size optional Int
+		}
+	`))
+	require.NoError(t, err)
+	typ := ts.TypeByName("Result")
+
+	build := func(t *testing.T, fn func(ma ipld.MapAssembler)) ipld.Node {
+		nd, err := qp.BuildMap(basicnode.Prototype.Any, -1, fn)
+		require.NoError(t, err)
+		return nd
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		require.NoError(t, Validate(build(t, func(ma ipld.MapAssembler) {
+			qp.MapEntry(ma, "status", qp.String("done"))
+		}), typ))
+		require.NoError(t, Validate(build(t, func(ma ipld.MapAssembler) {
+			qp.MapEntry(ma, "status", qp.String("done"))
+			qp.MapEntry(ma, "size", qp.Int(138))
+		}), typ))
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		require.Error(t, Validate(build(t, func(ma ipld.MapAssembler) {
+			qp.MapEntry(ma, "size", qp.Int(138))
+		}), typ))
+	})
+
+	t.Run("wrong kind", func(t *testing.T) {
+		require.Error(t, Validate(build(t, func(ma ipld.MapAssembler) {
+			qp.MapEntry(ma, "status", qp.Int(1))
+		}), typ))
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		require.Error(t, Validate(build(t, func(ma ipld.MapAssembler) {
+			qp.MapEntry(ma, "status", qp.String("done"))
+			qp.MapEntry(ma, "extra", qp.String("?"))
+		}), typ))
+	})
+}
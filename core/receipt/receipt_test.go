@@ -485,3 +485,52 @@ func TestAnyReceiptReader(t *testing.T) {
 	require.Equal(t, "some ok value", someOk.SomeOkProperty)
 	require.Nil(t, someErr)
 }
+
+func TestMemoryStore(t *testing.T) {
+	issue := func(t *testing.T) (ipld.Link, AnyReceipt) {
+		inv, err := invocation.Invoke(
+			fixtures.Alice,
+			fixtures.Bob,
+			ucan.NewCapability("ran/invoke", fixtures.Alice.DID().String(), ucan.NoCaveats{}),
+			delegation.WithNonce(helpers.RandomCID().String()),
+		)
+		require.NoError(t, err)
+		out := result.Ok[someOkType, someErrorType](someOkType{SomeOkProperty: "some ok value"})
+		rcpt, err := Issue(fixtures.Bob, out, ran.FromInvocation(inv))
+		require.NoError(t, err)
+		return inv.Link(), rcpt
+	}
+
+	t.Run("put and get", func(t *testing.T) {
+		store, err := NewMemoryStore(0)
+		require.NoError(t, err)
+
+		inv, rcpt := issue(t)
+		_, ok, err := store.Get(t.Context(), inv)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		require.NoError(t, store.Put(t.Context(), rcpt))
+		got, ok, err := store.Get(t.Context(), inv)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, rcpt.Root().Link(), got.Root().Link())
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		store, err := NewMemoryStore(1)
+		require.NoError(t, err)
+
+		inv0, rcpt0 := issue(t)
+		inv1, rcpt1 := issue(t)
+		require.NoError(t, store.Put(t.Context(), rcpt0))
+		require.NoError(t, store.Put(t.Context(), rcpt1))
+
+		_, ok, err := store.Get(t.Context(), inv0)
+		require.NoError(t, err)
+		require.False(t, ok)
+		_, ok, err = store.Get(t.Context(), inv1)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+}
@@ -0,0 +1,51 @@
+package receipt
+
+import (
+	"context"
+	"fmt"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/storacha/go-ucanto/core/ipld"
+)
+
+// Store is a store of receipts, keyed by the CID of the invocation they were
+// issued for.
+type Store interface {
+	// Put stores a receipt under the CID of the invocation it was issued for.
+	Put(ctx context.Context, rcpt AnyReceipt) error
+	// Get a receipt by invocation CID.
+	Get(ctx context.Context, invocation ipld.Link) (AnyReceipt, bool, error)
+}
+
+const MemoryStoreSize = 1000
+
+// MemoryStore is an in-memory LRU [Store].
+type MemoryStore struct {
+	data *lru.Cache[string, AnyReceipt]
+}
+
+func (m *MemoryStore) Put(ctx context.Context, rcpt AnyReceipt) error {
+	m.data.Add(rcpt.Ran().Link().String(), rcpt)
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, invocation ipld.Link) (AnyReceipt, bool, error) {
+	rcpt, ok := m.data.Get(invocation.String())
+	return rcpt, ok, nil
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates a new in-memory LRU store for receipts. The size
+// parameter controls the maximum number of receipts that can be stored. Pass a
+// value less than 1 to use the default size [MemoryStoreSize].
+func NewMemoryStore(size int) (*MemoryStore, error) {
+	if size <= 0 {
+		size = MemoryStoreSize
+	}
+	cache, err := lru.New[string, AnyReceipt](size)
+	if err != nil {
+		return nil, fmt.Errorf("creating receipt LRU: %w", err)
+	}
+	return &MemoryStore{data: cache}, nil
+}
@@ -25,12 +25,12 @@ func DID(opts ...DIDOption) Reader[string, did.DID] {
 	for _, opt := range opts {
 		opt(c)
 	}
+	pfx := "did:"
+	if c.method != "" {
+		pfx = fmt.Sprintf("%s%s:", pfx, c.method)
+	}
 	return reader[string, did.DID]{
 		readFunc: func(input string) (did.DID, failure.Failure) {
-			pfx := "did:"
-			if c.method != "" {
-				pfx = fmt.Sprintf("%s%s:", pfx, c.method)
-			}
 			if !strings.HasPrefix(input, pfx) {
 				return did.Undef, NewSchemaError(fmt.Sprintf(`Expected a "%s" but got "%s" instead`, pfx, input))
 			}
@@ -40,6 +40,7 @@ func DID(opts ...DIDOption) Reader[string, did.DID] {
 			}
 			return d, nil
 		},
+		description: pfx + "*",
 	}
 }
 
@@ -54,5 +55,6 @@ func DIDString(opts ...DIDOption) Reader[string, string] {
 			}
 			return d.String(), nil
 		},
+		description: Describe(rdr),
 	}
 }
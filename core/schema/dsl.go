@@ -0,0 +1,303 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/schema"
+)
+
+// prelude are the types implicitly available in every IPLD schema.
+var prelude = map[string]schema.TypeKind{
+	"Bool":   schema.TypeKind_Bool,
+	"Int":    schema.TypeKind_Int,
+	"Float":  schema.TypeKind_Float,
+	"String": schema.TypeKind_String,
+	"Bytes":  schema.TypeKind_Bytes,
+	"Any":    schema.TypeKind_Any,
+	"Map":    schema.TypeKind_Map,
+	"List":   schema.TypeKind_List,
+	"Link":   schema.TypeKind_Link,
+}
+
+// DSL returns the IPLD schema DSL for the passed type followed by the named
+// types it references, in the order they are first referenced. Prelude types
+// such as String and Link are not included. A nil type returns an empty
+// string.
+//
+// Representation parameters that go-ipld-prime does not expose, namely the
+// discriminants of inline and envelope unions and the separators of
+// stringpairs structs, are read by reflection.
+func DSL(typ schema.Type) string {
+	if typ == nil {
+		return ""
+	}
+	p := dslPrinter{seen: map[schema.TypeName]struct{}{}}
+	p.enqueue(typ)
+	defs := []string{}
+	for len(p.queue) > 0 {
+		t := p.queue[0]
+		p.queue = p.queue[1:]
+		defs = append(defs, fmt.Sprintf("type %s %s", t.Name(), p.definition(t)))
+	}
+	return strings.Join(defs, "\n\n")
+}
+
+type dslPrinter struct {
+	seen  map[schema.TypeName]struct{}
+	queue []schema.Type
+}
+
+func isPrelude(t schema.Type) bool {
+	k, ok := prelude[t.Name()]
+	return ok && k == t.TypeKind()
+}
+
+// isAnonymous reports whether the type is an inline definition, which is
+// printed in place rather than referenced by name. Schemas loaded from DSL
+// name inline definitions after their kind and parameters, e.g. List__String.
+func isAnonymous(t schema.Type) bool {
+	switch t := t.(type) {
+	case *schema.TypeMap:
+		return t.IsAnonymous() || strings.HasPrefix(t.Name(), "Map__")
+	case *schema.TypeList:
+		return t.IsAnonymous() || strings.HasPrefix(t.Name(), "List__")
+	case *schema.TypeLink:
+		return strings.HasPrefix(t.Name(), "Link__")
+	}
+	return false
+}
+
+func (p *dslPrinter) enqueue(t schema.Type) {
+	if _, ok := p.seen[t.Name()]; ok {
+		return
+	}
+	p.seen[t.Name()] = struct{}{}
+	p.queue = append(p.queue, t)
+}
+
+// ref returns the name used to reference the passed type, queueing it to be
+// defined if necessary.
+func (p *dslPrinter) ref(t schema.Type) string {
+	if isAnonymous(t) {
+		return p.inline(t)
+	}
+	if !isPrelude(t) {
+		p.enqueue(t)
+	}
+	return t.Name()
+}
+
+func nullable(nullable bool) string {
+	if nullable {
+		return "nullable "
+	}
+	return ""
+}
+
+// inline returns the inline definition of a recursive or link type.
+func (p *dslPrinter) inline(t schema.Type) string {
+	switch t := t.(type) {
+	case *schema.TypeMap:
+		return fmt.Sprintf("{%s:%s%s}", p.ref(t.KeyType()), nullable(t.ValueIsNullable()), p.ref(t.ValueType()))
+	case *schema.TypeList:
+		return fmt.Sprintf("[%s%s]", nullable(t.ValueIsNullable()), p.ref(t.ValueType()))
+	case *schema.TypeLink:
+		if t.HasReferencedType() {
+			return "&" + p.ref(t.ReferencedType())
+		}
+		return "Link"
+	}
+	return p.ref(t)
+}
+
+func (p *dslPrinter) definition(t schema.Type) string {
+	switch t := t.(type) {
+	case *schema.TypeBool:
+		return "bool"
+	case *schema.TypeInt:
+		return "int"
+	case *schema.TypeFloat:
+		return "float"
+	case *schema.TypeString:
+		return "string"
+	case *schema.TypeBytes:
+		return "bytes"
+	case *schema.TypeAny:
+		return "any"
+	case *schema.TypeMap, *schema.TypeList:
+		return p.inline(t)
+	case *schema.TypeLink:
+		if t.HasReferencedType() {
+			return "&" + p.ref(t.ReferencedType())
+		}
+		return "link"
+	case *schema.TypeStruct:
+		return p.structDefinition(t)
+	case *schema.TypeUnion:
+		return p.unionDefinition(t)
+	case *schema.TypeEnum:
+		return enumDefinition(t)
+	}
+	return "any"
+}
+
+func (p *dslPrinter) structDefinition(t *schema.TypeStruct) string {
+	var sb strings.Builder
+	sb.WriteString("struct {")
+	if len(t.Fields()) > 0 {
+		sb.WriteString("\n")
+	}
+	mapRepr, isMap := t.RepresentationStrategy().(schema.StructRepresentation_Map)
+	for _, f := range t.Fields() {
+		sb.WriteString("  ")
+		sb.WriteString(f.Name())
+		sb.WriteString(" ")
+		if f.IsOptional() {
+			sb.WriteString("optional ")
+		}
+		sb.WriteString(nullable(f.IsNullable()))
+		sb.WriteString(p.ref(f.Type()))
+		if isMap {
+			var params []string
+			if mapRepr.FieldHasRename(f) {
+				params = append(params, fmt.Sprintf("rename %q", mapRepr.GetFieldKey(f)))
+			}
+			if implicit := mapRepr.FieldImplicit(f); implicit != nil {
+				params = append(params, "implicit "+implicitValue(implicit))
+			}
+			if len(params) > 0 {
+				sb.WriteString(" (" + strings.Join(params, " ") + ")")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+
+	switch r := t.RepresentationStrategy().(type) {
+	case schema.StructRepresentation_Tuple:
+		sb.WriteString(" representation tuple")
+	case schema.StructRepresentation_ListPairs:
+		sb.WriteString(" representation listpairs")
+	case schema.StructRepresentation_StringPairs:
+		sb.WriteString(fmt.Sprintf(" representation stringpairs {\n  innerDelim %q\n  entryDelim %q\n}", reprString(r, "sep1"), reprString(r, "sep2")))
+	case schema.StructRepresentation_Stringjoin:
+		sb.WriteString(fmt.Sprintf(" representation stringjoin {\n  join %q\n}", r.GetDelim()))
+	}
+	return sb.String()
+}
+
+func implicitValue(v schema.ImplicitValue) string {
+	switch v := v.(type) {
+	case schema.ImplicitValue_String:
+		return fmt.Sprintf("%q", string(v))
+	case schema.ImplicitValue_Int:
+		return fmt.Sprintf("%d", int(v))
+	case schema.ImplicitValue_Bool:
+		return fmt.Sprintf("%t", bool(v))
+	case schema.ImplicitValue_EmptyList:
+		return "[]"
+	case schema.ImplicitValue_EmptyMap:
+		return "{}"
+	}
+	return ""
+}
+
+var kinds = []datamodel.Kind{
+	datamodel.Kind_Map,
+	datamodel.Kind_List,
+	datamodel.Kind_Null,
+	datamodel.Kind_Bool,
+	datamodel.Kind_Int,
+	datamodel.Kind_Float,
+	datamodel.Kind_String,
+	datamodel.Kind_Bytes,
+	datamodel.Kind_Link,
+}
+
+func (p *dslPrinter) unionDefinition(t *schema.TypeUnion) string {
+	var sb strings.Builder
+	sb.WriteString("union {\n")
+	var repr string
+	switch r := t.RepresentationStrategy().(type) {
+	case schema.UnionRepresentation_Keyed:
+		for _, m := range t.Members() {
+			sb.WriteString(fmt.Sprintf("  | %s %q\n", p.ref(m), r.GetDiscriminant(m)))
+		}
+		repr = "keyed"
+	case schema.UnionRepresentation_Kinded:
+		for _, m := range t.Members() {
+			for _, k := range kinds {
+				if r.GetMember(k) == m.Name() {
+					sb.WriteString(fmt.Sprintf("  | %s %s\n", p.ref(m), k.String()))
+				}
+			}
+		}
+		repr = "kinded"
+	case schema.UnionRepresentation_Stringprefix:
+		for _, m := range t.Members() {
+			sb.WriteString(fmt.Sprintf("  | %s %q\n", p.ref(m), r.GetDiscriminant(m)))
+		}
+		repr = "stringprefix"
+	case schema.UnionRepresentation_Inline:
+		p.writeDiscriminatedMembers(&sb, t, reprDiscriminants(r))
+		repr = fmt.Sprintf("inline {\n  discriminantKey %q\n}", reprString(r, "discriminantKey"))
+	case schema.UnionRepresentation_Envelope:
+		p.writeDiscriminatedMembers(&sb, t, reprDiscriminants(r))
+		repr = fmt.Sprintf("envelope {\n  discriminantKey %q\n  contentKey %q\n}", reprString(r, "discriminantKey"), reprString(r, "contentKey"))
+	}
+	sb.WriteString("} representation " + repr)
+	return sb.String()
+}
+
+func (p *dslPrinter) writeDiscriminatedMembers(sb *strings.Builder, t *schema.TypeUnion, discriminants map[schema.TypeName]string) {
+	for _, m := range t.Members() {
+		sb.WriteString(fmt.Sprintf("  | %s %q\n", p.ref(m), discriminants[m.Name()]))
+	}
+}
+
+// reprString reads a string parameter of a representation strategy that
+// go-ipld-prime does not expose.
+func reprString(repr any, field string) string {
+	return reflect.ValueOf(repr).FieldByName(field).String()
+}
+
+// reprDiscriminants reads the discriminants of the members of an inline or
+// envelope union, which go-ipld-prime does not expose.
+func reprDiscriminants(repr any) map[schema.TypeName]string {
+	discriminants := map[schema.TypeName]string{}
+	iter := reflect.ValueOf(repr).FieldByName("table").MapRange()
+	for iter.Next() {
+		discriminants[iter.Value().String()] = iter.Key().String()
+	}
+	return discriminants
+}
+
+func enumDefinition(t *schema.TypeEnum) string {
+	var sb strings.Builder
+	sb.WriteString("enum {\n")
+	switch r := t.RepresentationStrategy().(type) {
+	case schema.EnumRepresentation_Int:
+		for _, m := range t.Members() {
+			sb.WriteString(fmt.Sprintf("  | %s (\"%d\")\n", m, r[m]))
+		}
+		sb.WriteString("} representation int")
+	case schema.EnumRepresentation_String:
+		for _, m := range t.Members() {
+			if v, ok := r[m]; ok && v != m {
+				sb.WriteString(fmt.Sprintf("  | %s (%q)\n", m, v))
+			} else {
+				sb.WriteString(fmt.Sprintf("  | %s\n", m))
+			}
+		}
+		sb.WriteString("}")
+	default:
+		for _, m := range t.Members() {
+			sb.WriteString(fmt.Sprintf("  | %s\n", m))
+		}
+		sb.WriteString("}")
+	}
+	return sb.String()
+}
@@ -0,0 +1,140 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDSL(t *testing.T) {
+	ts := helpers.Must(ipld.LoadSchemaBytes([]byte(`
+		type Root struct {
+			name String
+			tags [String]
+			meta optional {String:nullable Meta}
+			link &Root (rename "l")
+			any Link
+			kind Kind
+			result Result
+		}
+
+		type Meta struct {
+			size Int
+			ok Bool
+		} representation tuple
+
+		type Kind enum {
+			| Small ("s")
+			| Large
+		}
+
+		type Result union {
+			| Ok "ok"
+			| Error "error"
+		} representation keyed
+
+		type Ok struct {}
+
+		type Error struct {
+			message String
+		}
+
+		type Unused struct {
+			name String
+		}
+	`)))
+
+	expected := `type Root struct {
+  name String
+  tags [String]
+  meta optional {String:nullable Meta}
+  link &Root (rename "l")
+  any Link
+  kind Kind
+  result Result
+}
+
+type Meta struct {
+  size Int
+  ok Bool
+} representation tuple
+
+type Kind enum {
+  | Small ("s")
+  | Large
+}
+
+type Result union {
+  | Ok "ok"
+  | Error "error"
+} representation keyed
+
+type Ok struct {}
+
+type Error struct {
+  message String
+}`
+
+	t.Run("prints type and references", func(t *testing.T) {
+		require.Equal(t, expected, DSL(ts.TypeByName("Root")))
+	})
+
+	t.Run("output is a loadable schema", func(t *testing.T) {
+		ts2, err := ipld.LoadSchemaBytes([]byte(DSL(ts.TypeByName("Root"))))
+		require.NoError(t, err)
+		require.Equal(t, expected, DSL(ts2.TypeByName("Root")))
+	})
+
+	t.Run("inline union", func(t *testing.T) {
+		ts := helpers.Must(ipld.LoadSchemaBytes([]byte(`
+			type Shape union {
+				| Circle "circle"
+				| Square "square"
+			} representation inline {
+				discriminantKey "type"
+			}
+
+			type Circle struct {
+				radius Int
+			}
+
+			type Square struct {
+				side Int
+			}
+		`)))
+
+		expected := `type Shape union {
+  | Circle "circle"
+  | Square "square"
+} representation inline {
+  discriminantKey "type"
+}
+
+type Circle struct {
+  radius Int
+}
+
+type Square struct {
+  side Int
+}`
+		require.Equal(t, expected, DSL(ts.TypeByName("Shape")))
+
+		ts2, err := ipld.LoadSchemaBytes([]byte(DSL(ts.TypeByName("Shape"))))
+		require.NoError(t, err)
+		require.Equal(t, expected, DSL(ts2.TypeByName("Shape")))
+	})
+
+	t.Run("nil type", func(t *testing.T) {
+		require.Equal(t, "", DSL(nil))
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	require.Equal(t, "did:*", Describe(DIDString()))
+	require.Equal(t, "did:key:*", Describe(DID(WithMethod("key"))))
+	require.Equal(t, "did:web:example.com | did:key:*", Describe(Or(Literal("did:web:example.com"), DIDString(WithMethod("key")))))
+	require.Equal(t, "mailto:*", Describe(URI(WithProtocol("mailto:"))))
+	require.Equal(t, "", Describe(Link()))
+}
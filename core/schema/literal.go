@@ -14,5 +14,6 @@ func Literal(expected string) Reader[string, string] {
 			}
 			return input, nil
 		},
+		description: expected,
 	}
 }
@@ -16,6 +16,10 @@ func (m mapped[I, O, O2]) Read(i I) (O2, failure.Failure) {
 	return m.converter(o)
 }
 
+func (m mapped[I, O, O2]) Describe() string {
+	return Describe(m.reader)
+}
+
 func Mapped[I, O, O2 any](reader Reader[I, O], converter func(O) (O2, failure.Failure)) Reader[I, O2] {
 	return mapped[I, O, O2]{reader, converter}
 }
@@ -58,3 +58,17 @@ func (or orReader[I, O]) Read(input I) (O, failure.Failure) {
 func Or[I, O any](readers ...Reader[I, O]) Reader[I, O] {
 	return orReader[I, O]{readers}
 }
+
+// Describe returns the descriptions of the readers separated by " | ", or an
+// empty string if any of the readers cannot be described.
+func (or orReader[I, O]) Describe() string {
+	descriptions := make([]string, 0, len(or.readers))
+	for _, reader := range or.readers {
+		d := Describe(reader)
+		if d == "" {
+			return ""
+		}
+		descriptions = append(descriptions, d)
+	}
+	return strings.Join(descriptions, " | ")
+}
@@ -10,14 +10,34 @@ type Reader[I, O any] interface {
 	Read(input I) (O, failure.Failure)
 }
 
+// Describer is implemented by readers that can describe the values they
+// accept, for example "did:key:*".
+type Describer interface {
+	Describe() string
+}
+
+// Describe returns a description of the values accepted by the passed reader,
+// or an empty string if the reader does not implement [Describer].
+func Describe(r any) string {
+	if d, ok := r.(Describer); ok {
+		return d.Describe()
+	}
+	return ""
+}
+
 type reader[I, O any] struct {
-	readFunc func(input I) (O, failure.Failure)
+	readFunc    func(input I) (O, failure.Failure)
+	description string
 }
 
 func (r reader[I, O]) Read(input I) (O, failure.Failure) {
 	return r.readFunc(input)
 }
 
+func (r reader[I, O]) Describe() string {
+	return r.description
+}
+
 type schemaerr struct {
 	message string
 }
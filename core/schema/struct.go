@@ -8,6 +8,11 @@ import (
 	"github.com/ucan-wg/go-ucan/capability/policy"
 )
 
+// Typed is implemented by readers that read values of an IPLD schema type.
+type Typed interface {
+	Type() schema.Type
+}
+
 type strukt[T any] struct {
 	typ    schema.Type
 	policy policy.Policy
@@ -45,6 +50,11 @@ func (s strukt[T]) Read(input any) (T, failure.Failure) {
 	return bind, nil
 }
 
+// Type returns the IPLD schema type the reader binds input to.
+func (s strukt[T]) Type() schema.Type {
+	return s.typ
+}
+
 func Struct[T any](typ schema.Type, policy policy.Policy, opts ...bindnode.Option) Reader[any, T] {
 	return strukt[T]{typ, policy, opts}
 }
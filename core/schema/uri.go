@@ -42,6 +42,13 @@ func (ur uriReader) Read(input any) (url.URL, failure.Failure) {
 	return asUrl, nil
 }
 
+func (ur uriReader) Describe() string {
+	if ur.uc.protocol != nil {
+		return *ur.uc.protocol + "*"
+	}
+	return "*:*"
+}
+
 func URI(opts ...URIOption) Reader[any, url.URL] {
 	uc := &uriConfig{}
 	for _, opt := range opts {
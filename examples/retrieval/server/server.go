@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path"
@@ -17,7 +16,6 @@ import (
 	"github.com/storacha/go-ucanto/server"
 	"github.com/storacha/go-ucanto/server/retrieval"
 	"github.com/storacha/go-ucanto/testing/fixtures"
-	thttp "github.com/storacha/go-ucanto/transport/http"
 	"github.com/storacha/go-ucanto/ucan"
 )
 
@@ -60,22 +58,7 @@ func main() {
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/{digest}", func(w http.ResponseWriter, r *http.Request) {
-		resp, err := server.Request(r.Context(), thttp.NewInboundRequest(r.URL, r.Body, r.Header))
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-			return
-		}
-		for name, values := range resp.Headers() {
-			for _, value := range values {
-				w.Header().Add(name, value)
-			}
-		}
-		w.WriteHeader(resp.Status())
-		body := resp.Body()
-		io.Copy(w, body)
-		body.Close()
-	})
+	mux.Handle("/{digest}", retrieval.NewHTTPHandler(server))
 
 	httpServer := &http.Server{
 		Addr:           ":3000",
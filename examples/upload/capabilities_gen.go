@@ -0,0 +1,213 @@
+// Code generated by ucantogen. DO NOT EDIT.
+
+package upload
+
+import (
+	"context"
+	"fmt"
+
+	ipldprime "github.com/ipld/go-ipld-prime"
+	ipldschema "github.com/ipld/go-ipld-prime/schema"
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	fdm "github.com/storacha/go-ucanto/core/result/failure/datamodel"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	udm "github.com/storacha/go-ucanto/core/result/ok/datamodel"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/server"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+type AddCaveats struct {
+	Root   ipld.Link
+	Shards *[]ipld.Link
+}
+
+// AddCaveatsType returns the schema type of [AddCaveats].
+func AddCaveatsType() ipldschema.Type {
+	return typeSystem.TypeByName("AddCaveats")
+}
+
+func (x AddCaveats) ToIPLD() (ipld.Node, error) {
+	return ipld.WrapWithRecovery(&x, AddCaveatsType())
+}
+
+type AddOk struct {
+	Root   ipld.Link
+	Status Status
+	Labels *struct {
+		Keys   []string
+		Values map[string]string
+	}
+}
+
+// AddOkType returns the schema type of [AddOk].
+func AddOkType() ipldschema.Type {
+	return typeSystem.TypeByName("AddOk")
+}
+
+func (x AddOk) ToIPLD() (ipld.Node, error) {
+	return ipld.WrapWithRecovery(&x, AddOkType())
+}
+
+type AddError struct {
+	NameField string
+	Message   string
+}
+
+// AddErrorType returns the schema type of [AddError].
+func AddErrorType() ipldschema.Type {
+	return typeSystem.TypeByName("AddError")
+}
+
+func (x AddError) ToIPLD() (ipld.Node, error) {
+	m := addErrorModel{
+		Name:    x.NameField,
+		Message: x.Message,
+	}
+	return ipld.WrapWithRecovery(&m, AddErrorType())
+}
+
+func (x AddError) Name() string {
+	return x.NameField
+}
+
+func (x AddError) Error() string {
+	return x.Message
+}
+
+// addErrorModel is bound to the schema type of [AddError].
+type addErrorModel struct {
+	Name    string
+	Message string
+}
+
+func (m addErrorModel) failure() AddError {
+	return AddError{
+		NameField: m.Name,
+		Message:   m.Message,
+	}
+}
+
+type ListOk struct {
+	Results []Upload
+	Cursor  *string
+}
+
+// ListOkType returns the schema type of [ListOk].
+func ListOkType() ipldschema.Type {
+	return typeSystem.TypeByName("ListOk")
+}
+
+func (x ListOk) ToIPLD() (ipld.Node, error) {
+	return ipld.WrapWithRecovery(&x, ListOkType())
+}
+
+type Status string
+
+const (
+	StatusQueued Status = "Queued"
+	StatusDone   Status = "Done"
+)
+
+type Upload struct {
+	Root ipld.Link
+	Size int64
+}
+
+// Add is the "upload/add" capability.
+var Add = validator.NewCapability(
+	"upload/add",
+	schema.DIDString(schema.WithMethod("key")),
+	schema.Struct[AddCaveats](AddCaveatsType(), nil),
+	validator.DefaultDerives,
+	validator.WithResultTypes(AddOkType(), AddErrorType()),
+)
+
+// WithAddHandler configures the handler for "upload/add" invocations.
+func WithAddHandler(handler server.HandlerFunc[AddCaveats, AddOk, AddError]) server.Option {
+	return server.WithServiceMethod(Add.Can(), server.Provide(Add, handler))
+}
+
+// InvokeAdd invokes "upload/add" on the service of the passed connection
+// and returns the result, along with the receipt it was read from.
+func InvokeAdd(ctx context.Context, conn client.Connection, issuer ucan.Signer, with ucan.Resource, nb AddCaveats, options ...delegation.Option) (result.Result[AddOk, AddError], receipt.AnyReceipt, error) {
+	inv, err := Add.Invoke(issuer, conn.ID(), with, nb, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, rcpt, err := client.Invoke[AddOk, addErrorModel](ctx, conn, inv, AddOkType(), AddErrorType())
+	if err != nil {
+		return nil, rcpt, err
+	}
+	return result.MapError(res, addErrorModel.failure), rcpt, nil
+}
+
+// List is the "upload/list" capability.
+var List = validator.NewCapability(
+	"upload/list",
+	schema.DIDString(),
+	schema.Struct[ok.Unit](udm.UnitType(), nil),
+	validator.DefaultDerives,
+	validator.WithResultTypes(ListOkType(), nil),
+)
+
+// WithListHandler configures the handler for "upload/list" invocations.
+func WithListHandler(handler server.HandlerFunc[ok.Unit, ListOk, failure.IPLDBuilderFailure]) server.Option {
+	return server.WithServiceMethod(List.Can(), server.Provide(List, handler))
+}
+
+// InvokeList invokes "upload/list" on the service of the passed connection
+// and returns the result, along with the receipt it was read from.
+func InvokeList(ctx context.Context, conn client.Connection, issuer ucan.Signer, with ucan.Resource, options ...delegation.Option) (result.Result[ListOk, fdm.FailureModel], receipt.AnyReceipt, error) {
+	inv, err := List.Invoke(issuer, conn.ID(), with, ok.Unit{}, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client.Invoke[ListOk, fdm.FailureModel](ctx, conn, inv, ListOkType(), nil)
+}
+
+var typeSystem = mustLoadTypeSystem()
+
+func mustLoadTypeSystem() *ipldschema.TypeSystem {
+	ts, err := ipldprime.LoadSchemaBytes([]byte(`type AddCaveats struct {
+  root Link
+  shards optional [Link]
+}
+
+type AddOk struct {
+  root Link
+  status Status
+  labels optional {String:String}
+}
+
+type Status enum {
+  | Queued ("queued")
+  | Done ("done")
+}
+
+type AddError struct {
+  name String
+  message String
+}
+
+type ListOk struct {
+  results [Upload]
+  cursor nullable String
+}
+
+type Upload struct {
+  root Link
+  size Int
+}
+`))
+	if err != nil {
+		panic(fmt.Errorf("loading schema: %w", err))
+	}
+	return ts
+}
@@ -0,0 +1,5 @@
+// Package upload is an example of capabilities generated by ucantogen from the
+// IPLD schema in upload.ipldsch and the manifest in capabilities.json.
+package upload
+
+//go:generate go run github.com/storacha/go-ucanto/cmd/ucantogen -manifest capabilities.json -out capabilities_gen.go
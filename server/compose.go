@@ -0,0 +1,161 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// FallbackAbility is the ability under which a fallback method is registered
+// in a [Service]. A method registered as "<namespace>/*" is the fallback for
+// abilities in that namespace. See [ResolveMethod].
+const FallbackAbility = "*"
+
+// ResolveMethod finds the method in the passed service that handles the passed
+// ability. If there is no method for the ability itself, the fallback methods
+// of its namespaces are tried, from most to least specific. For example, for
+// "space/blob/add" the methods for "space/blob/*", "space/*" and "*" are tried,
+// in that order.
+//
+// The ability of an invocation of a group of capabilities is the abilities of
+// its capabilities in lexical order separated by "&" (see
+// validator.GroupAbility), for example "store/add&upload/add". It is only
+// handled by a method registered for that ability, never by a fallback method.
+func ResolveMethod[M any](service map[ucan.Ability]M, can ucan.Ability) (M, bool) {
+	if method, ok := service[can]; ok {
+		return method, true
+	}
+	if isGroupAbility(can) {
+		var method M
+		return method, false
+	}
+	ns := can
+	for {
+		i := strings.LastIndex(ns, "/")
+		if i < 0 {
+			break
+		}
+		ns = ns[:i]
+		if method, ok := service[ns+"/"+FallbackAbility]; ok {
+			return method, true
+		}
+	}
+	method, ok := service[FallbackAbility]
+	return method, ok
+}
+
+// isFallback reports whether the passed ability is that of a fallback method
+// (see [FallbackAbility]).
+func isFallback(can ucan.Ability) bool {
+	return can == FallbackAbility || strings.HasSuffix(can, "/"+FallbackAbility)
+}
+
+// WithService adds the methods of the passed service to the server, allowing
+// services to be built from independently developed modules. It is an error if
+// a method is already configured for any of the abilities.
+//
+// The methods of a service do not declare the capability they provide, even
+// if they were created with [Provide], so their abilities are not described
+// unless configured with [WithAbilityDescription], and cannot be dry run (see
+// [WithDryRun]).
+func WithService(service Service) Option {
+	return func(cfg *srvConfig) error {
+		for can, method := range service {
+			if err := cfg.addMethod(can, method); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithMount mounts the passed service under an ability namespace, for example
+// "upload". The service is given exclusive ownership of the namespace: all of
+// its abilities must be within the namespace (e.g. "upload/add") and it is an
+// error to configure any other method within it. A fallback method in the
+// service (see [FallbackAbility]) handles abilities in the namespace that the
+// service does not provide.
+//
+// Abilities are not renamed when mounted, since service methods validate the
+// invoked ability against their capability definition.
+func WithMount(namespace string, service Service) Option {
+	return func(cfg *srvConfig) error {
+		if !isValidNamespace(namespace) {
+			return fmt.Errorf("invalid service namespace: %q", namespace)
+		}
+		for _, ns := range cfg.mounts {
+			if ns == namespace || inNamespace(ns, namespace) || inNamespace(namespace, ns) {
+				return fmt.Errorf("service namespace %q overlaps mounted namespace %q", namespace, ns)
+			}
+		}
+		for can := range cfg.service {
+			if inNamespace(namespace, can) {
+				return fmt.Errorf("service namespace %q is not available, method configured for ability: %s", namespace, can)
+			}
+		}
+
+		for can, method := range service {
+			if can == FallbackAbility {
+				can = namespace + "/" + FallbackAbility
+			}
+			if !inNamespace(namespace, can) {
+				return fmt.Errorf("ability %s is not in service namespace %q", can, namespace)
+			}
+			if err := cfg.addMethod(can, method); err != nil {
+				return err
+			}
+		}
+		cfg.mounts = append(cfg.mounts, namespace)
+		return nil
+	}
+}
+
+// WithFallback configures a method that handles invocations of abilities the
+// service does not provide, instead of failing them with a
+// [HandlerNotFoundError]. Since the method handles any ability, it must perform
+// its own validation of the invocation.
+func WithFallback[O ipld.Builder, X failure.IPLDBuilderFailure](handleFunc Method[O, X]) Option {
+	return func(cfg *srvConfig) error {
+		return addServiceMethod(cfg, FallbackAbility, handleFunc)
+	}
+}
+
+// addMethod adds a method to the service, failing if a method is already
+// configured for the ability or the ability is in a mounted namespace.
+func (cfg *srvConfig) addMethod(can ucan.Ability, method ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) error {
+	if _, ok := cfg.service[can]; ok {
+		return fmt.Errorf("duplicate service method for ability: %s", can)
+	}
+	for _, ns := range cfg.mounts {
+		if inNamespace(ns, can) {
+			return fmt.Errorf("ability %s is in mounted service namespace %q", can, ns)
+		}
+	}
+	cfg.service[can] = method
+	return nil
+}
+
+func isValidNamespace(namespace string) bool {
+	return namespace != "" && !strings.HasSuffix(namespace, "/") && !strings.Contains(namespace, "*")
+}
+
+// inNamespace reports whether the passed ability is in the namespace. The
+// ability of a capability group is in the namespace if the abilities of all of
+// its members are.
+func inNamespace(namespace string, can ucan.Ability) bool {
+	for _, member := range strings.Split(can, "&") {
+		if !strings.HasPrefix(member, namespace+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// isGroupAbility reports whether the passed ability is that of a group of
+// capabilities (see [invokedCapability]).
+func isGroupAbility(can ucan.Ability) bool {
+	return strings.Contains(can, "&")
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveMethod(t *testing.T) {
+	service := map[ucan.Ability]string{
+		"space/blob/add": "space/blob/add",
+		"space/blob/*":   "space/blob/*",
+		"space/*":        "space/*",
+	}
+
+	testCases := []struct {
+		can      ucan.Ability
+		expected string
+		found    bool
+	}{
+		{"space/blob/add", "space/blob/add", true},
+		{"space/blob/remove", "space/blob/*", true},
+		{"space/index/add", "space/*", true},
+		{"space/info", "space/*", true},
+		{"upload/add", "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.can, func(t *testing.T) {
+			method, found := ResolveMethod(service, tc.can)
+			require.Equal(t, tc.found, found)
+			require.Equal(t, tc.expected, method)
+		})
+	}
+
+	service[FallbackAbility] = "*"
+	method, found := ResolveMethod(service, "upload/add")
+	require.True(t, found)
+	require.Equal(t, "*", method)
+}
+
+func TestServiceComposition(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+	uploadAddMethod := AnyServiceMethod(Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+		return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+	}))
+
+	var fallbacks []ucan.Ability
+	fallback := ServiceMethod[ok.Unit, failure.IPLDBuilderFailure](func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (transaction.Transaction[ok.Unit, failure.IPLDBuilderFailure], error) {
+		fallbacks = append(fallbacks, inv.Capabilities()[0].Can())
+		return transaction.NewTransaction(result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{})), nil
+	})
+
+	run := func(t *testing.T, srv ServerView[Service], can ucan.Ability) *string {
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, ucan.NewCapability(can, fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		rcpt := helpers.Must(srv.Run(t.Context(), inv))
+		_, x := result.Unwrap(rcpt.Out())
+		if x == nil {
+			return nil
+		}
+		return asFailure(t, x).Name
+	}
+
+	t.Run("duplicate method", func(t *testing.T) {
+		_, err := NewServer(
+			fixtures.Service,
+			WithServiceMethod(uploadadd.Can(), Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+				return nil, nil, nil
+			})),
+			WithService(Service{uploadadd.Can(): uploadAddMethod}),
+		)
+		require.ErrorContains(t, err, "duplicate service method for ability: upload/add")
+	})
+
+	t.Run("merges services", func(t *testing.T) {
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			WithService(Service{uploadadd.Can(): uploadAddMethod}),
+			WithService(Service{"store/add": AnyServiceMethod(fallback)}),
+		))
+		require.Len(t, srv.Service(), 2)
+		require.Nil(t, run(t, srv, uploadadd.Can()))
+	})
+
+	t.Run("mount", func(t *testing.T) {
+		fallbacks = nil
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			WithMount("upload", Service{
+				uploadadd.Can(): uploadAddMethod,
+				FallbackAbility: AnyServiceMethod(fallback),
+			}),
+		))
+
+		require.Nil(t, run(t, srv, uploadadd.Can()))
+		require.Nil(t, run(t, srv, "upload/remove"))
+		require.Equal(t, []ucan.Ability{"upload/remove"}, fallbacks)
+		require.Equal(t, "HandlerNotFoundError", *run(t, srv, "store/add"))
+	})
+
+	t.Run("mount owns namespace", func(t *testing.T) {
+		_, err := NewServer(
+			fixtures.Service,
+			WithMount("upload", Service{uploadadd.Can(): uploadAddMethod}),
+			WithService(Service{"upload/list": AnyServiceMethod(fallback)}),
+		)
+		require.ErrorContains(t, err, "mounted service namespace")
+
+		_, err = NewServer(
+			fixtures.Service,
+			WithService(Service{"upload/list": AnyServiceMethod(fallback)}),
+			WithMount("upload", Service{uploadadd.Can(): uploadAddMethod}),
+		)
+		require.ErrorContains(t, err, "is not available")
+
+		_, err = NewServer(
+			fixtures.Service,
+			WithMount("upload", Service{uploadadd.Can(): uploadAddMethod}),
+			WithMount("upload/shard", Service{"upload/shard/list": AnyServiceMethod(fallback)}),
+		)
+		require.ErrorContains(t, err, "overlaps")
+	})
+
+	t.Run("mount rejects ability outside namespace", func(t *testing.T) {
+		_, err := NewServer(fixtures.Service, WithMount("store", Service{uploadadd.Can(): uploadAddMethod}))
+		require.ErrorContains(t, err, "not in service namespace")
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		fallbacks = nil
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			WithService(Service{uploadadd.Can(): uploadAddMethod}),
+			WithFallback(fallback),
+		))
+
+		require.Nil(t, run(t, srv, uploadadd.Can()))
+		require.Nil(t, run(t, srv, "store/add"))
+		require.Equal(t, []ucan.Ability{"store/add"}, fallbacks)
+	})
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/principal"
+	"github.com/storacha/go-ucanto/principal/verifier"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// ConcludeAbility is the ability used to conclude a task i.e. to submit the
+// receipt for an effect the server delegated to an agent.
+const ConcludeAbility = "ucan/conclude"
+
+// Conclude is the `ucan/conclude` capability. The resource is the DID of the
+// agent submitting the receipt and the caveats link to the receipt, which must
+// be included in the agent message.
+var Conclude = validator.NewCapability(
+	ConcludeAbility,
+	schema.DIDString(),
+	schema.Struct[sdm.ConcludeModel](sdm.ConcludeType(), nil),
+	validator.DefaultDerives,
+)
+
+// ConclusionHandlerFunc is called with a receipt sent to the server to
+// conclude a task, after it has been verified. The task is the invocation the
+// receipt was issued for.
+type ConclusionHandlerFunc func(ctx context.Context, rcpt receipt.AnyReceipt, task invocation.Invocation, ictx InvocationContext) error
+
+// conclude creates the service method for `ucan/conclude` invocations.
+//
+// The receipt is accepted if:
+//
+//   - The task it was issued for is included and was issued by this service,
+//     or by its receipt signer (see [WithReceiptSigner]).
+//   - It was issued by the audience of the task.
+//   - It is signed by its issuer.
+func conclude(handler ConclusionHandlerFunc) ProvidedMethod[ok.Unit] {
+	return Provide(Conclude, func(ctx context.Context, cap ucan.Capability[sdm.ConcludeModel], inv invocation.Invocation, ictx InvocationContext) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, error) {
+		rcptlnk := cap.Nb().Receipt
+		invalid := func(format string, a ...any) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, error) {
+			return result.Error[ok.Unit, failure.IPLDBuilderFailure](NewInvalidReceiptError(rcptlnk, fmt.Sprintf(format, a...))), nil, nil
+		}
+
+		br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(inv.Blocks()))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, found, err := br.Get(rcptlnk); err != nil {
+			return nil, nil, err
+		} else if !found {
+			return invalid("receipt not found in message")
+		}
+		rcpt, err := receipt.NewAnyReceipt(rcptlnk, br)
+		if err != nil {
+			return invalid("decoding receipt: %s", err.Error())
+		}
+
+		task, found := rcpt.Ran().Invocation()
+		if !found {
+			return invalid("task %s not found in receipt", rcpt.Ran().Link())
+		}
+		// tasks are issued by the service, or by its receipt signer if the
+		// service identity cannot sign
+		tvfr := ictx.ID().Verifier()
+		if signer, _ := signerOf(ictx); task.Issuer().DID() == signer.DID() {
+			tvfr = signer.Verifier()
+		} else if task.Issuer().DID() != ictx.ID().DID() {
+			return invalid("task %s was not issued by %s", task.Link(), ictx.ID().DID())
+		}
+		if _, err := validator.VerifySignature(task, tvfr); err != nil {
+			return invalid("verifying task signature: %s", err.Error())
+		}
+
+		issuer := task.Audience()
+		if rcpt.Issuer() != nil {
+			if rcpt.Issuer().DID() != issuer.DID() {
+				return invalid("receipt issuer %s does not match task audience %s", rcpt.Issuer().DID(), issuer.DID())
+			}
+		}
+
+		vfr, err := receiptVerifier(ctx, issuer, ictx)
+		if err != nil {
+			return invalid("resolving receipt issuer: %s", err.Error())
+		}
+		valid, err := rcpt.VerifySignature(vfr)
+		if err != nil {
+			return invalid("verifying receipt signature: %s", err.Error())
+		}
+		if !valid {
+			return invalid("receipt signature does not verify as issuer %s", issuer.DID())
+		}
+
+		if err := handler(ctx, rcpt, task, ictx); err != nil {
+			return nil, nil, err
+		}
+		return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, nil
+	})
+}
+
+// concludeReported creates a `ucan/conclude` invocation for each receipt
+// reported in the passed message, if the server handles them. The invocations
+// are issued by the receipt signer of the service to the service, and are
+// validated in the same way as invocations sent by agents.
+func concludeReported(server Server[Service], msg message.AgentMessage) ([]invocation.Invocation, error) {
+	if len(msg.Receipts()) == 0 {
+		return nil, nil
+	}
+	if _, ok := server.Service()[ConcludeAbility]; !ok {
+		return nil, nil
+	}
+
+	// the service identity may not be able to sign, see [VerifierIdentity]
+	signer, _ := signerOf(server)
+	var invs []invocation.Invocation
+	for _, rcptlnk := range msg.Receipts() {
+		rcpt, ok, err := msg.Receipt(rcptlnk)
+		if err != nil {
+			return nil, fmt.Errorf("reading reported receipt %s: %w", rcptlnk, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("reported receipt %s not found in message", rcptlnk)
+		}
+		inv, err := invocation.InvokeWithBlocks(signer, server.ID(), Conclude.New(signer.DID().String(), sdm.ConcludeModel{Receipt: rcpt.Root().Link()}), rcpt.Export())
+		if err != nil {
+			return nil, fmt.Errorf("concluding reported receipt %s: %w", rcptlnk, err)
+		}
+		invs = append(invs, inv)
+	}
+	return invs, nil
+}
+
+// receiptVerifier returns a verifier for the passed receipt issuer, resolving
+// non did:key principals.
+func receiptVerifier(ctx context.Context, issuer ucan.Principal, ictx InvocationContext) (principal.Verifier, error) {
+	if issuer.DID() == ictx.ID().DID() {
+		return ictx.ID().Verifier(), nil
+	}
+	if strings.HasPrefix(issuer.DID().String(), "did:key:") {
+		return ictx.ParsePrincipal(issuer.DID().String())
+	}
+
+	key, uerr := ictx.ResolveDIDKey(ctx, issuer.DID())
+	if uerr != nil {
+		return nil, uerr
+	}
+	vfr, err := ictx.ParsePrincipal(key.String())
+	if err != nil {
+		return nil, err
+	}
+	return verifier.Wrap(vfr, issuer.DID())
+}
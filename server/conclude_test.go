@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/ran"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/principal"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConclude(t *testing.T) {
+	var concluded []receipt.AnyReceipt
+	server := helpers.Must(NewServer(
+		fixtures.Service,
+		WithConclusionHandler(func(ctx context.Context, rcpt receipt.AnyReceipt, task invocation.Invocation, ictx InvocationContext) error {
+			require.Equal(t, rcpt.Ran().Link(), task.Link())
+			concluded = append(concluded, rcpt)
+			return nil
+		}),
+	))
+	conn := helpers.Must(client.NewConnection(fixtures.Service, server))
+
+	newTask := func(t *testing.T, issuer principal.Signer, audience ucan.Principal) invocation.Invocation {
+		return helpers.Must(invocation.Invoke(issuer, audience, ucan.NewCapability("test/task", audience.DID().String(), ucan.NoCaveats{})))
+	}
+
+	issue := func(t *testing.T, issuer principal.Signer, task invocation.Invocation) receipt.AnyReceipt {
+		return helpers.Must(receipt.Issue(issuer, result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), ran.FromInvocation(task)))
+	}
+
+	execute := func(t *testing.T, inv invocation.Invocation) result.Result[ipld.Node, ipld.Node] {
+		resp := helpers.Must(client.Execute(t.Context(), []invocation.Invocation{inv}, conn))
+		rcptlnk, ok := resp.Get(inv.Link())
+		require.True(t, ok)
+		rcpt := helpers.Must(receipt.NewAnyReceiptReader().Read(rcptlnk, resp.Blocks()))
+		return rcpt.Out()
+	}
+
+	t.Run("concludes task", func(t *testing.T) {
+		concluded = nil
+		task := newTask(t, fixtures.Service, fixtures.Alice)
+		rcpt := issue(t, fixtures.Alice, task)
+
+		inv := helpers.Must(client.Conclude(fixtures.Alice, fixtures.Service, rcpt))
+		_, x := result.Unwrap(execute(t, inv))
+		require.Nil(t, x)
+		require.Len(t, concluded, 1)
+		require.Equal(t, rcpt.Root().Link(), concluded[0].Root().Link())
+	})
+
+	t.Run("receipt issuer is not task audience", func(t *testing.T) {
+		concluded = nil
+		task := newTask(t, fixtures.Service, fixtures.Alice)
+		rcpt := issue(t, fixtures.Bob, task)
+
+		inv := helpers.Must(client.Conclude(fixtures.Bob, fixtures.Service, rcpt))
+		_, x := result.Unwrap(execute(t, inv))
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidReceipt", *asFailure(t, x).Name)
+		require.Empty(t, concluded)
+	})
+
+	t.Run("task not issued by service", func(t *testing.T) {
+		concluded = nil
+		task := newTask(t, fixtures.Mallory, fixtures.Alice)
+		rcpt := issue(t, fixtures.Alice, task)
+
+		inv := helpers.Must(client.Conclude(fixtures.Alice, fixtures.Service, rcpt))
+		_, x := result.Unwrap(execute(t, inv))
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidReceipt", *asFailure(t, x).Name)
+		require.Empty(t, concluded)
+	})
+
+	t.Run("concludes reported receipt", func(t *testing.T) {
+		concluded = nil
+		task := newTask(t, fixtures.Service, fixtures.Alice)
+		rcpt := issue(t, fixtures.Alice, task)
+
+		msg := helpers.Must(message.Build(nil, []receipt.AnyReceipt{rcpt}))
+		out := helpers.Must(Execute(t.Context(), server, msg))
+		require.Len(t, concluded, 1)
+		require.Equal(t, rcpt.Root().Link(), concluded[0].Root().Link())
+
+		require.Len(t, out.Receipts(), 1)
+		ack, ok, err := out.Receipt(out.Receipts()[0])
+		require.NoError(t, err)
+		require.True(t, ok)
+		_, x := result.Unwrap(ack.Out())
+		require.Nil(t, x)
+		inv, ok := ack.Ran().Invocation()
+		require.True(t, ok)
+		require.Equal(t, ConcludeAbility, inv.Capabilities()[0].Can())
+	})
+
+	t.Run("reported receipt is verified", func(t *testing.T) {
+		concluded = nil
+		task := newTask(t, fixtures.Service, fixtures.Alice)
+		rcpt := issue(t, fixtures.Bob, task)
+
+		msg := helpers.Must(message.Build(nil, []receipt.AnyReceipt{rcpt}))
+		out := helpers.Must(Execute(t.Context(), server, msg))
+		require.Empty(t, concluded)
+
+		require.Len(t, out.Receipts(), 1)
+		ack, ok, err := out.Receipt(out.Receipts()[0])
+		require.NoError(t, err)
+		require.True(t, ok)
+		_, x := result.Unwrap(ack.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidReceipt", *asFailure(t, x).Name)
+	})
+
+	t.Run("reported receipts are ignored if not handled", func(t *testing.T) {
+		task := newTask(t, fixtures.Service, fixtures.Alice)
+		rcpt := issue(t, fixtures.Alice, task)
+
+		msg := helpers.Must(message.Build(nil, []receipt.AnyReceipt{rcpt}))
+		out := helpers.Must(Execute(t.Context(), newUploadAddServer(t, nil), msg))
+		require.Empty(t, out.Receipts())
+	})
+
+	t.Run("receipt not included", func(t *testing.T) {
+		concluded = nil
+		task := newTask(t, fixtures.Service, fixtures.Alice)
+		rcpt := issue(t, fixtures.Alice, task)
+
+		inv := helpers.Must(Conclude.Invoke(fixtures.Alice, fixtures.Service, fixtures.Alice.DID().String(), sdm.ConcludeModel{Receipt: rcpt.Root().Link()}))
+		_, x := result.Unwrap(execute(t, inv))
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidReceipt", *asFailure(t, x).Name)
+		require.Empty(t, concluded)
+	})
+}
@@ -0,0 +1,36 @@
+package datamodel
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/schema"
+	ucanipld "github.com/storacha/go-ucanto/core/ipld"
+)
+
+//go:embed conclude.ipldsch
+var concludesch []byte
+var concludeTypeSystem *schema.TypeSystem
+
+func init() {
+	ts, err := ipld.LoadSchemaBytes(concludesch)
+	if err != nil {
+		panic(fmt.Errorf("failed to load IPLD schema: %w", err))
+	}
+	concludeTypeSystem = ts
+}
+
+func ConcludeType() schema.Type {
+	return concludeTypeSystem.TypeByName("Conclude")
+}
+
+// ConcludeModel is the caveats of a `ucan/conclude` invocation.
+type ConcludeModel struct {
+	// Receipt is the link to the receipt being concluded.
+	Receipt ipld.Link
+}
+
+func (m ConcludeModel) ToIPLD() (ipld.Node, error) {
+	return ucanipld.WrapWithRecovery(&m, ConcludeType())
+}
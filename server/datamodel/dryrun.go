@@ -0,0 +1,64 @@
+package datamodel
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/schema"
+	ucanipld "github.com/storacha/go-ucanto/core/ipld"
+)
+
+//go:embed dryrun.ipldsch
+var dryrunsch []byte
+var dryRunTypeSystem *schema.TypeSystem
+
+func init() {
+	ts, err := ipld.LoadSchemaBytes(dryrunsch)
+	if err != nil {
+		panic(fmt.Errorf("failed to load IPLD schema: %w", err))
+	}
+	dryRunTypeSystem = ts
+}
+
+func DryRunType() schema.Type {
+	return dryRunTypeSystem.TypeByName("DryRun")
+}
+
+// DryRunModel is the caveats of a `ucanto/dry-run` invocation.
+type DryRunModel struct {
+	// Invocation is the link to the invocation to authorize.
+	Invocation ipld.Link
+}
+
+func (m DryRunModel) ToIPLD() (ipld.Node, error) {
+	return ucanipld.WrapWithRecovery(&m, DryRunType())
+}
+
+func DryRunOkType() schema.Type {
+	return dryRunTypeSystem.TypeByName("DryRunOk")
+}
+
+// DryRunOkModel is the result of a dry run of an invocation that would be
+// authorized.
+type DryRunOkModel struct {
+	Authorization AuthorizationModel
+}
+
+func (m DryRunOkModel) ToIPLD() (ipld.Node, error) {
+	return ucanipld.WrapWithRecovery(&m, DryRunOkType())
+}
+
+// AuthorizationModel is an authorization resolved by the validator. The
+// delegation is the invocation itself at the root of the chain, and the
+// proofs are the authorizations of the delegations it was authorized by.
+// Attestations are the `ucan/attest` delegations used to authorize a non
+// did:key issuer.
+type AuthorizationModel struct {
+	Delegation   ipld.Link
+	Issuer       string
+	Audience     string
+	Capability   CapabilityModel
+	Proofs       []AuthorizationModel
+	Attestations []ipld.Link
+}
@@ -83,3 +83,82 @@ type InvalidAudienceErrorModel struct {
 	Name    *string
 	Message string
 }
+
+func ReplayedErrorType() schema.Type {
+	return errorTypeSystem.TypeByName("ReplayedError")
+}
+
+type ReplayedErrorModel struct {
+	Error      bool
+	Name       *string
+	Message    string
+	Invocation ipld.Link
+}
+
+func InvalidExpirationErrorType() schema.Type {
+	return errorTypeSystem.TypeByName("InvalidExpirationError")
+}
+
+type InvalidExpirationErrorModel struct {
+	Error      bool
+	Name       *string
+	Message    string
+	Invocation ipld.Link
+}
+
+func InvalidReceiptErrorType() schema.Type {
+	return errorTypeSystem.TypeByName("InvalidReceiptError")
+}
+
+type InvalidReceiptErrorModel struct {
+	Error   bool
+	Name    *string
+	Message string
+	Receipt ipld.Link
+}
+
+func RateLimitedErrorType() schema.Type {
+	return errorTypeSystem.TypeByName("RateLimitedError")
+}
+
+type RateLimitedErrorModel struct {
+	Error   bool
+	Name    *string
+	Message string
+	// RetryAfter is the number of seconds after which the invocation may be
+	// retried.
+	RetryAfter int64
+}
+
+func TimeoutErrorType() schema.Type {
+	return errorTypeSystem.TypeByName("TimeoutError")
+}
+
+type TimeoutErrorModel struct {
+	Error      bool
+	Name       *string
+	Message    string
+	Capability CapabilityModel
+}
+
+func InvalidDryRunErrorType() schema.Type {
+	return errorTypeSystem.TypeByName("InvalidDryRunError")
+}
+
+type InvalidDryRunErrorModel struct {
+	Error      bool
+	Name       *string
+	Message    string
+	Invocation ipld.Link
+}
+
+func InvalidRevocationErrorType() schema.Type {
+	return errorTypeSystem.TypeByName("InvalidRevocationError")
+}
+
+type InvalidRevocationErrorModel struct {
+	Error      bool
+	Name       *string
+	Message    string
+	Delegation ipld.Link
+}
@@ -0,0 +1,45 @@
+package datamodel
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/schema"
+	ucanipld "github.com/storacha/go-ucanto/core/ipld"
+)
+
+//go:embed introspect.ipldsch
+var introspectsch []byte
+var introspectTypeSystem *schema.TypeSystem
+
+func init() {
+	ts, err := ipld.LoadSchemaBytes(introspectsch)
+	if err != nil {
+		panic(fmt.Errorf("failed to load IPLD schema: %w", err))
+	}
+	introspectTypeSystem = ts
+}
+
+func IntrospectOkType() schema.Type {
+	return introspectTypeSystem.TypeByName("IntrospectOk")
+}
+
+// IntrospectOkModel is the result of a successful introspection invocation.
+type IntrospectOkModel struct {
+	Abilities []AbilityDescriptionModel
+}
+
+func (m IntrospectOkModel) ToIPLD() (ipld.Node, error) {
+	return ucanipld.WrapWithRecovery(&m, IntrospectOkType())
+}
+
+// AbilityDescriptionModel describes an ability supported by a service. The
+// caveats (nb), ok and error types are IPLD schema DSL.
+type AbilityDescriptionModel struct {
+	Can   string
+	With  *string
+	Nb    *string
+	Ok    *string
+	Error *string
+}
@@ -0,0 +1,36 @@
+package datamodel
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/schema"
+	ucanipld "github.com/storacha/go-ucanto/core/ipld"
+)
+
+//go:embed revoke.ipldsch
+var revokesch []byte
+var revokeTypeSystem *schema.TypeSystem
+
+func init() {
+	ts, err := ipld.LoadSchemaBytes(revokesch)
+	if err != nil {
+		panic(fmt.Errorf("failed to load IPLD schema: %w", err))
+	}
+	revokeTypeSystem = ts
+}
+
+func RevokeType() schema.Type {
+	return revokeTypeSystem.TypeByName("Revoke")
+}
+
+// RevokeModel is the caveats of a `ucan/revoke` invocation.
+type RevokeModel struct {
+	// Ucan is the link to the delegation being revoked.
+	Ucan ipld.Link
+}
+
+func (m RevokeModel) ToIPLD() (ipld.Node, error) {
+	return ucanipld.WrapWithRecovery(&m, RevokeType())
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// DryRunAbility is the ability used to ask a service whether an invocation
+// would be authorized, without executing it.
+const DryRunAbility = "ucanto/dry-run"
+
+// DryRun is the `ucanto/dry-run` capability. The resource is the DID of the
+// agent requesting the dry run and the caveats link to the invocation to
+// authorize, which must be included in the agent message.
+var DryRun = validator.NewCapability(
+	DryRunAbility,
+	schema.DIDString(),
+	schema.Struct[sdm.DryRunModel](sdm.DryRunType(), nil),
+	validator.DefaultDerives,
+)
+
+// authorizeMethod creates the service method that takes the place of the
+// passed provided method in dry runs. It authorizes invocations without
+// executing them, and succeeds with the authorization.
+func authorizeMethod(p provider) ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure] {
+	return func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+		auth, x := p.Authorize(ctx, inv, ictx)
+		if x != nil {
+			return transaction.NewTransaction(result.Error[ipld.Builder](x)), nil
+		}
+		return transaction.NewTransaction(result.Ok[ipld.Builder, failure.IPLDBuilderFailure](sdm.DryRunOkModel{
+			Authorization: authorizationModel(auth),
+		})), nil
+	}
+}
+
+// dryRunMethodResolver returns the method that authorizes the passed
+// invocation in a dry run, or false if it cannot be dry run.
+type dryRunMethodResolver func(inv invocation.Invocation, can ucan.Ability) (ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure], bool)
+
+// dryRun creates the service method for `ucanto/dry-run` invocations.
+//
+// The invocation is authorized by the method resolved for its ability in the
+// same way as it would be executed, including fallbacks and interceptors, with
+// the invocation context of the service. The method is resolved from the
+// methods created by [Provide], which authorize invocations without calling
+// their handler (see [authorizeMethod]).
+func dryRun(resolve dryRunMethodResolver) ProvidedMethod[sdm.DryRunOkModel] {
+	return Provide(DryRun, func(ctx context.Context, cap ucan.Capability[sdm.DryRunModel], inv invocation.Invocation, ictx InvocationContext) (result.Result[sdm.DryRunOkModel, failure.IPLDBuilderFailure], fx.Effects, error) {
+		invlnk := cap.Nb().Invocation
+		invalid := func(format string, a ...any) (result.Result[sdm.DryRunOkModel, failure.IPLDBuilderFailure], fx.Effects, error) {
+			return result.Error[sdm.DryRunOkModel, failure.IPLDBuilderFailure](NewInvalidDryRunError(invlnk, fmt.Sprintf(format, a...))), nil, nil
+		}
+
+		br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(inv.Blocks()))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, found, err := br.Get(invlnk); err != nil {
+			return nil, nil, err
+		} else if !found {
+			return invalid("invocation not found in message")
+		}
+		target, err := invocation.NewInvocationView(invlnk, br)
+		if err != nil {
+			return invalid("decoding invocation: %s", err.Error())
+		}
+
+		invoked, ok := invokedCapability(target.Capabilities())
+		if !ok {
+			return invalid("invocation has %d capabilities, expected 1 or a group on the same resource", len(target.Capabilities()))
+		}
+		method, ok := resolve(target, invoked.Can())
+		if !ok {
+			return invalid("ability %s cannot be dry run", invoked.Can())
+		}
+
+		tx, err := method(ctx, target, ictx)
+		if err != nil {
+			return nil, nil, err
+		}
+		o, x := result.Unwrap(tx.Out())
+		if x != nil {
+			return result.Error[sdm.DryRunOkModel](x), nil, nil
+		}
+		// an interceptor may succeed without calling the method
+		model, ok := o.(sdm.DryRunOkModel)
+		if !ok {
+			return invalid("invocation of %s was handled without being authorized", invoked.Can())
+		}
+		return result.Ok[sdm.DryRunOkModel, failure.IPLDBuilderFailure](model), nil, nil
+	})
+}
+
+// authorizationModel converts an authorization to its data model.
+func authorizationModel(auth validator.Authorization[any]) sdm.AuthorizationModel {
+	model := sdm.AuthorizationModel{
+		Delegation: auth.Delegation().Link(),
+		Issuer:     auth.Issuer().DID().String(),
+		Audience:   auth.Audience().DID().String(),
+		Capability: sdm.CapabilityModel{
+			Can:  auth.Capability().Can(),
+			With: auth.Capability().With(),
+		},
+		Proofs:       []sdm.AuthorizationModel{},
+		Attestations: []ipld.Link{},
+	}
+	for _, p := range auth.Proofs() {
+		model.Proofs = append(model.Proofs, authorizationModel(p))
+	}
+	for _, a := range auth.Attestations() {
+		model.Attestations = append(model.Attestations, a.Delegation().Link())
+	}
+	return model
+}
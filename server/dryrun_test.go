@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	var handled, intercepted, raw atomic.Int64
+	server := newUploadAddServer(t, uploadAddOk(&handled),
+		WithDryRun(),
+		WithServiceMethod("store/add", ServiceMethod[ok.Unit, failure.IPLDBuilderFailure](func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (transaction.Transaction[ok.Unit, failure.IPLDBuilderFailure], error) {
+			raw.Add(1)
+			return transaction.NewTransaction(result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{})), nil
+		})),
+		WithInterceptor(func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext, next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+			if inv.Capabilities()[0].Can() == DryRunAbility {
+				return next(ctx, inv, ictx)
+			}
+			intercepted.Add(1)
+			if inv.Issuer().DID() == fixtures.Bob.DID() {
+				return transaction.NewTransaction(result.Error[ipld.Builder](failure.FromError(errors.New("denied")))), nil
+			}
+			return next(ctx, inv, ictx)
+		}),
+	)
+
+	dryRun := func(t *testing.T, srv ServerView[Service], inv invocation.Invocation) (sdm.DryRunOkModel, ipld.Node) {
+		handled.Store(0)
+		dryrun := helpers.Must(client.DryRun(fixtures.Mallory, fixtures.Service, inv))
+		o, x := result.Unwrap(executeInvocation(t, srv, dryrun).Out())
+		require.Zero(t, handled.Load())
+		if x != nil {
+			return sdm.DryRunOkModel{}, x
+		}
+		return helpers.Must(ipld.Rebind[sdm.DryRunOkModel](o, sdm.DryRunOkType())), nil
+	}
+
+	t.Run("self issued", func(t *testing.T) {
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+
+		ok, x := dryRun(t, server, inv)
+		require.Nil(t, x)
+		require.Equal(t, inv.Link(), ok.Authorization.Delegation)
+		require.Equal(t, fixtures.Alice.DID().String(), ok.Authorization.Issuer)
+		require.Equal(t, fixtures.Service.DID().String(), ok.Authorization.Audience)
+		require.Equal(t, sdm.CapabilityModel{Can: "upload/add", With: fixtures.Alice.DID().String()}, ok.Authorization.Capability)
+		require.Empty(t, ok.Authorization.Proofs)
+	})
+
+	t.Run("delegated", func(t *testing.T) {
+		dlg := helpers.Must(delegation.Delegate(
+			fixtures.Bob,
+			fixtures.Alice,
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/add", fixtures.Bob.DID().String(), ucan.NoCaveats{})},
+		))
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Bob.DID().String(), delegation.WithProof(delegation.FromDelegation(dlg)))
+
+		ok, x := dryRun(t, server, inv)
+		require.Nil(t, x)
+		require.Equal(t, inv.Link(), ok.Authorization.Delegation)
+		require.Len(t, ok.Authorization.Proofs, 1)
+		prf := ok.Authorization.Proofs[0]
+		require.Equal(t, dlg.Link(), prf.Delegation)
+		require.Equal(t, fixtures.Bob.DID().String(), prf.Issuer)
+		require.Equal(t, fixtures.Alice.DID().String(), prf.Audience)
+		require.Empty(t, prf.Proofs)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Bob.DID().String())
+
+		_, x := dryRun(t, server, inv)
+		require.NotNil(t, x)
+		f := asFailure(t, x)
+		require.Equal(t, "Unauthorized", *f.Name)
+		require.Contains(t, f.Message, fixtures.Bob.DID().String())
+
+		// executing the invocation fails with the same error
+		_, x = result.Unwrap(executeInvocation(t, server, inv).Out())
+		require.NotNil(t, x)
+		require.Equal(t, f.Message, asFailure(t, x).Message)
+	})
+
+	t.Run("invalid audience", func(t *testing.T) {
+		inv := helpers.Must(uploadAdd.Invoke(fixtures.Alice, fixtures.Bob, fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}))
+
+		_, x := dryRun(t, server, inv)
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidAudienceError", *asFailure(t, x).Name)
+	})
+
+	t.Run("runs interceptors", func(t *testing.T) {
+		intercepted.Store(0)
+		inv := newUploadAddInvocation(t, fixtures.Bob, fixtures.Bob.DID().String())
+
+		_, x := dryRun(t, server, inv)
+		require.NotNil(t, x)
+		require.Equal(t, "denied", asFailure(t, x).Message)
+		require.Equal(t, int64(1), intercepted.Load())
+	})
+
+	t.Run("unknown ability", func(t *testing.T) {
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, ucan.NewCapability("space/info", fixtures.Alice.DID().String(), ucan.NoCaveats{})))
+
+		_, x := dryRun(t, server, inv)
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidDryRun", *asFailure(t, x).Name)
+	})
+
+	t.Run("method not created by Provide", func(t *testing.T) {
+		raw.Store(0)
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, ucan.NewCapability("store/add", fixtures.Alice.DID().String(), ucan.NoCaveats{})))
+
+		_, x := dryRun(t, server, inv)
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidDryRun", *asFailure(t, x).Name)
+		require.Zero(t, raw.Load())
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		server := helpers.Must(NewServer(fixtures.Service, WithDryRun(), WithFallback(Provide(uploadAdd, uploadAddOk(&handled)))))
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+
+		ok, x := dryRun(t, server, inv)
+		require.Nil(t, x)
+		require.Equal(t, inv.Link(), ok.Authorization.Delegation)
+	})
+
+	t.Run("dry run of dry run", func(t *testing.T) {
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+		dryrun := helpers.Must(client.DryRun(fixtures.Alice, fixtures.Service, inv))
+
+		_, x := dryRun(t, server, dryrun)
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidDryRun", *asFailure(t, x).Name)
+	})
+
+	t.Run("invocation not included", func(t *testing.T) {
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+		dryrun := helpers.Must(DryRun.Invoke(fixtures.Mallory, fixtures.Service, fixtures.Mallory.DID().String(), sdm.DryRunModel{Invocation: inv.Link()}))
+
+		require.Equal(t, "InvalidDryRun", receiptFailure(t, executeInvocation(t, server, dryrun)))
+	})
+
+	t.Run("executes invocation", func(t *testing.T) {
+		handled.Store(0)
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+
+		require.Equal(t, "", receiptFailure(t, executeInvocation(t, server, inv)))
+		require.Equal(t, int64(1), handled.Load())
+	})
+}
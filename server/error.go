@@ -2,7 +2,9 @@ package server
 
 import (
 	"fmt"
+	"math"
 	"strings"
+	"time"
 
 	"github.com/storacha/go-ucanto/core/ipld"
 	"github.com/storacha/go-ucanto/core/result/failure"
@@ -197,3 +199,288 @@ func (i InvalidAudienceError) ToIPLD() (ipld.Node, error) {
 func NewInvalidAudienceError(actual ucan.Principal, expected ...ucan.Principal) InvalidAudienceError {
 	return InvalidAudienceError{expected, actual}
 }
+
+// Replayed is a failure returned when an invocation that has already been
+// executed is received again before it expires.
+type Replayed interface {
+	failure.IPLDBuilderFailure
+	Invocation() ipld.Link
+}
+
+type replayedError struct {
+	invocation ipld.Link
+}
+
+func (r replayedError) Invocation() ipld.Link {
+	return r.invocation
+}
+
+func (r replayedError) Error() string {
+	return fmt.Sprintf("Invocation %s has already been executed", r.invocation)
+}
+
+func (r replayedError) Name() string {
+	return "Replayed"
+}
+
+func (r replayedError) ToIPLD() (ipld.Node, error) {
+	name := r.Name()
+	mdl := sdm.ReplayedErrorModel{
+		Error:      true,
+		Name:       &name,
+		Message:    r.Error(),
+		Invocation: r.invocation,
+	}
+	return ipld.WrapWithRecovery(&mdl, sdm.ReplayedErrorType())
+}
+
+func NewReplayedError(invocation ipld.Link) Replayed {
+	return replayedError{invocation}
+}
+
+// InvalidExpiration is a failure returned by replay protection (see
+// [ReplayProtection]) for an invocation that does not expire, or that expires
+// later than replays of it are detected.
+type InvalidExpiration interface {
+	failure.IPLDBuilderFailure
+	Invocation() ipld.Link
+}
+
+type invalidExpirationError struct {
+	invocation   ipld.Link
+	maxRetention time.Duration
+}
+
+func (i invalidExpirationError) Invocation() ipld.Link {
+	return i.invocation
+}
+
+func (i invalidExpirationError) Error() string {
+	return fmt.Sprintf("Invocation %s must expire within %s", i.invocation, i.maxRetention)
+}
+
+func (i invalidExpirationError) Name() string {
+	return "InvalidExpiration"
+}
+
+func (i invalidExpirationError) ToIPLD() (ipld.Node, error) {
+	name := i.Name()
+	mdl := sdm.InvalidExpirationErrorModel{
+		Error:      true,
+		Name:       &name,
+		Message:    i.Error(),
+		Invocation: i.invocation,
+	}
+	return ipld.WrapWithRecovery(&mdl, sdm.InvalidExpirationErrorType())
+}
+
+func NewInvalidExpirationError(invocation ipld.Link, maxRetention time.Duration) InvalidExpiration {
+	return invalidExpirationError{invocation, maxRetention}
+}
+
+// InvalidReceipt is a failure returned when a receipt sent to the server to
+// conclude a task cannot be accepted.
+type InvalidReceipt interface {
+	failure.IPLDBuilderFailure
+	Receipt() ipld.Link
+}
+
+type invalidReceiptError struct {
+	receipt ipld.Link
+	message string
+}
+
+func (i invalidReceiptError) Receipt() ipld.Link {
+	return i.receipt
+}
+
+func (i invalidReceiptError) Error() string {
+	return fmt.Sprintf("Invalid receipt %s: %s", i.receipt, i.message)
+}
+
+func (i invalidReceiptError) Name() string {
+	return "InvalidReceipt"
+}
+
+func (i invalidReceiptError) ToIPLD() (ipld.Node, error) {
+	name := i.Name()
+	mdl := sdm.InvalidReceiptErrorModel{
+		Error:   true,
+		Name:    &name,
+		Message: i.Error(),
+		Receipt: i.receipt,
+	}
+	return ipld.WrapWithRecovery(&mdl, sdm.InvalidReceiptErrorType())
+}
+
+func NewInvalidReceiptError(receipt ipld.Link, message string) InvalidReceipt {
+	return invalidReceiptError{receipt, message}
+}
+
+// InvalidDryRun is a failure returned when a dry run of an invocation cannot be
+// performed, for example because the invocation is not included in the agent
+// message. Invocations that would not be authorized fail with the error they
+// would fail with if executed instead.
+type InvalidDryRun interface {
+	failure.IPLDBuilderFailure
+	Invocation() ipld.Link
+}
+
+type invalidDryRunError struct {
+	invocation ipld.Link
+	message    string
+}
+
+func (i invalidDryRunError) Invocation() ipld.Link {
+	return i.invocation
+}
+
+func (i invalidDryRunError) Error() string {
+	return fmt.Sprintf("Invalid dry run of %s: %s", i.invocation, i.message)
+}
+
+func (i invalidDryRunError) Name() string {
+	return "InvalidDryRun"
+}
+
+func (i invalidDryRunError) ToIPLD() (ipld.Node, error) {
+	name := i.Name()
+	mdl := sdm.InvalidDryRunErrorModel{
+		Error:      true,
+		Name:       &name,
+		Message:    i.Error(),
+		Invocation: i.invocation,
+	}
+	return ipld.WrapWithRecovery(&mdl, sdm.InvalidDryRunErrorType())
+}
+
+func NewInvalidDryRunError(invocation ipld.Link, message string) InvalidDryRun {
+	return invalidDryRunError{invocation, message}
+}
+
+// InvalidRevocation is a failure returned when a revocation sent to the server
+// cannot be accepted, for example because the revoker is not an issuer in the
+// proof chain of the delegation.
+type InvalidRevocation interface {
+	failure.IPLDBuilderFailure
+	Delegation() ipld.Link
+}
+
+type invalidRevocationError struct {
+	delegation ipld.Link
+	message    string
+}
+
+func (i invalidRevocationError) Delegation() ipld.Link {
+	return i.delegation
+}
+
+func (i invalidRevocationError) Error() string {
+	return fmt.Sprintf("Invalid revocation of %s: %s", i.delegation, i.message)
+}
+
+func (i invalidRevocationError) Name() string {
+	return "InvalidRevocation"
+}
+
+func (i invalidRevocationError) ToIPLD() (ipld.Node, error) {
+	name := i.Name()
+	mdl := sdm.InvalidRevocationErrorModel{
+		Error:      true,
+		Name:       &name,
+		Message:    i.Error(),
+		Delegation: i.delegation,
+	}
+	return ipld.WrapWithRecovery(&mdl, sdm.InvalidRevocationErrorType())
+}
+
+func NewInvalidRevocationError(delegation ipld.Link, message string) InvalidRevocation {
+	return invalidRevocationError{delegation, message}
+}
+
+// RateLimited is a failure returned when an invocation exceeds a rate limit
+// configured on the server.
+type RateLimited interface {
+	failure.IPLDBuilderFailure
+	// RetryAfter is the time after which the invocation may succeed if retried.
+	RetryAfter() time.Duration
+}
+
+type rateLimitedError struct {
+	capability ucan.Capability[any]
+	retryAfter time.Duration
+}
+
+func (r rateLimitedError) RetryAfter() time.Duration {
+	return r.retryAfter
+}
+
+func (r rateLimitedError) Error() string {
+	return fmt.Sprintf("Rate limit exceeded for %s on %s, retry after %s", r.capability.Can(), r.capability.With(), r.retryAfter)
+}
+
+func (r rateLimitedError) Name() string {
+	return "RateLimited"
+}
+
+func (r rateLimitedError) ToIPLD() (ipld.Node, error) {
+	name := r.Name()
+	mdl := sdm.RateLimitedErrorModel{
+		Error:      true,
+		Name:       &name,
+		Message:    r.Error(),
+		RetryAfter: int64(math.Ceil(r.retryAfter.Seconds())),
+	}
+	return ipld.WrapWithRecovery(&mdl, sdm.RateLimitedErrorType())
+}
+
+func NewRateLimitedError(capability ucan.Capability[any], retryAfter time.Duration) RateLimited {
+	return rateLimitedError{capability, retryAfter}
+}
+
+// Timeout is a failure returned when a handler does not complete within the
+// timeout configured for the ability.
+type Timeout interface {
+	failure.IPLDBuilderFailure
+	Capability() ucan.Capability[any]
+	Timeout() time.Duration
+}
+
+type timeoutError struct {
+	capability ucan.Capability[any]
+	timeout    time.Duration
+}
+
+func (t timeoutError) Capability() ucan.Capability[any] {
+	return t.capability
+}
+
+func (t timeoutError) Timeout() time.Duration {
+	return t.timeout
+}
+
+func (t timeoutError) Error() string {
+	return fmt.Sprintf("service handler {can: \"%s\"} did not complete within %s", t.capability.Can(), t.timeout)
+}
+
+func (t timeoutError) Name() string {
+	return "Timeout"
+}
+
+func (t timeoutError) ToIPLD() (ipld.Node, error) {
+	name := t.Name()
+	mdl := sdm.TimeoutErrorModel{
+		Error:   true,
+		Name:    &name,
+		Message: t.Error(),
+		Capability: sdm.CapabilityModel{
+			Can:  t.capability.Can(),
+			With: t.capability.With(),
+		},
+	}
+	return ipld.WrapWithRecovery(&mdl, sdm.TimeoutErrorType())
+}
+
+func NewTimeoutError(capability ucan.Capability[any], timeout time.Duration) Timeout {
+	return timeoutError{capability, timeout}
+}
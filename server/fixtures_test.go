@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadAdd is the `upload/add` capability provided by the test service.
+var uploadAdd = validator.NewCapability(
+	"upload/add",
+	schema.DIDString(),
+	schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+	nil,
+)
+
+// uploadAddHandler is a handler of `upload/add` invocations.
+type uploadAddHandler = HandlerFunc[uploadAddCaveats, uploadAddSuccess, uploadAddFailure]
+
+// uploadAddOk returns a handler that succeeds, counting its calls in the passed
+// counter if not nil.
+func uploadAddOk(calls *atomic.Int64) uploadAddHandler {
+	return func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+		if calls != nil {
+			calls.Add(1)
+		}
+		return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+	}
+}
+
+// newUploadAddServer creates a server of the test service that handles
+// `upload/add` invocations with the passed handler, or [uploadAddOk] if nil.
+func newUploadAddServer(t *testing.T, handler uploadAddHandler, options ...Option) ServerView[Service] {
+	t.Helper()
+	if handler == nil {
+		handler = uploadAddOk(nil)
+	}
+	options = append([]Option{WithServiceMethod(uploadAdd.Can(), Provide(uploadAdd, handler))}, options...)
+	return helpers.Must(NewServer(fixtures.Service, options...))
+}
+
+// newUploadAddInvocation creates an `upload/add` invocation of a random root
+// on the passed resource, addressed to the test service.
+func newUploadAddInvocation(t *testing.T, issuer ucan.Signer, with ucan.Resource, options ...delegation.Option) invocation.Invocation {
+	t.Helper()
+	return helpers.Must(uploadAdd.Invoke(issuer, fixtures.Service, with, uploadAddCaveats{Root: helpers.RandomCID()}, options...))
+}
+
+// executeInvocation sends the passed invocation to the server and returns the
+// receipt it issued.
+func executeInvocation(t *testing.T, srv ServerView[Service], inv invocation.Invocation) receipt.AnyReceipt {
+	t.Helper()
+	conn := helpers.Must(client.NewConnection(fixtures.Service, srv))
+	resp := helpers.Must(client.Execute(t.Context(), []invocation.Invocation{inv}, conn))
+	rcptlnk, ok := resp.Get(inv.Link())
+	require.True(t, ok)
+	return helpers.Must(receipt.NewAnyReceiptReader().Read(rcptlnk, resp.Blocks()))
+}
+
+// receiptFailure returns the name of the failure of the passed receipt, or an
+// empty string if it succeeded.
+func receiptFailure(t *testing.T, rcpt receipt.AnyReceipt) string {
+	t.Helper()
+	_, x := result.Unwrap(rcpt.Out())
+	if x == nil {
+		return ""
+	}
+	f := asFailure(t, x)
+	require.NotNil(t, f.Name)
+	return *f.Name
+}
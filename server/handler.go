@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	ipldschema "github.com/ipld/go-ipld-prime/schema"
 	"github.com/storacha/go-ucanto/core/invocation"
 	"github.com/storacha/go-ucanto/core/ipld"
 	"github.com/storacha/go-ucanto/core/receipt/fx"
@@ -12,8 +15,14 @@ import (
 	"github.com/storacha/go-ucanto/server/transaction"
 	"github.com/storacha/go-ucanto/ucan"
 	"github.com/storacha/go-ucanto/validator"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrInvalidResult is the error returned by a service method when the result
+// of its handler does not conform to the result types declared by the
+// capability (see [validator.WithResultTypes]).
+var ErrInvalidResult = errors.New("invalid handler result")
+
 type HandlerFunc[C any, O ipld.Builder, X failure.IPLDBuilderFailure] func(
 	ctx context.Context,
 	capability ucan.Capability[C],
@@ -24,11 +33,21 @@ type HandlerFunc[C any, O ipld.Builder, X failure.IPLDBuilderFailure] func(
 // Provide is used to define given capability provider. It decorates the passed
 // handler and takes care of UCAN validation. It only calls the handler
 // when validation succeeds.
+//
+// The returned [ProvidedMethod] describes the capability (see [Abilities]) and
+// supports dry runs (see [WithDryRun]), in which the invocation is validated
+// but the handler is not called. Authorized invocations are recorded for replay
+// protection (see [ReplayProtection]).
+//
+// If the capability declares the types of its results (see
+// [validator.ResultTyped]), the result of the handler is validated against
+// them and the service method fails with [ErrInvalidResult] if it does not
+// conform.
 func Provide[C any, O ipld.Builder, X failure.IPLDBuilderFailure](
 	capability validator.CapabilityParser[C],
 	handler HandlerFunc[C, O, X],
-) ServiceMethod[O, failure.IPLDBuilderFailure] {
-	return func(ctx context.Context, invocation invocation.Invocation, ictx InvocationContext) (transaction.Transaction[O, failure.IPLDBuilderFailure], error) {
+) ProvidedMethod[O] {
+	authorize := func(ctx context.Context, invocation invocation.Invocation, ictx InvocationContext) (validator.Authorization[C], failure.IPLDBuilderFailure) {
 		vctx := validator.NewValidationContext(
 			ictx.ID().Verifier(),
 			capability,
@@ -40,6 +59,9 @@ func Provide[C any, O ipld.Builder, X failure.IPLDBuilderFailure](
 			ictx.ValidateTimeBounds,
 			ictx.AuthorityProofs()...,
 		)
+		if vc, ok := ictx.(validator.VerificationCacher); ok && vc.VerificationCache() != nil {
+			vctx = validator.WithVerificationCache(vctx, vc.VerificationCache())
+		}
 
 		// confirm the audience of the invocation is this service or any of the configured alternative audiences
 		acceptedAudiences := schema.Literal(ictx.ID().DID().String())
@@ -54,19 +76,60 @@ func Provide[C any, O ipld.Builder, X failure.IPLDBuilderFailure](
 
 		if _, err := acceptedAudiences.Read(invocation.Audience().DID().String()); err != nil {
 			expectedAudiences := append([]ucan.Principal{ictx.ID()}, ictx.AlternativeAudiences()...)
-			audErr := NewInvalidAudienceError(invocation.Audience(), expectedAudiences...)
-			return transaction.NewTransaction(result.Error[O, failure.IPLDBuilderFailure](audErr)), nil
+			return nil, NewInvalidAudienceError(invocation.Audience(), expectedAudiences...)
 		}
 
 		auth, aerr := validator.Access(ctx, invocation, vctx)
 		if aerr != nil {
-			return transaction.NewTransaction(result.Error[O](failure.FromError(aerr))), nil
+			return nil, failure.FromError(aerr)
+		}
+		return auth, nil
+	}
+
+	execute := func(ctx context.Context, invocation invocation.Invocation, ictx InvocationContext) (transaction.Transaction[O, failure.IPLDBuilderFailure], error) {
+		auth, x := authorize(ctx, invocation, ictx)
+		if x != nil {
+			return transaction.NewTransaction(result.Error[O](x)), nil
+		}
+
+		if rl, ok := ictx.(rateLimiter); ok {
+			cap := auth.Capability()
+			anycap := ucan.NewCapability[any](cap.Can(), cap.With(), cap.Nb())
+			wait, err := rl.takeToken(ctx, invocation.Issuer().DID(), anycap)
+			if err != nil {
+				return nil, err
+			}
+			if wait > 0 {
+				rlerr := NewRateLimitedError(anycap, wait)
+				return transaction.NewTransaction(result.Error[O, failure.IPLDBuilderFailure](rlerr)), nil
+			}
+		}
+
+		guard, replayed, err := guardReplay(ctx, invocation)
+		if err != nil {
+			return nil, err
+		}
+		if replayed != nil {
+			return transaction.NewTransaction(result.Error[O, failure.IPLDBuilderFailure](replayed)), nil
 		}
 
-		res, fx, herr := handler(ctx, auth.Capability(), invocation, ictx)
+		hctx, span := tracer.Start(ctx, "ucanto.server.Handler", trace.WithAttributes(AbilityKey.String(capability.Can())))
+		res, fx, herr := handler(hctx, auth.Capability(), invocation, ictx)
+		if herr == nil {
+			if rt, ok := capability.(validator.ResultTyped); ok {
+				herr = validateResult(res, rt.OkType(), rt.ErrorType())
+			}
+		}
 		if herr != nil {
+			RecordError(span, herr)
+			span.End()
+			// the invocation was not executed, so it may be retried
+			if rerr := guard.release(ctx); rerr != nil {
+				herr = errors.Join(herr, rerr)
+			}
 			return nil, herr
 		}
+		span.End()
 
 		return transaction.NewTransaction(
 			result.MapResultR0(
@@ -77,4 +140,41 @@ func Provide[C any, O ipld.Builder, X failure.IPLDBuilderFailure](
 			transaction.WithEffects(fx),
 		), nil
 	}
+
+	return ProvidedMethod[O]{
+		describe: func() AbilityDescription {
+			return Describe(capability, nil, nil)
+		},
+		authorize: func(ctx context.Context, invocation invocation.Invocation, ictx InvocationContext) (validator.Authorization[any], failure.IPLDBuilderFailure) {
+			auth, x := authorize(ctx, invocation, ictx)
+			if x != nil {
+				return nil, x
+			}
+			return validator.ConvertUnknownAuthorization(auth), nil
+		},
+		execute: execute,
+	}
+}
+
+// validateResult checks that the IPLD representation of the passed result
+// conforms to the passed ok or error type. A nil type is not checked.
+func validateResult[O, X ipld.Builder](res result.Result[O, X], okType, errorType ipldschema.Type) error {
+	validate := func(kind string, b ipld.Builder, typ ipldschema.Type) error {
+		if typ == nil {
+			return nil
+		}
+		nd, err := b.ToIPLD()
+		if err != nil {
+			return fmt.Errorf("%w: building %s IPLD: %w", ErrInvalidResult, kind, err)
+		}
+		if err := ipld.Validate(nd, typ); err != nil {
+			return fmt.Errorf("%w: %s does not conform to type %s: %w", ErrInvalidResult, kind, typ.Name(), err)
+		}
+		return nil
+	}
+	return result.MatchResultR1(res, func(o O) error {
+		return validate("ok", o, okType)
+	}, func(x X) error {
+		return validate("error", x, errorType)
+	})
 }
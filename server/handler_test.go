@@ -0,0 +1,302 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	ipldprime "github.com/ipld/go-ipld-prime"
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultValidation(t *testing.T) {
+	ts := helpers.Must(ipldprime.LoadSchemaBytes([]byte(`
+		type UploadAddOk struct {
+			root Link
+			status String
+		}
+		type UploadAddSizedOk struct {
+			root Link
+			status String
+			size Int
+		}
+		type UploadAddError struct {
+			name String
+			message String
+		}
+	`)))
+
+	var failed bool
+	handler := func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+		if failed {
+			return result.Error[uploadAddSuccess](uploadAddFailure{name: "UploadAddError", message: "boom"}), nil, nil
+		}
+		return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+	}
+
+	run := func(t *testing.T, capability validator.CapabilityParser[uploadAddCaveats]) (*string, []HandlerExecutionError[any]) {
+		var caught []HandlerExecutionError[any]
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(capability.Can(), Provide(capability, handler)),
+			WithErrorHandler(func(err HandlerExecutionError[any]) {
+				caught = append(caught, err)
+			}),
+		))
+		inv := helpers.Must(capability.Invoke(fixtures.Alice, fixtures.Service, fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}))
+		rcpt := helpers.Must(srv.Run(t.Context(), inv))
+		_, x := result.Unwrap(rcpt.Out())
+		if x == nil {
+			return nil, caught
+		}
+		return asFailure(t, x).Name, caught
+	}
+
+	newCapability := func(options ...validator.CapabilityOption) validator.CapabilityParser[uploadAddCaveats] {
+		return validator.NewCapability(
+			"upload/add",
+			schema.DIDString(),
+			schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+			nil,
+			options...,
+		)
+	}
+
+	t.Run("conforming result", func(t *testing.T) {
+		failed = false
+		capability := newCapability(validator.WithResultTypes(ts.TypeByName("UploadAddOk"), ts.TypeByName("UploadAddError")))
+		name, caught := run(t, capability)
+		require.Nil(t, name)
+		require.Empty(t, caught)
+
+		failed = true
+		name, caught = run(t, capability)
+		require.Equal(t, "UploadAddError", *name)
+		require.Empty(t, caught)
+	})
+
+	t.Run("nonconforming result", func(t *testing.T) {
+		failed = false
+		name, caught := run(t, newCapability(validator.WithResultTypes(ts.TypeByName("UploadAddSizedOk"), nil)))
+		require.Equal(t, "HandlerExecutionError", *name)
+		require.Len(t, caught, 1)
+		require.ErrorIs(t, caught[0].Cause(), ErrInvalidResult)
+		require.ErrorContains(t, caught[0], "UploadAddSizedOk")
+	})
+
+	t.Run("undeclared types", func(t *testing.T) {
+		failed = false
+		name, caught := run(t, newCapability())
+		require.Nil(t, name)
+		require.Empty(t, caught)
+
+		failed = true
+		name, caught = run(t, newCapability(validator.WithResultTypes(ts.TypeByName("UploadAddSizedOk"), nil)))
+		require.Equal(t, "UploadAddError", *name)
+		require.Empty(t, caught)
+	})
+
+	t.Run("describes declared types", func(t *testing.T) {
+		desc := Describe(newCapability(validator.WithResultTypes(ts.TypeByName("UploadAddOk"), ts.TypeByName("UploadAddError"))), nil, nil)
+		require.Equal(t, schema.DSL(ts.TypeByName("UploadAddOk")), desc.Ok)
+		require.Equal(t, schema.DSL(ts.TypeByName("UploadAddError")), desc.Error)
+	})
+}
+
+func TestProvideAlternatives(t *testing.T) {
+	newCapability := func(can string) validator.CapabilityParser[uploadAddCaveats] {
+		return validator.NewCapability(
+			can,
+			schema.DIDString(),
+			schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+			nil,
+		)
+	}
+	uploadadd := newCapability("upload/add")
+	uploadput := newCapability("upload/put")
+
+	method := Provide(validator.Or(uploadput, uploadadd), func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+		return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: cap.Can()}), nil, nil
+	})
+	srv := helpers.Must(NewServer(
+		fixtures.Service,
+		WithServiceMethod(uploadadd.Can(), method),
+		WithServiceMethod(uploadput.Can(), method),
+	))
+
+	for _, capability := range []validator.CapabilityParser[uploadAddCaveats]{uploadadd, uploadput} {
+		t.Run(capability.Can(), func(t *testing.T) {
+			inv := helpers.Must(capability.Invoke(fixtures.Alice, fixtures.Service, fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}))
+			rcpt := helpers.Must(srv.Run(t.Context(), inv))
+			o, x := result.Unwrap(rcpt.Out())
+			require.Nil(t, x)
+			status := helpers.Must(helpers.Must(o.LookupByString("status")).AsString())
+			require.Equal(t, capability.Can(), status)
+		})
+	}
+}
+
+func TestProvideGroup(t *testing.T) {
+	uploadput := validator.NewCapability(
+		"upload/put",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+	group := validator.And(validator.ConvertUnknownCapability(uploadAdd), validator.ConvertUnknownCapability(uploadput))
+
+	var calls atomic.Int64
+	method := Provide(group, func(ctx context.Context, cap ucan.Capability[validator.Group], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+		calls.Add(1)
+		root := cap.Nb()[0].Nb().(uploadAddCaveats).Root
+		return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: root, Status: cap.Can()}), nil, nil
+	})
+	srv := helpers.Must(NewServer(
+		fixtures.Service,
+		WithServiceMethod(group.Can(), method),
+		WithFallback(Provide(uploadAdd, uploadAddOk(&calls))),
+		WithDryRun(),
+	))
+
+	invoke := func(t *testing.T, issuer ucan.Signer, with ucan.Resource, options ...delegation.Option) invocation.Invocation {
+		nb := validator.Group{
+			ucan.NewCapability[any](uploadAdd.Can(), with, uploadAddCaveats{Root: helpers.RandomCID()}),
+			ucan.NewCapability[any](uploadput.Can(), with, uploadAddCaveats{Root: helpers.RandomCID()}),
+		}
+		return helpers.Must(group.Invoke(issuer, fixtures.Service, with, nb, options...))
+	}
+
+	t.Run("executes group", func(t *testing.T) {
+		calls.Store(0)
+		inv := invoke(t, fixtures.Alice, fixtures.Alice.DID().String())
+		require.Len(t, inv.Capabilities(), 2)
+
+		rcpt := executeInvocation(t, srv, inv)
+		o, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+		status := helpers.Must(helpers.Must(o.LookupByString("status")).AsString())
+		require.Equal(t, "upload/add&upload/put", status)
+		require.Equal(t, int64(1), calls.Load())
+	})
+
+	t.Run("reverse order", func(t *testing.T) {
+		calls.Store(0)
+		root := helpers.RandomCID()
+		inv := helpers.Must(invocation.InvokeCapabilities(fixtures.Alice, fixtures.Service, []ucan.Capability[uploadAddCaveats]{
+			uploadput.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}),
+			uploadAdd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: root}),
+		}))
+
+		rcpt := executeInvocation(t, srv, inv)
+		o, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+		require.Equal(t, "upload/add&upload/put", helpers.Must(helpers.Must(o.LookupByString("status")).AsString()))
+		// the members of the group are in the order of the parsers
+		require.Equal(t, root.String(), helpers.Must(helpers.Must(o.LookupByString("root")).AsLink()).String())
+		require.Equal(t, int64(1), calls.Load())
+
+		// a method provided for the group parsed in reverse order handles it too
+		reversed := validator.And(validator.ConvertUnknownCapability(uploadput), validator.ConvertUnknownCapability(uploadAdd))
+		require.Equal(t, group.Can(), reversed.Can())
+		srv := helpers.Must(NewServer(fixtures.Service, WithServiceMethod(reversed.Can(), method)))
+		require.Equal(t, "", receiptFailure(t, executeInvocation(t, srv, invoke(t, fixtures.Alice, fixtures.Alice.DID().String()))))
+	})
+
+	t.Run("member not delegated", func(t *testing.T) {
+		calls.Store(0)
+		dlg := helpers.Must(uploadAdd.Delegate(fixtures.Alice, fixtures.Bob, fixtures.Alice.DID().String(), uploadAddCaveats{}))
+		inv := invoke(t, fixtures.Bob, fixtures.Alice.DID().String(), delegation.WithProof(delegation.FromDelegation(dlg)))
+
+		require.Equal(t, "Unauthorized", receiptFailure(t, executeInvocation(t, srv, inv)))
+		require.Zero(t, calls.Load())
+	})
+
+	t.Run("not handled by fallback", func(t *testing.T) {
+		calls.Store(0)
+		srv := helpers.Must(NewServer(fixtures.Service, WithFallback(Provide(uploadAdd, uploadAddOk(&calls)))))
+
+		require.Equal(t, "HandlerNotFoundError", receiptFailure(t, executeInvocation(t, srv, invoke(t, fixtures.Alice, fixtures.Alice.DID().String()))))
+		require.Zero(t, calls.Load())
+	})
+
+	t.Run("different resources", func(t *testing.T) {
+		inv := helpers.Must(invocation.InvokeCapabilities(fixtures.Alice, fixtures.Service, []ucan.Capability[uploadAddCaveats]{
+			uploadAdd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}),
+			uploadput.New(fixtures.Bob.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}),
+		}))
+
+		require.Equal(t, "InvocationCapabilityError", receiptFailure(t, executeInvocation(t, srv, inv)))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		calls.Store(0)
+		inv := invoke(t, fixtures.Alice, fixtures.Alice.DID().String())
+		dryrun := helpers.Must(client.DryRun(fixtures.Alice, fixtures.Service, inv))
+
+		require.Equal(t, "", receiptFailure(t, executeInvocation(t, srv, dryrun)))
+		require.Zero(t, calls.Load())
+	})
+}
+
+func TestProvideVerificationCache(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+	cache := helpers.Must(validator.NewMemoryVerificationCache(0))
+	srv := helpers.Must(NewServer(
+		fixtures.Service,
+		WithVerificationCache(cache),
+		WithServiceMethod(uploadadd.Can(), Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+		})),
+	))
+
+	inv := helpers.Must(uploadadd.Invoke(fixtures.Alice, fixtures.Service, fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}))
+	rcpt := helpers.Must(srv.Run(t.Context(), inv))
+	_, x := result.Unwrap(rcpt.Out())
+	require.Nil(t, x)
+
+	_, ok, err := cache.Get(t.Context(), inv.Link(), fixtures.Alice.DID())
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestProvidedMethod(t *testing.T) {
+	var calls atomic.Int64
+	method := Provide(uploadAdd, uploadAddOk(&calls))
+	ictx := newUploadAddServer(t, uploadAddOk(&calls)).Context()
+
+	t.Run("description", func(t *testing.T) {
+		require.Equal(t, Describe(uploadAdd, nil, nil), method.Description())
+	})
+
+	t.Run("authorize", func(t *testing.T) {
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+		auth, x := method.Authorize(t.Context(), inv, ictx)
+		require.Nil(t, x)
+		require.Equal(t, uploadAdd.Can(), auth.Capability().Can())
+		require.Equal(t, inv.Link(), auth.Delegation().Link())
+		require.Zero(t, calls.Load())
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Bob.DID().String())
+		_, x := method.Authorize(t.Context(), inv, ictx)
+		require.NotNil(t, x)
+		require.Equal(t, "Unauthorized", x.Name())
+		require.Zero(t, calls.Load())
+	})
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/storacha/go-ucanto/core/car"
+	"github.com/storacha/go-ucanto/core/receipt"
+	thttp "github.com/storacha/go-ucanto/transport/http"
+)
+
+// NewHTTPHandler creates a [http.Handler] for the passed server. It accepts
+// POST requests only, unless configured otherwise with
+// [thttp.WithAllowedMethods].
+func NewHTTPHandler(server ServerView[Service], options ...thttp.HandlerOption) http.Handler {
+	return thttp.NewHandler(server, options...)
+}
+
+// NewReceiptHandler creates a [http.Handler] that serves receipts from the
+// passed store as CAR archives (see [receipt.Receipt.Archive]). The invocation
+// CID is taken from the "cid" path value, or the last path segment if there is
+// none, so it should be mounted like:
+//
+//	mux.Handle("GET /receipt/{cid}", server.NewReceiptHandler(store))
+func NewReceiptHandler(store receipt.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		str := r.PathValue("cid")
+		if str == "" {
+			str = path.Base(r.URL.Path)
+		}
+		c, err := cid.Parse(str)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid invocation CID: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		rcpt, ok, err := store.Get(r.Context(), cidlink.Link{Cid: c})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: getting receipt for invocation %s: %s\n", c, err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, fmt.Sprintf("receipt not found for invocation: %s", c), http.StatusNotFound)
+			return
+		}
+
+		// receipts are small, so buffer the archive to allow encoding errors to be
+		// reported to the client
+		archive, err := io.ReadAll(rcpt.Archive())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: archiving receipt for invocation %s: %s\n", c, err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", car.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(archive)
+	})
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server/transaction"
+)
+
+// Interceptor is middleware that wraps the execution of every service method.
+// It receives the invocation and invocation context along with the next
+// service method in the chain.
+//
+// An interceptor may:
+//
+//   - Call next (optionally with a derived context carrying request scoped
+//     values) and inspect or decorate the resulting transaction, for example
+//     by adding receipt metadata using [transaction.WithMeta].
+//   - Short-circuit execution by returning a failure transaction without
+//     calling next, which results in a failure receipt for the invocation.
+//   - Return an error, which is handled in the same way as an error returned
+//     from a service method.
+type Interceptor func(
+	ctx context.Context,
+	invocation invocation.Invocation,
+	context InvocationContext,
+	next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure],
+) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error)
+
+// Intercept wraps the passed service method with the passed interceptors. The
+// first interceptor is the outermost i.e. it is called first and receives the
+// transaction last.
+func Intercept(method ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure], interceptors ...Interceptor) ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure] {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		intercept, next := interceptors[i], method
+		method = func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+			return intercept(ctx, inv, ictx, next)
+		}
+	}
+	return method
+}
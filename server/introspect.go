@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	ipldschema "github.com/ipld/go-ipld-prime/schema"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	udm "github.com/storacha/go-ucanto/core/result/ok/datamodel"
+	"github.com/storacha/go-ucanto/core/schema"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// IntrospectAbility is the ability used to discover the abilities supported by
+// a service.
+const IntrospectAbility = "ucanto/introspect"
+
+// Introspect is the `ucanto/introspect` capability. The resource is the DID of
+// the agent requesting the description, so that any agent may invoke it, and
+// there are no caveats.
+var Introspect = validator.NewCapability(
+	IntrospectAbility,
+	schema.DIDString(),
+	schema.Struct[ok.Unit](udm.UnitType(), nil),
+	validator.DefaultDerives,
+)
+
+// AbilityDescription describes an ability supported by a service. Schemas are
+// IPLD schema DSL, and are empty if not known.
+type AbilityDescription struct {
+	// Can is the ability.
+	Can ucan.Ability `json:"can"`
+	// With describes the resources the ability may be invoked on, for example
+	// "did:key:*".
+	With string `json:"with,omitempty"`
+	// Caveats is the schema of the capability caveats (nb).
+	Caveats string `json:"nb,omitempty"`
+	// Ok is the schema of a successful result.
+	Ok string `json:"ok,omitempty"`
+	// Error is the schema of a failure result.
+	Error string `json:"error,omitempty"`
+}
+
+// Describe creates a description of an ability from the passed capability
+// parser, for use with [WithAbilityDescription]. The resource and caveats are
+// described by the readers of the capability where possible (see
+// [validator.Describer], [schema.Describer] and [schema.Typed]), which is the
+// case for capabilities created by [validator.NewCapability] with readers
+// created by [schema.DIDString] and [schema.Struct]. The ok and error types are the
+// schema types of the result of the service method, and may be nil, in which
+// case the types declared by the capability are used, if any (see
+// [validator.ResultTyped]).
+func Describe[C any](capability validator.CapabilityParser[C], okType, errorType ipldschema.Type) AbilityDescription {
+	if rt, ok := capability.(validator.ResultTyped); ok {
+		if okType == nil {
+			okType = rt.OkType()
+		}
+		if errorType == nil {
+			errorType = rt.ErrorType()
+		}
+	}
+	desc := AbilityDescription{
+		Can:   capability.Can(),
+		Ok:    schema.DSL(okType),
+		Error: schema.DSL(errorType),
+	}
+	if d, ok := capability.(validator.Describer[C]); ok {
+		desc.With = schema.Describe(d.Descriptor().With())
+		if t, ok := d.Descriptor().Nb().(schema.Typed); ok {
+			desc.Caveats = schema.DSL(t.Type())
+		}
+	}
+	return desc
+}
+
+// describer is implemented by servers configured with ability descriptions.
+type describer interface {
+	describe(can ucan.Ability) (AbilityDescription, bool)
+}
+
+func (srv *server) describe(can ucan.Ability) (AbilityDescription, bool) {
+	desc, ok := srv.descriptions[can]
+	return desc, ok
+}
+
+// Abilities returns descriptions of the abilities of the passed server, sorted
+// by ability. Abilities are described by the capability of the service method
+// if it was created by [Provide], unless configured with a description (see
+// [WithAbilityDescription]). Other abilities are described by name only.
+// Fallback methods (see [FallbackAbility]) are not listed.
+func Abilities(srv Server[Service]) []AbilityDescription {
+	d, _ := srv.(describer)
+	abilities := make([]AbilityDescription, 0, len(srv.Service()))
+	for can := range srv.Service() {
+		if isFallback(can) {
+			continue
+		}
+		desc := AbilityDescription{Can: can}
+		if d != nil {
+			if dd, ok := d.describe(can); ok {
+				desc = dd
+			}
+		}
+		abilities = append(abilities, desc)
+	}
+	slices.SortFunc(abilities, func(a, b AbilityDescription) int {
+		return strings.Compare(a.Can, b.Can)
+	})
+	return abilities
+}
+
+// introspect creates the service method for `ucanto/introspect` invocations.
+// The abilities function is called for every invocation.
+func introspect(abilities func() []AbilityDescription) ProvidedMethod[sdm.IntrospectOkModel] {
+	return Provide(Introspect, func(ctx context.Context, cap ucan.Capability[ok.Unit], inv invocation.Invocation, ictx InvocationContext) (result.Result[sdm.IntrospectOkModel, failure.IPLDBuilderFailure], fx.Effects, error) {
+		descs := abilities()
+		model := sdm.IntrospectOkModel{Abilities: make([]sdm.AbilityDescriptionModel, 0, len(descs))}
+		for _, desc := range descs {
+			model.Abilities = append(model.Abilities, sdm.AbilityDescriptionModel{
+				Can:   desc.Can,
+				With:  optionalString(desc.With),
+				Nb:    optionalString(desc.Caveats),
+				Ok:    optionalString(desc.Ok),
+				Error: optionalString(desc.Error),
+			})
+		}
+		return result.Ok[sdm.IntrospectOkModel, failure.IPLDBuilderFailure](model), nil, nil
+	})
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// NewIntrospectionHandler creates a [http.Handler] that responds to GET
+// requests with a JSON array of the descriptions of the abilities of the passed
+// server (see [Abilities]).
+func NewIntrospectionHandler(srv Server[Service]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := json.Marshal(Abilities(srv))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: encoding abilities: %s\n", err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(body)
+	})
+}
+
+var _ describer = (*server)(nil)
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/core/schema"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospection(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(schema.WithMethod("key")),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+	uploadlist := validator.NewCapability(
+		"upload/list",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+	handler := func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+		return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+	}
+
+	// raw service methods do not declare the capability they provide
+	raw := ServiceMethod[uploadAddSuccess, uploadAddFailure](func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (transaction.Transaction[uploadAddSuccess, uploadAddFailure], error) {
+		return transaction.NewTransaction(result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Status: "done"})), nil
+	})
+
+	srv := helpers.Must(NewServer(
+		fixtures.Service,
+		WithServiceMethod(uploadadd.Can(), Provide(uploadadd, handler)),
+		WithServiceMethod(uploadlist.Can(), Provide(uploadlist, handler)),
+		WithServiceMethod("upload/remove", raw),
+		WithFallback(raw),
+		WithAbilityDescription(Describe(uploadadd, uploadAddCaveatsType(), nil)),
+		WithIntrospection(),
+	))
+
+	expected := []AbilityDescription{
+		{
+			Can:     "ucanto/introspect",
+			With:    "did:*",
+			Caveats: "type Unit struct {}",
+			Ok:      schema.DSL(sdm.IntrospectOkType()),
+		},
+		{
+			Can:     "upload/add",
+			With:    "did:key:*",
+			Caveats: "type UploadAddCaveats struct {\n  root Link\n}",
+			Ok:      "type UploadAddCaveats struct {\n  root Link\n}",
+		},
+		{
+			Can:     "upload/list",
+			With:    "did:*",
+			Caveats: "type UploadAddCaveats struct {\n  root Link\n}",
+		},
+		{Can: "upload/remove"},
+	}
+
+	t.Run("abilities", func(t *testing.T) {
+		require.Equal(t, expected, Abilities(srv))
+	})
+
+	t.Run("invoke", func(t *testing.T) {
+		conn := helpers.Must(client.NewConnection(fixtures.Service, srv))
+		inv := helpers.Must(Introspect.Invoke(fixtures.Alice, fixtures.Service, fixtures.Alice.DID().String(), ok.Unit{}))
+		resp := helpers.Must(client.Execute(t.Context(), []invocation.Invocation{inv}, conn))
+
+		rcptlnk, found := resp.Get(inv.Link())
+		require.True(t, found)
+		rcpt := helpers.Must(receipt.NewAnyReceiptReader().Read(rcptlnk, resp.Blocks()))
+		out, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+
+		model := helpers.Must(ipld.Rebind[sdm.IntrospectOkModel](out, sdm.IntrospectOkType()))
+		require.Len(t, model.Abilities, len(expected))
+		for i, desc := range expected {
+			require.Equal(t, desc.Can, model.Abilities[i].Can)
+			require.Equal(t, optionalString(desc.With), model.Abilities[i].With)
+			require.Equal(t, optionalString(desc.Caveats), model.Abilities[i].Nb)
+			require.Equal(t, optionalString(desc.Ok), model.Abilities[i].Ok)
+			require.Nil(t, model.Abilities[i].Error)
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+		httpServer := httptest.NewServer(NewIntrospectionHandler(srv))
+		t.Cleanup(httpServer.Close)
+
+		res := helpers.Must(httpServer.Client().Get(httpServer.URL))
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+		var abilities []AbilityDescription
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&abilities))
+		require.Equal(t, expected, abilities)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		srv := helpers.Must(NewServer(fixtures.Service, WithServiceMethod(uploadadd.Can(), Provide(uploadadd, handler))))
+		require.Equal(t, []AbilityDescription{{
+			Can:     "upload/add",
+			With:    "did:key:*",
+			Caveats: "type UploadAddCaveats struct {\n  root Link\n}",
+		}}, Abilities(srv))
+	})
+}
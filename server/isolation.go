@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/result/failure"
+)
+
+// ErrHandlerTimeout is returned by [ExecuteHandler] when a handler does not
+// complete within its timeout.
+var ErrHandlerTimeout = errors.New("handler timed out")
+
+// HandlerPanic is the cause of a [HandlerExecutionError] for a handler that
+// panicked. The stack trace is captured where the panic was recovered, so it
+// includes the frames of the handler that panicked.
+type HandlerPanic interface {
+	failure.Failure
+	failure.WithStackTrace
+	// Value is the value passed to panic.
+	Value() any
+}
+
+type handlerPanic struct {
+	failure.NamedWithStackTrace
+	value any
+}
+
+func (h handlerPanic) Value() any {
+	return h.value
+}
+
+func (h handlerPanic) Error() string {
+	return fmt.Sprintf("panic: %v", h.value)
+}
+
+// ExecuteHandler calls the passed function, which typically calls a service
+// method, and isolates the caller from it:
+//
+//   - If the function panics, the panic is recovered and returned as a
+//     [HandlerPanic] error.
+//   - If timeout is positive, the function is called with a context that is
+//     canceled after the timeout. If it has not returned by then,
+//     [ErrHandlerTimeout] is returned without waiting for it. A value it
+//     returns after the timeout is closed if it implements [io.Closer], since
+//     the caller will not receive it.
+//
+// The context of the function is canceled when it returns. Use
+// [ExecuteStreamingHandler] if the value it returns is still bound to it.
+func ExecuteHandler[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	value, release, err := ExecuteStreamingHandler(ctx, timeout, fn)
+	release()
+	return value, err
+}
+
+// ExecuteStreamingHandler is like [ExecuteHandler], except that the context of
+// a function that returns successfully before the timeout is not canceled, so
+// that the value it returns, such as a response body streamed from an
+// upstream request, can still use it. The timeout no longer applies, and the
+// caller must call the returned release function when it is done with the
+// value to cancel the context.
+func ExecuteStreamingHandler[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, func(), error) {
+	if timeout <= 0 {
+		value, err := callHandler(ctx, fn)
+		return value, func() {}, err
+	}
+
+	cctx, cancel := context.WithCancelCause(ctx)
+	hctx := handlerContext{cctx, time.Now().Add(timeout), &atomic.Bool{}}
+	timer := time.AfterFunc(timeout, func() { cancel(ErrHandlerTimeout) })
+	release := func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := callHandler(hctx, fn)
+		done <- outcome{value, err}
+	}()
+
+	select {
+	case o := <-done:
+		if timer.Stop() {
+			hctx.stopped.Store(true)
+		}
+		// a handler that respects the context may return its error before the
+		// timeout is observed here
+		if o.err != nil && errors.Is(context.Cause(hctx), ErrHandlerTimeout) {
+			release()
+			discardValue(o.value)
+			var zero T
+			return zero, func() {}, ErrHandlerTimeout
+		}
+		if o.err != nil {
+			release()
+			return o.value, func() {}, o.err
+		}
+		return o.value, release, nil
+	case <-hctx.Done():
+		release()
+		go func() {
+			o := <-done
+			discardValue(o.value)
+		}()
+		var zero T
+		if errors.Is(context.Cause(hctx), ErrHandlerTimeout) {
+			return zero, func() {}, ErrHandlerTimeout
+		}
+		return zero, func() {}, hctx.Err()
+	}
+}
+
+// handlerContext is the context of a handler with a timeout. Unlike a context
+// created with [context.WithTimeout], its timer can be stopped without
+// canceling it, after which it no longer has its deadline. It is canceled with
+// [context.DeadlineExceeded] once the timeout has elapsed.
+type handlerContext struct {
+	context.Context
+	deadline time.Time
+	stopped  *atomic.Bool
+}
+
+func (c handlerContext) Deadline() (time.Time, bool) {
+	d, ok := c.Context.Deadline()
+	if c.stopped.Load() || (ok && d.Before(c.deadline)) {
+		return d, ok
+	}
+	return c.deadline, true
+}
+
+func (c handlerContext) Err() error {
+	if errors.Is(context.Cause(c.Context), ErrHandlerTimeout) {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+// discardValue closes a value returned by a handler that will not be received
+// by the caller, if it implements [io.Closer].
+func discardValue(value any) {
+	if c, ok := value.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+func callHandler[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = handlerPanic{failure.NamedWithCurrentStackTrace("HandlerPanic"), r}
+		}
+	}()
+	return fn(ctx)
+}
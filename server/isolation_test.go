@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerIsolation(t *testing.T) {
+	newServer := func(t *testing.T, handler uploadAddHandler, options ...Option) ServerView[Service] {
+		return newUploadAddServer(t, handler, options...)
+	}
+
+	newInvocation := func(t *testing.T) invocation.Invocation {
+		return newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+	}
+
+	t.Run("recovers panic", func(t *testing.T) {
+		var caught []HandlerExecutionError[any]
+		srv := newServer(t, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			panic("boom")
+		}, WithErrorHandler(func(err HandlerExecutionError[any]) {
+			caught = append(caught, err)
+		}))
+
+		rcpt, err := srv.Run(t.Context(), newInvocation(t))
+		require.NoError(t, err)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "HandlerExecutionError", *asFailure(t, x).Name)
+
+		require.Len(t, caught, 1)
+		var perr HandlerPanic
+		require.True(t, errors.As(caught[0].Cause(), &perr))
+		require.Equal(t, "boom", perr.Value())
+		require.Equal(t, "HandlerPanic", perr.Name())
+		require.Contains(t, perr.Stack(), "TestHandlerIsolation")
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+
+		srv := newServer(t, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			// ignores context cancellation
+			<-release
+			return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+		}, WithTimeout(10*time.Millisecond))
+
+		rcpt, err := srv.Run(t.Context(), newInvocation(t))
+		require.NoError(t, err)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "Timeout", *asFailure(t, x).Name)
+	})
+
+	t.Run("cancels handler context", func(t *testing.T) {
+		canceled := make(chan error, 1)
+		srv := newServer(t, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return nil, nil, ctx.Err()
+		}, WithTimeout(10*time.Millisecond))
+
+		rcpt, err := srv.Run(t.Context(), newInvocation(t))
+		require.NoError(t, err)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "Timeout", *asFailure(t, x).Name)
+		require.ErrorIs(t, <-canceled, context.DeadlineExceeded)
+	})
+
+	t.Run("ability timeout overrides default", func(t *testing.T) {
+		srv := newServer(t, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			time.Sleep(50 * time.Millisecond)
+			return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+		}, WithTimeout(10*time.Millisecond), WithAbilityTimeout(uploadAdd.Can(), time.Second))
+
+		require.Equal(t, time.Second, srv.(timeoutLimiter).Timeout(uploadAdd.Can()))
+		require.Equal(t, 10*time.Millisecond, srv.(timeoutLimiter).Timeout("upload/remove"))
+
+		rcpt, err := srv.Run(t.Context(), newInvocation(t))
+		require.NoError(t, err)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+	})
+}
+
+// closer records whether it has been closed.
+type closer struct {
+	closed chan struct{}
+}
+
+func (c closer) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestExecuteHandler(t *testing.T) {
+	t.Run("closes value returned after timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		c := closer{make(chan struct{})}
+		_, err := ExecuteHandler(t.Context(), 10*time.Millisecond, func(ctx context.Context) (closer, error) {
+			<-release
+			return c, nil
+		})
+		require.ErrorIs(t, err, ErrHandlerTimeout)
+
+		close(release)
+		select {
+		case <-c.closed:
+		case <-time.After(time.Second):
+			t.Fatal("value returned after timeout was not closed")
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	ucar "github.com/storacha/go-ucanto/core/car"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/transport"
+	thttp "github.com/storacha/go-ucanto/transport/http"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// limits bound the resources a request may consume. Zero values mean no
+// limit.
+type limits struct {
+	// maxRequestSize is the maximum size of a request body in bytes
+	maxRequestSize int64
+	// maxBlocks is the maximum number of blocks in a request
+	maxBlocks int
+	// maxBlockSize is the maximum size of a block in bytes
+	maxBlockSize uint64
+	// maxInvocations is the maximum number of invocations in a request
+	maxInvocations int
+	// proofs bound the proof chains explored when authorizing invocations
+	proofs validator.ProofLimits
+}
+
+// decodeOptions returns the options enforcing the limits while decoding a CAR.
+func (l limits) decodeOptions() []ucar.DecodeOption {
+	var opts []ucar.DecodeOption
+	if l.maxBlocks > 0 {
+		opts = append(opts, ucar.WithMaxBlocks(l.maxBlocks))
+	}
+	if l.maxBlockSize > 0 {
+		opts = append(opts, ucar.WithMaxBlockSize(l.maxBlockSize))
+	}
+	return opts
+}
+
+// limiter is implemented by servers configured with limits.
+type limiter interface {
+	limits() limits
+}
+
+func (srv *server) limits() limits {
+	return srv.lims
+}
+
+// messageLimiter is implemented by servers that bound the contents of the
+// agent messages they execute.
+type messageLimiter interface {
+	// CheckMessage returns an error if a decoded agent message exceeds the
+	// limits of the server.
+	CheckMessage(msg message.AgentMessage) transport.HTTPError
+}
+
+func (srv *server) CheckMessage(msg message.AgentMessage) transport.HTTPError {
+	return checkMessage(msg, srv.lims)
+}
+
+// proofLimiter is implemented by servers that bound the proof chains explored
+// when authorizing invocations.
+type proofLimiter interface {
+	// ProofLimits are the limits of the proof chains explored when authorizing
+	// an invocation. Zero values mean no limit.
+	ProofLimits() validator.ProofLimits
+}
+
+func (srv *server) ProofLimits() validator.ProofLimits {
+	return srv.lims.proofs
+}
+
+// limitRequest returns a request whose body fails to read beyond the maximum
+// request size, or an error if the content length of the request exceeds it.
+func limitRequest(request transport.HTTPRequest, l limits) (transport.HTTPRequest, *limitedReader, transport.HTTPError) {
+	if l.maxRequestSize <= 0 {
+		return request, nil, nil
+	}
+	if cl := request.Headers().Get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n > l.maxRequestSize {
+			return nil, nil, requestTooLarge(l)
+		}
+	}
+	body := &limitedReader{r: request.Body(), n: l.maxRequestSize}
+	if inreq, ok := request.(transport.InboundHTTPRequest); ok {
+		return thttp.NewInboundRequest(inreq.URL(), body, request.Headers()), body, nil
+	}
+	return thttp.NewRequest(body, request.Headers()), body, nil
+}
+
+// decodeError returns the HTTP error for a request that failed to decode
+// because it exceeds the limits, or nil if it failed for another reason.
+func decodeError(err error, body *limitedReader, l limits) transport.HTTPError {
+	if body != nil && body.exceeded {
+		return requestTooLarge(l)
+	}
+	if errors.Is(err, ucar.ErrTooManyBlocks) {
+		return tooManyBlocks(l)
+	}
+	if errors.Is(err, ucar.ErrBlockTooLarge) {
+		return blockTooLarge(l)
+	}
+	return nil
+}
+
+// checkMessage checks that a decoded message is within the limits. The number
+// and size of blocks are checked again since a custom codec may not enforce
+// them while decoding.
+func checkMessage(msg message.AgentMessage, l limits) transport.HTTPError {
+	if l.maxInvocations > 0 && len(msg.Invocations()) > l.maxInvocations {
+		return thttp.NewHTTPError(
+			fmt.Sprintf("The request contains %d invocations, exceeding the maximum of %d.", len(msg.Invocations()), l.maxInvocations),
+			http.StatusRequestEntityTooLarge,
+			nil,
+		)
+	}
+	if l.maxBlocks <= 0 && l.maxBlockSize <= 0 {
+		return nil
+	}
+	n := 0
+	for b, err := range msg.Blocks() {
+		if err != nil {
+			return thttp.NewHTTPError(
+				fmt.Sprintf("The server failed to read the request payload: %s", err.Error()),
+				http.StatusBadRequest,
+				nil,
+			)
+		}
+		n++
+		if l.maxBlocks > 0 && n > l.maxBlocks {
+			return tooManyBlocks(l)
+		}
+		if l.maxBlockSize > 0 && uint64(len(b.Link().Binary())+len(b.Bytes())) > l.maxBlockSize {
+			return blockTooLarge(l)
+		}
+	}
+	return nil
+}
+
+func requestTooLarge(l limits) transport.HTTPError {
+	return thttp.NewHTTPError(
+		fmt.Sprintf("The request payload exceeds the maximum size of %d bytes.", l.maxRequestSize),
+		http.StatusRequestEntityTooLarge,
+		nil,
+	)
+}
+
+func tooManyBlocks(l limits) transport.HTTPError {
+	return thttp.NewHTTPError(
+		fmt.Sprintf("The request payload contains more than the maximum of %d blocks.", l.maxBlocks),
+		http.StatusRequestEntityTooLarge,
+		nil,
+	)
+}
+
+func blockTooLarge(l limits) transport.HTTPError {
+	return thttp.NewHTTPError(
+		fmt.Sprintf("The request payload contains a block exceeding the maximum size of %d bytes.", l.maxBlockSize),
+		http.StatusRequestEntityTooLarge,
+		nil,
+	)
+}
+
+// limitedReader reads from r until n bytes have been read, after which it
+// fails and records that the limit was exceeded.
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.n <= 0 {
+		// the body is only too large if there is more to read
+		var b [1]byte
+		n, err := lr.r.Read(b[:])
+		if n > 0 {
+			lr.exceeded = true
+			return 0, errRequestTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > lr.n {
+		p = p[:lr.n]
+	}
+	n, err := lr.r.Read(p)
+	lr.n -= int64(n)
+	return n, err
+}
+
+var errRequestTooLarge = errors.New("request body too large")
+
+var _ limiter = (*server)(nil)
+var _ messageLimiter = (*server)(nil)
+var _ proofLimiter = (*server)(nil)
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/transport"
+	"github.com/storacha/go-ucanto/transport/car/request"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	newRequest := func(t *testing.T, n int) transport.HTTPRequest {
+		var invs []invocation.Invocation
+		for range n {
+			invs = append(invs, newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String()))
+		}
+		return helpers.Must(request.Encode(helpers.Must(message.Build(invs, nil))))
+	}
+
+	requireStatus := func(t *testing.T, status int, res transport.HTTPResponse, msg string) {
+		require.Equal(t, status, res.Status())
+		body := helpers.Must(io.ReadAll(res.Body()))
+		require.Contains(t, string(body), msg)
+	}
+
+	t.Run("within limits", func(t *testing.T) {
+		server := newUploadAddServer(t, nil,
+			WithMaxRequestSize(1<<20),
+			WithMaxBlocks(10),
+			WithMaxBlockSize(1<<10),
+			WithMaxInvocations(2),
+		)
+		res := helpers.Must(Handle(t.Context(), server, newRequest(t, 2)))
+		require.Equal(t, http.StatusOK, res.Status())
+	})
+
+	t.Run("request size", func(t *testing.T) {
+		server := newUploadAddServer(t, nil, WithMaxRequestSize(64))
+		res := helpers.Must(Handle(t.Context(), server, newRequest(t, 1)))
+		requireStatus(t, http.StatusRequestEntityTooLarge, res, "exceeds the maximum size of 64 bytes")
+
+		req := newRequest(t, 1)
+		req.Headers().Set("Content-Length", "1000")
+		res = helpers.Must(Handle(t.Context(), server, req))
+		requireStatus(t, http.StatusRequestEntityTooLarge, res, "exceeds the maximum size of 64 bytes")
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		server := newUploadAddServer(t, nil, WithMaxBlocks(2))
+		res := helpers.Must(Handle(t.Context(), server, newRequest(t, 2)))
+		requireStatus(t, http.StatusRequestEntityTooLarge, res, "more than the maximum of 2 blocks")
+	})
+
+	t.Run("block size", func(t *testing.T) {
+		server := newUploadAddServer(t, nil, WithMaxBlockSize(32))
+		res := helpers.Must(Handle(t.Context(), server, newRequest(t, 1)))
+		requireStatus(t, http.StatusRequestEntityTooLarge, res, "block exceeding the maximum size of 32 bytes")
+	})
+
+	t.Run("invocations", func(t *testing.T) {
+		server := newUploadAddServer(t, nil, WithMaxInvocations(2))
+		res := helpers.Must(Handle(t.Context(), server, newRequest(t, 3)))
+		requireStatus(t, http.StatusRequestEntityTooLarge, res, "contains 3 invocations, exceeding the maximum of 2")
+	})
+
+	t.Run("proof depth", func(t *testing.T) {
+		server := newUploadAddServer(t, nil, WithProofLimits(validator.ProofLimits{MaxDepth: 1}))
+
+		alice2bob := helpers.Must(uploadAdd.Delegate(fixtures.Alice, fixtures.Bob, fixtures.Alice.DID().String(), uploadAddCaveats{}))
+		bob2mallory := helpers.Must(uploadAdd.Delegate(fixtures.Bob, fixtures.Mallory, fixtures.Alice.DID().String(), uploadAddCaveats{}, delegation.WithProof(delegation.FromDelegation(alice2bob))))
+
+		inv := helpers.Must(uploadAdd.Invoke(fixtures.Bob, fixtures.Service, fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}, delegation.WithProof(delegation.FromDelegation(alice2bob))))
+		rcpt := helpers.Must(server.Run(t.Context(), inv))
+		_, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+
+		inv = helpers.Must(uploadAdd.Invoke(fixtures.Mallory, fixtures.Service, fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()}, delegation.WithProof(delegation.FromDelegation(bob2mallory))))
+		rcpt = helpers.Must(server.Run(t.Context(), inv))
+		_, x = result.Unwrap(rcpt.Out())
+		require.Equal(t, "Unauthorized", *asFailure(t, x).Name)
+		require.Contains(t, asFailure(t, x).Message, "exceed the maximum proof chain depth of 1")
+	})
+
+	t.Run("unreadable blocks", func(t *testing.T) {
+		msg := helpers.Must(message.Build([]invocation.Invocation{newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())}, nil))
+		lerr := checkMessage(unreadableMessage{msg}, limits{maxBlocks: 10})
+		require.NotNil(t, lerr)
+		require.Equal(t, http.StatusBadRequest, lerr.Status())
+		require.Contains(t, lerr.Error(), "boom")
+	})
+
+	t.Run("invalid proof limits", func(t *testing.T) {
+		_, err := NewServer(fixtures.Service, WithProofLimits(validator.ProofLimits{MaxDepth: -1}))
+		require.ErrorContains(t, err, "invalid proof limits")
+	})
+}
+
+// unreadableMessage is an agent message whose blocks fail to be read.
+type unreadableMessage struct {
+	message.AgentMessage
+}
+
+func (unreadableMessage) Blocks() iter.Seq2[ipld.Block, error] {
+	return func(yield func(ipld.Block, error) bool) {
+		yield(nil, errors.New("boom"))
+	}
+}
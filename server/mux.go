@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/transport"
+)
+
+// NewMux creates a server that hosts several services, each with its own
+// identity, service methods and options, behind a single endpoint. Each
+// invocation in a request is routed by its audience to the server identified
+// by that DID, or configured to accept it as an alternative audience (see
+// [WithAlternativeAudiences]), and receipts are issued by that server.
+//
+// The first server is the default. Invocations addressed to an audience that
+// no server accepts are routed to it, and it decodes requests and encodes
+// responses for all servers, so its codec, request limits (see
+// [WithMaxRequestSize]), status policy (see [WithStatusPolicy]) and maximum
+// concurrency apply to every request. It is an error if more than one server
+// accepts the same audience.
+func NewMux(servers ...ServerView[Service]) (ServerView[Service], error) {
+	if len(servers) == 0 {
+		return nil, errors.New("missing servers")
+	}
+	hosts := map[did.DID]ServerView[Service]{}
+	for _, s := range servers {
+		audiences := []did.DID{s.ID().DID()}
+		for _, a := range s.Context().AlternativeAudiences() {
+			audiences = append(audiences, a.DID())
+		}
+		for _, aud := range audiences {
+			if _, ok := hosts[aud]; ok {
+				return nil, fmt.Errorf("duplicate server for audience: %s", aud)
+			}
+			hosts[aud] = s
+		}
+	}
+	return &mux{ServerView: servers[0], hosts: hosts}, nil
+}
+
+type mux struct {
+	// ServerView is the default server
+	ServerView[Service]
+	// hosts are the servers by the audiences they accept
+	hosts map[did.DID]ServerView[Service]
+}
+
+func (m *mux) host(inv invocation.Invocation) Server[Service] {
+	if s, ok := m.hosts[inv.Audience().DID()]; ok {
+		return s
+	}
+	return m.ServerView
+}
+
+func (m *mux) Request(ctx context.Context, request transport.HTTPRequest) (transport.HTTPResponse, error) {
+	return Handle(ctx, m, request)
+}
+
+func (m *mux) Run(ctx context.Context, invocation ServiceInvocation) (receipt.AnyReceipt, error) {
+	return Run(ctx, m, invocation)
+}
+
+func (m *mux) limits() limits {
+	if l, ok := m.ServerView.(limiter); ok {
+		return l.limits()
+	}
+	return limits{}
+}
+
+func (m *mux) MaxConcurrency() int {
+	if cl, ok := m.ServerView.(concurrencyLimiter); ok {
+		return cl.MaxConcurrency()
+	}
+	return DefaultMaxConcurrency
+}
+
+func (m *mux) statusPolicy() StatusPolicy {
+	if sp, ok := m.ServerView.(statusPolicer); ok {
+		return sp.statusPolicy()
+	}
+	return nil
+}
+
+// multiplexer is implemented by servers that route invocations to other
+// servers.
+type multiplexer interface {
+	host(inv invocation.Invocation) Server[Service]
+}
+
+// hostFor returns the server that runs the passed invocation.
+func hostFor(server Server[Service], inv invocation.Invocation) Server[Service] {
+	if m, ok := server.(multiplexer); ok {
+		return m.host(inv)
+	}
+	return server
+}
+
+var _ multiplexer = (*mux)(nil)
+var _ limiter = (*mux)(nil)
+var _ concurrencyLimiter = (*mux)(nil)
+var _ statusPolicer = (*mux)(nil)
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMux(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+	method := func(status string) Option {
+		return WithServiceMethod(uploadadd.Can(), Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: status}), nil, nil
+		}))
+	}
+
+	service := helpers.Must(NewServer(fixtures.Service, method("service")))
+	bob := helpers.Must(NewServer(fixtures.Bob, method("bob"), WithAlternativeAudiences(fixtures.Mallory)))
+	mux := helpers.Must(NewMux(service, bob))
+
+	invoke := func(t *testing.T, audience ucan.Principal) invocation.Invocation {
+		cap := uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})
+		return helpers.Must(invocation.Invoke(fixtures.Alice, audience, cap))
+	}
+
+	t.Run("routes by audience", func(t *testing.T) {
+		invs := []invocation.Invocation{invoke(t, fixtures.Service), invoke(t, fixtures.Bob), invoke(t, fixtures.Mallory)}
+		conn := helpers.Must(client.NewConnection(fixtures.Service, mux))
+		resp := helpers.Must(client.Execute(t.Context(), invs, conn))
+
+		expect := []struct {
+			issuer ucan.Principal
+			status string
+		}{{fixtures.Service, "service"}, {fixtures.Bob, "bob"}, {fixtures.Bob, "bob"}}
+		for i, inv := range invs {
+			rcptlnk, ok := resp.Get(inv.Link())
+			require.True(t, ok)
+			rcpt := helpers.Must(receipt.NewAnyReceiptReader().Read(rcptlnk, resp.Blocks()))
+			require.Equal(t, expect[i].issuer.DID(), rcpt.Issuer().DID())
+			o, x := result.Unwrap(rcpt.Out())
+			require.Nil(t, x)
+			status := helpers.Must(helpers.Must(o.LookupByString("status")).AsString())
+			require.Equal(t, expect[i].status, status)
+		}
+	})
+
+	t.Run("unknown audience", func(t *testing.T) {
+		rcpt := helpers.Must(mux.Run(t.Context(), invoke(t, fixtures.Alice)))
+		require.Equal(t, fixtures.Service.DID(), rcpt.Issuer().DID())
+		_, x := result.Unwrap(rcpt.Out())
+		require.Equal(t, "InvalidAudienceError", *asFailure(t, x).Name)
+	})
+
+	t.Run("duplicate audience", func(t *testing.T) {
+		mallory := helpers.Must(NewServer(fixtures.Mallory, method("mallory")))
+		_, err := NewMux(service, bob, mallory)
+		require.ErrorContains(t, err, "duplicate server for audience: "+fixtures.Mallory.DID().String())
+
+		_, err = NewMux()
+		require.ErrorContains(t, err, "missing servers")
+	})
+}
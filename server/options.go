@@ -2,12 +2,18 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/core/invocation"
 	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
 	"github.com/storacha/go-ucanto/core/result"
 	"github.com/storacha/go-ucanto/core/result/failure"
+	udm "github.com/storacha/go-ucanto/core/result/ok/datamodel"
+	"github.com/storacha/go-ucanto/principal"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
 	"github.com/storacha/go-ucanto/server/transaction"
 	"github.com/storacha/go-ucanto/transport"
 	"github.com/storacha/go-ucanto/ucan"
@@ -30,23 +36,54 @@ type srvConfig struct {
 	altAudiences          []ucan.Principal
 	catch                 ErrorHandlerFunc
 	logReceipt            ReceiptLoggerFunc
+	maxConcurrency        int
+	interceptors          []Interceptor
+	replayStore           ReplayStore
+	replayRetention       time.Duration
+	receiptStore          receipt.Store
+	taskScheduler         TaskScheduler
+	taskExecutors         Service
+	descriptions          map[ucan.Ability]AbilityDescription
+	providers             map[ucan.Ability]provider
+	introspection         bool
+	dryRun                bool
+	revocationStore       RevocationStore
+	rateLimits            []RateLimit
+	rateLimitStore        RateLimitStore
+	timeout               time.Duration
+	timeouts              map[ucan.Ability]time.Duration
+	mounts                []string
+	proxyRoutes           []ProxyRoute
+	receiptSigner         principal.Signer
+	receiptProofs         []delegation.Delegation
+	limits                limits
+	statusPolicy          StatusPolicy
+	verificationCache     validator.VerificationCache
 }
 
-func WithServiceMethod[O ipld.Builder, X failure.IPLDBuilderFailure](can string, handleFunc ServiceMethod[O, X]) Option {
+// WithServiceMethod configures the method that handles invocations of the
+// passed ability. It is an error to configure more than one method for the
+// same ability.
+func WithServiceMethod[O ipld.Builder, X failure.IPLDBuilderFailure](can string, handleFunc Method[O, X]) Option {
 	return func(cfg *srvConfig) error {
-		cfg.service[can] = func(ctx context.Context, input invocation.Invocation, invCtx InvocationContext) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
-			tx, err := handleFunc(ctx, input, invCtx)
-			if err != nil {
-				return nil, err
-			}
-			out := result.MapResultR0(
-				tx.Out(),
-				func(o O) ipld.Builder { return o },
-				func(x X) failure.IPLDBuilderFailure { return x },
-			)
-			return transaction.NewTransaction(out, transaction.WithEffects(tx.Fx())), nil
+		return addServiceMethod(cfg, can, handleFunc)
+	}
+}
+
+// AnyServiceMethod converts a service method with specific result types to
+// one that can be added to a [Service].
+func AnyServiceMethod[O ipld.Builder, X failure.IPLDBuilderFailure](handleFunc Method[O, X]) ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure] {
+	return func(ctx context.Context, input invocation.Invocation, invCtx InvocationContext) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+		tx, err := handleFunc.Execute(ctx, input, invCtx)
+		if err != nil {
+			return nil, err
 		}
-		return nil
+		out := result.MapResultR0(
+			tx.Out(),
+			func(o O) ipld.Builder { return o },
+			func(x X) failure.IPLDBuilderFailure { return x },
+		)
+		return transaction.NewTransaction(out, transaction.WithEffects(tx.Fx()), transaction.WithMeta(tx.Meta())), nil
 	}
 }
 
@@ -68,6 +105,23 @@ func WithRevocationChecker(fn validator.RevocationCheckerFunc[any]) Option {
 	}
 }
 
+// WithRevocation enables the `ucan/revoke` capability, which allows an issuer
+// in the proof chain of a delegation to revoke it, and rejects invocations
+// authorized by a revoked delegation with a [validator.Revoked] error.
+// Revocations are recorded in the passed store, which may be shared by services
+// that accept the same delegations. Authorizations are checked against the
+// store (see [RevocationChecker]) before any revocation checker configured with
+// [WithRevocationChecker].
+func WithRevocation(store RevocationStore) Option {
+	return func(cfg *srvConfig) error {
+		if err := addServiceMethod(cfg, RevokeAbility, revoke(store)); err != nil {
+			return err
+		}
+		cfg.revocationStore = store
+		return WithAbilityDescription(Describe(Revoke, udm.UnitType(), sdm.InvalidRevocationErrorType()))(cfg)
+	}
+}
+
 // WithErrorHandler configures a function to be called when errors occur during
 // execution of a handler.
 func WithErrorHandler(fn ErrorHandlerFunc) Option {
@@ -149,3 +203,283 @@ func WithAlternativeAudiences(audiences ...ucan.Principal) Option {
 		return nil
 	}
 }
+
+// WithMaxConcurrency configures the maximum number of invocations from a single
+// agent message that will be executed concurrently. Receipts are always
+// returned in the same order as the invocations in the message, regardless of
+// this setting. Pass a value less than 1 to use the default
+// [DefaultMaxConcurrency].
+func WithMaxConcurrency(n int) Option {
+	return func(cfg *srvConfig) error {
+		cfg.maxConcurrency = n
+		return nil
+	}
+}
+
+// WithMaxRequestSize configures the maximum size in bytes of a request body.
+// Requests with a larger body receive a 413 Request Entity Too Large response.
+// By default there is no limit.
+func WithMaxRequestSize(n int64) Option {
+	return func(cfg *srvConfig) error {
+		cfg.limits.maxRequestSize = n
+		return nil
+	}
+}
+
+// WithMaxBlocks configures the maximum number of blocks in a request. Requests
+// with more blocks receive a 413 Request Entity Too Large response. By default
+// there is no limit.
+func WithMaxBlocks(n int) Option {
+	return func(cfg *srvConfig) error {
+		cfg.limits.maxBlocks = n
+		return nil
+	}
+}
+
+// WithMaxBlockSize configures the maximum size in bytes of a block in a
+// request, including its CID. Requests with a larger block receive a 413
+// Request Entity Too Large response. By default there is no limit.
+//
+// The default codec stops decoding a request as soon as it exceeds the block
+// limits. Requests decoded by a codec configured with [WithInboundCodec] are
+// checked once decoded.
+func WithMaxBlockSize(n uint64) Option {
+	return func(cfg *srvConfig) error {
+		cfg.limits.maxBlockSize = n
+		return nil
+	}
+}
+
+// WithMaxInvocations configures the maximum number of invocations in a single
+// agent message. Requests with more invocations receive a 413 Request Entity
+// Too Large response. By default there is no limit.
+func WithMaxInvocations(n int) Option {
+	return func(cfg *srvConfig) error {
+		cfg.limits.maxInvocations = n
+		return nil
+	}
+}
+
+// WithProofLimits configures limits on the depth and width of the proof chains
+// explored when authorizing an invocation. Invocations whose proofs exceed the
+// limits receive an [validator.Unauthorized] failure detailing the
+// [validator.ProofLimitExceeded] error. By default there are no limits.
+func WithProofLimits(limits validator.ProofLimits) Option {
+	return func(cfg *srvConfig) error {
+		if limits.MaxDepth < 0 || limits.MaxWidth < 0 {
+			return fmt.Errorf("invalid proof limits: limits must not be negative")
+		}
+		cfg.limits.proofs = limits
+		return nil
+	}
+}
+
+// WithVerificationCache configures a cache of successful verifications of
+// delegation signatures and session attestations, so that proof chains that
+// are presented repeatedly are not verified every time. The time bounds of
+// delegations are still validated for every invocation. The cache may be
+// shared by servers with the same identity, for example
+// [validator.MemoryVerificationCache].
+func WithVerificationCache(cache validator.VerificationCache) Option {
+	return func(cfg *srvConfig) error {
+		cfg.verificationCache = cache
+		return nil
+	}
+}
+
+// WithTimeout configures the maximum time a handler may take to execute an
+// invocation, unless configured otherwise for the ability with
+// [WithAbilityTimeout]. The context passed to the handler is canceled when the
+// timeout elapses and the invocation receives a [Timeout] failure, without
+// waiting for the handler to return. By default there is no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *srvConfig) error {
+		cfg.timeout = timeout
+		return nil
+	}
+}
+
+// WithAbilityTimeout configures the maximum time the handler for the passed
+// ability may take to execute an invocation, overriding the timeout configured
+// with [WithTimeout]. A zero timeout means no limit.
+func WithAbilityTimeout(can ucan.Ability, timeout time.Duration) Option {
+	return func(cfg *srvConfig) error {
+		if cfg.timeouts == nil {
+			cfg.timeouts = map[ucan.Ability]time.Duration{}
+		}
+		cfg.timeouts[can] = timeout
+		return nil
+	}
+}
+
+// WithInterceptor configures middleware that wraps every service method. It may
+// be passed multiple times. Interceptors run in the order they are configured,
+// the first being the outermost.
+func WithInterceptor(interceptors ...Interceptor) Option {
+	return func(cfg *srvConfig) error {
+		cfg.interceptors = append(cfg.interceptors, interceptors...)
+		return nil
+	}
+}
+
+// WithReplayProtection configures the server to reject invocations that have
+// already been executed with a [Replayed] failure. Invocations are recorded in
+// the passed store once authorized, and until they expire. Invocations that do
+// not expire within maxRetention are rejected, pass a value less than or equal
+// to zero to use [DefaultReplayRetention]. See [ReplayProtection] for details.
+// Replay protection is applied before any interceptors configured with
+// [WithInterceptor].
+func WithReplayProtection(store ReplayStore, maxRetention time.Duration) Option {
+	return func(cfg *srvConfig) error {
+		cfg.replayStore = store
+		cfg.replayRetention = maxRetention
+		return nil
+	}
+}
+
+// WithReceiptStore configures a store that every receipt issued by the server
+// is persisted to, keyed by invocation CID. An error persisting a receipt
+// causes the server to fail the request, in the same way as an error returned
+// from a receipt logger. See [NewReceiptHandler] for serving stored receipts.
+func WithReceiptStore(store receipt.Store) Option {
+	return func(cfg *srvConfig) error {
+		cfg.receiptStore = store
+		return nil
+	}
+}
+
+// WithRateLimits configures limits on the rate at which invocations are
+// executed, see [RateLimit]. Limits are evaluated in the order they are
+// configured, after an invocation has been authorized and before the handler
+// is called. Invocations that exceed a limit receive a [RateLimited] failure.
+//
+// Buckets are held in the passed store, or in a [MemoryRateLimitStore] of the
+// default size if nil. A store shared by multiple servers must only be used
+// with the same limits, since buckets are identified by the position of the
+// limit in the configuration.
+func WithRateLimits(store RateLimitStore, limits ...RateLimit) Option {
+	return func(cfg *srvConfig) error {
+		for _, l := range limits {
+			if l.Limit < 1 || l.Period <= 0 {
+				return fmt.Errorf("invalid rate limit: limit and period must be positive")
+			}
+			// tokens are replenished at intervals of period/limit
+			if l.Period/time.Duration(l.Limit) == 0 {
+				return fmt.Errorf("invalid rate limit: period %s is too short for a limit of %d", l.Period, l.Limit)
+			}
+		}
+		cfg.rateLimitStore = store
+		cfg.rateLimits = limits
+		return nil
+	}
+}
+
+// WithTaskExecution enables execution of effects by the server. When a receipt
+// is issued with fork or join effects that include the effect invocation (not
+// just a link to it), each effect invocation is scheduled using the passed
+// scheduler and executed by the server, as if it had been received.
+//
+// Receipts for effects are issued, logged and stored in the same way as
+// receipts for received invocations, so configure a receipt store (see
+// [WithReceiptStore]) to allow clients to resolve the receipt of a join.
+//
+// If scheduler is nil, each effect is executed in a new goroutine.
+func WithTaskExecution(scheduler TaskScheduler) Option {
+	return func(cfg *srvConfig) error {
+		if scheduler == nil {
+			scheduler = goScheduler{}
+		}
+		cfg.taskScheduler = scheduler
+		return nil
+	}
+}
+
+// WithTaskExecutor configures a method that executes effect invocations for
+// the passed ability, see [WithTaskExecution]. Task executors take precedence
+// over service methods for the same ability, but unlike service methods they
+// are not exposed to clients.
+func WithTaskExecutor[O ipld.Builder, X failure.IPLDBuilderFailure](can string, handleFunc Method[O, X]) Option {
+	return func(cfg *srvConfig) error {
+		if cfg.taskExecutors == nil {
+			cfg.taskExecutors = Service{}
+		}
+		cfg.taskExecutors[can] = AnyServiceMethod(handleFunc)
+		return nil
+	}
+}
+
+// WithConclusionHandler enables the `ucan/conclude` capability, which allows
+// agents to submit receipts for tasks the server delegated to them, typically
+// as fork effects. Submitted receipts are verified before being passed to the
+// handler and the agent receives a receipt acknowledging the conclusion.
+// Receipts may be submitted in a `ucan/conclude` invocation (see
+// client.Conclude) or reported directly in the agent message, in which case
+// the acknowledgement is for an invocation issued by the service to itself.
+func WithConclusionHandler(fn ConclusionHandlerFunc) Option {
+	return func(cfg *srvConfig) error {
+		if err := addServiceMethod(cfg, ConcludeAbility, conclude(fn)); err != nil {
+			return err
+		}
+		return WithAbilityDescription(Describe(Conclude, udm.UnitType(), sdm.InvalidReceiptErrorType()))(cfg)
+	}
+}
+
+// WithAbilityDescription configures descriptions of the abilities provided by
+// the service, which are returned by [Abilities] and the `ucanto/introspect`
+// capability (see [WithIntrospection]). Abilities provided by methods created
+// by [Provide] and configured with [WithServiceMethod] are described by their
+// capability without configuration, so
+// descriptions are only needed to add the types of results or to describe
+// other methods. Use [Describe] to derive a description from a capability
+// parser, for example:
+//
+//	server.WithServiceMethod(uploadadd.Can(), server.Provide(uploadadd, handler)),
+//	server.WithAbilityDescription(server.Describe(uploadadd, okType, errType)),
+func WithAbilityDescription(descriptions ...AbilityDescription) Option {
+	return func(cfg *srvConfig) error {
+		if cfg.descriptions == nil {
+			cfg.descriptions = map[ucan.Ability]AbilityDescription{}
+		}
+		for _, desc := range descriptions {
+			cfg.descriptions[desc.Can] = desc
+		}
+		return nil
+	}
+}
+
+// WithIntrospection enables the `ucanto/introspect` capability, which allows
+// any agent to discover the abilities supported by the service, along with the
+// schemas of their caveats and results where known. See [NewIntrospectionHandler]
+// for serving the same information over HTTP.
+func WithIntrospection() Option {
+	return func(cfg *srvConfig) error {
+		cfg.introspection = true
+		return nil
+	}
+}
+
+// WithDryRun enables the `ucanto/dry-run` capability, which allows any agent
+// to ask whether an invocation would be authorized without executing it. The
+// invocation is validated by the service method for its ability with the
+// invocation context of the service, including its proof resolver, revocation
+// checker and authority proofs, and the agent receives a receipt with the
+// resolved authorization, or the error that the invocation would fail with,
+// typically [validator.Unauthorized].
+//
+// The service method is resolved as it would be to execute the invocation,
+// including fallback methods (see [WithFallback]), and interceptors run
+// before it, so that an invocation they would fail fails the dry run in the
+// same way. Only service methods created with [Provide] and configured with
+// [WithServiceMethod] or [WithFallback] can be dry run, since they authorize
+// invocations without calling their handler (see [ProvidedMethod.Authorize]).
+// Dry runs of any other method, including the methods of a [Service] added
+// with [WithService] or [WithMount], and of proxied invocations (see
+// [WithProxy]), fail with an [InvalidDryRun] failure without calling the
+// method. Invocations are
+// not recorded for replay protection and rate limits are not applied.
+func WithDryRun() Option {
+	return func(cfg *srvConfig) error {
+		cfg.dryRun = true
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// ProvidedMethod is the service method created by [Provide] for a capability.
+// Unlike other service methods it declares the capability it provides, so that
+// the ability is described without configuration (see [Abilities]), and it can
+// authorize an invocation without calling its handler, so that the invocation
+// can be dry run (see [WithDryRun]).
+type ProvidedMethod[O ipld.Builder] struct {
+	describe  func() AbilityDescription
+	authorize func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (validator.Authorization[any], failure.IPLDBuilderFailure)
+	execute   ServiceMethod[O, failure.IPLDBuilderFailure]
+}
+
+// Execute validates the invocation and calls the handler if it is authorized.
+func (m ProvidedMethod[O]) Execute(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (transaction.Transaction[O, failure.IPLDBuilderFailure], error) {
+	return m.execute(ctx, inv, ictx)
+}
+
+// Description returns the description of the capability provided by the
+// method.
+func (m ProvidedMethod[O]) Description() AbilityDescription {
+	return m.describe()
+}
+
+// Authorize validates the invocation in the same way as [ProvidedMethod.Execute]
+// but does not call the handler. It returns the authorization of the
+// invocation, or the failure it would be rejected with.
+func (m ProvidedMethod[O]) Authorize(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (validator.Authorization[any], failure.IPLDBuilderFailure) {
+	return m.authorize(ctx, inv, ictx)
+}
+
+// provider is implemented by service methods that declare the capability they
+// provide (see [ProvidedMethod]).
+type provider interface {
+	Description() AbilityDescription
+	Authorize(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (validator.Authorization[any], failure.IPLDBuilderFailure)
+}
+
+// addServiceMethod adds a method to the service (see [srvConfig.addMethod]),
+// recording the capability it provides if it declares it.
+func addServiceMethod[O ipld.Builder, X failure.IPLDBuilderFailure](cfg *srvConfig, can ucan.Ability, method Method[O, X]) error {
+	if err := cfg.addMethod(can, AnyServiceMethod(method)); err != nil {
+		return err
+	}
+	if p, ok := method.(provider); ok {
+		if cfg.providers == nil {
+			cfg.providers = map[ucan.Ability]provider{}
+		}
+		cfg.providers[can] = p
+	}
+	return nil
+}
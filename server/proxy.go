@@ -0,0 +1,342 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/iterable"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/receipt/ran"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/transport"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// ProxyRoute forwards the invocations it matches to an upstream service. A
+// route matches an invocation if it matches both the namespace and the
+// audience. See [WithProxy].
+type ProxyRoute struct {
+	// Namespace is the ability namespace of the invocations forwarded by the
+	// route, for example "space/blob" matches "space/blob/add". If empty, the
+	// route matches invocations of any ability.
+	Namespace string
+	// Audience is the DID invocations forwarded by the route are addressed to.
+	// If undefined, the route matches invocations addressed to any audience.
+	Audience did.DID
+	// Connection is the connection to the upstream service.
+	Connection ProxyConnection
+	// Reissue causes the receipt from the upstream service to be re-issued by
+	// the proxy, with the upstream receipt linked from its metadata and
+	// attached (see [UpstreamReceipt]). By default, the upstream receipt is
+	// returned to the caller unchanged.
+	Reissue bool
+}
+
+// ProxyConnection is the connection of a [ProxyRoute] to an upstream service,
+// for example a connection created with client.NewConnection.
+type ProxyConnection interface {
+	Channel() transport.Channel
+	Codec() transport.OutboundCodec
+}
+
+func (r ProxyRoute) matches(inv invocation.Invocation, can ucan.Ability) bool {
+	if r.Audience != did.Undef && inv.Audience().DID() != r.Audience {
+		return false
+	}
+	return r.Namespace == "" || can == r.Namespace || inNamespace(r.Namespace, can)
+}
+
+// WithProxy configures routes that forward invocations to upstream services
+// rather than handling them with a service method. Routes are tried in the
+// order they are configured and invocations are forwarded by the first route
+// that matches. Invocations are forwarded as they were received, so the
+// upstream service performs authorization.
+//
+// Interceptors (see [WithInterceptor]) run before an invocation is forwarded,
+// and may short-circuit forwarding, in which case the proxy issues the receipt
+// for the transaction they return. If they return a different transaction for
+// a forwarded invocation, for example to add metadata or rewrite the result,
+// the proxy issues the receipt for that transaction with the upstream receipt
+// linked and attached as if the route re-issued it.
+//
+// The receipt for a forwarded invocation is stored and logged like the receipt
+// for any other invocation, but its effects are not executed by the proxy. An
+// error forwarding an invocation results in a receipt with a
+// [HandlerExecutionError] failure, and the handler timeout (see [WithTimeout])
+// applies to the upstream request.
+func WithProxy(routes ...ProxyRoute) Option {
+	return func(cfg *srvConfig) error {
+		for _, r := range routes {
+			if r.Connection == nil {
+				return fmt.Errorf("missing connection for proxy route: namespace %q, audience %q", r.Namespace, r.Audience)
+			}
+			if r.Namespace != "" && !isValidNamespace(r.Namespace) {
+				return fmt.Errorf("invalid proxy route namespace: %q", r.Namespace)
+			}
+		}
+		cfg.proxyRoutes = append(cfg.proxyRoutes, routes...)
+		return nil
+	}
+}
+
+// proxy is implemented by servers configured with proxy routes.
+type proxy interface {
+	route(inv invocation.Invocation, can ucan.Ability) (ProxyRoute, bool)
+	// interceptors are the interceptors run before an invocation is forwarded.
+	interceptors() []Interceptor
+}
+
+func (srv *server) route(inv invocation.Invocation, can ucan.Ability) (ProxyRoute, bool) {
+	for _, r := range srv.proxyRoutes {
+		if r.matches(inv, can) {
+			return r, true
+		}
+	}
+	return ProxyRoute{}, false
+}
+
+func (srv *server) interceptors() []Interceptor {
+	return srv.intercept
+}
+
+// forward runs an invocation of the passed capability (see
+// [invokedCapability]) by forwarding it to the upstream service of the passed
+// route, after the interceptors of the proxy.
+func forward(ctx context.Context, server Server[Service], p proxy, route ProxyRoute, cap ucan.Capability[any], inv invocation.Invocation) (receipt.AnyReceipt, error) {
+
+	// upstream is the receipt from the upstream service, nil if an interceptor
+	// did not forward the invocation, and forwarded is the transaction for it
+	// passed to the interceptors
+	var upstream receipt.AnyReceipt
+	var forwarded transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure]
+	method := Intercept(func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+		rcpt, err := Forward(ctx, route.Connection, inv)
+		if err != nil {
+			return nil, err
+		}
+		upstream = rcpt
+		forwarded = upstreamTransaction(rcpt)
+		return forwarded, nil
+	}, p.interceptors()...)
+
+	timeout := handlerTimeout(server, cap.Can())
+	tx, err := ExecuteHandler(ctx, timeout, func(ctx context.Context) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+		return method(ctx, inv, server.Context())
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if errors.Is(err, ErrHandlerTimeout) {
+			return IssueReceipt(server, result.NewFailure(NewTimeoutError(cap, timeout)), ran.FromInvocation(inv))
+		}
+		herr := NewHandlerExecutionError(err, cap)
+		server.Catch(herr)
+		return IssueReceipt(server, result.NewFailure(herr), ran.FromInvocation(inv))
+	}
+
+	var rcpt receipt.AnyReceipt
+	switch {
+	case upstream == nil:
+		var opts []receipt.Option
+		if fx := tx.Fx(); fx != nil {
+			opts = append(opts, receipt.WithJoin(fx.Join()), receipt.WithFork(fx.Fork()...))
+		}
+		if meta := tx.Meta(); len(meta) > 0 {
+			opts = append(opts, receipt.WithMeta(meta))
+		}
+		rcpt, err = IssueReceipt(server, tx.Out(), ran.FromInvocation(inv), opts...)
+	case tx == forwarded && !route.Reissue:
+		rcpt = upstream
+	default:
+		// the interceptors may have changed the transaction of the upstream
+		// receipt, so the proxy issues the receipt for the transaction they
+		// returned
+		signer, proofs := signerOf(server)
+		rcpt, err = issueUpstream(signer, upstream, tx.Out(), tx.Fx(), tx.Meta(), proofs...)
+	}
+	if err != nil {
+		herr := NewHandlerExecutionError(err, cap)
+		server.Catch(herr)
+		return IssueReceipt(server, result.NewFailure(herr), ran.FromInvocation(inv))
+	}
+
+	if err := server.LogReceipt(ctx, rcpt, inv); err != nil {
+		herr := NewHandlerExecutionError(err, cap)
+		server.Catch(herr)
+		return IssueReceipt(server, result.NewFailure(herr), ran.FromInvocation(inv))
+	}
+
+	return rcpt, nil
+}
+
+// upstreamTransaction is the transaction passed to interceptors for the
+// receipt from an upstream service.
+func upstreamTransaction(upstream receipt.AnyReceipt) transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure] {
+	out := result.MapResultR0(upstream.Out(), func(o ipld.Node) ipld.Builder {
+		return nodeBuilder{o}
+	}, func(x ipld.Node) failure.IPLDBuilderFailure {
+		return nodeFailure{x}
+	})
+	return &upstreamTx{transaction.NewTransaction(out, transaction.WithEffects(upstream.Fx()))}
+}
+
+// upstreamTx is the transaction for the receipt from an upstream service. It
+// is a pointer, so that it can be compared with the transaction returned by
+// the interceptors to tell if they returned it unchanged.
+type upstreamTx struct {
+	transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure]
+}
+
+// Forward sends the passed invocation to the upstream service over the passed
+// connection and returns the receipt it issued.
+func Forward(ctx context.Context, conn ProxyConnection, inv invocation.Invocation) (receipt.AnyReceipt, error) {
+	input, err := message.Build([]invocation.Invocation{inv}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building message: %w", err)
+	}
+	req, err := conn.Codec().Encode(input)
+	if err != nil {
+		return nil, fmt.Errorf("encoding message: %w", err)
+	}
+	res, err := conn.Channel().Request(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("forwarding invocation: %w", err)
+	}
+	defer res.Body().Close()
+	resp, err := conn.Codec().Decode(res)
+	if err != nil {
+		return nil, fmt.Errorf("decoding message: %w", err)
+	}
+	rcptlnk, ok := resp.Get(inv.Link())
+	if !ok {
+		return nil, fmt.Errorf("receipt not found in upstream response for invocation: %s", inv.Link())
+	}
+	rcpt, err := receipt.NewAnyReceiptReader().Read(rcptlnk, resp.Blocks())
+	if err != nil {
+		return nil, fmt.Errorf("reading upstream receipt: %w", err)
+	}
+	return rcpt, nil
+}
+
+// upstreamMetaKey is the key of the receipt metadata that links to the
+// upstream receipt of a re-issued receipt.
+const upstreamMetaKey = "upstream"
+
+// Reissue issues a receipt for the same invocation and with the same result
+// and effects as the passed upstream receipt, signed by the passed issuer with
+// the passed proofs. The upstream receipt is linked from the "upstream" key of
+// the metadata of the new receipt, and its blocks are included.
+func Reissue(issuer principal.Signer, upstream receipt.AnyReceipt, proofs ...delegation.Proof) (receipt.AnyReceipt, error) {
+	out := result.MapResultR0(upstream.Out(), func(o ipld.Node) ipld.Builder {
+		return nodeBuilder{o}
+	}, func(x ipld.Node) ipld.Builder {
+		return nodeBuilder{x}
+	})
+	return issueUpstream(issuer, upstream, out, upstream.Fx(), nil, proofs...)
+}
+
+// issueUpstream issues a receipt for the invocation of the passed upstream
+// receipt with the passed result, effects and metadata. The upstream receipt
+// is linked from the "upstream" key of the metadata, and its blocks are
+// included.
+func issueUpstream[O, X ipld.Builder](issuer principal.Signer, upstream receipt.AnyReceipt, out result.Result[O, X], effects fx.Effects, meta map[string]any, proofs ...delegation.Proof) (receipt.AnyReceipt, error) {
+	r := ran.FromLink(upstream.Ran().Link())
+	if inv, ok := upstream.Ran().Invocation(); ok {
+		r = ran.FromInvocation(inv)
+	}
+
+	upstreamlnk := upstream.Root().Link()
+	m := maps.Clone(meta)
+	if m == nil {
+		m = map[string]any{}
+	}
+	m[upstreamMetaKey] = &upstreamlnk
+	opts := []receipt.Option{receipt.WithMeta(m)}
+	if len(proofs) > 0 {
+		opts = append(opts, receipt.WithProofs(proofs))
+	}
+	if effects != nil {
+		opts = append(opts, receipt.WithJoin(effects.Join()), receipt.WithFork(effects.Fork()...))
+	}
+
+	rcpt, err := receipt.Issue(issuer, out, r, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("issuing receipt: %w", err)
+	}
+
+	br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(iterable.Concat2(rcpt.Blocks(), upstream.Blocks())))
+	if err != nil {
+		return nil, err
+	}
+	return receipt.NewAnyReceipt(rcpt.Root().Link(), br)
+}
+
+// UpstreamReceipt returns the upstream receipt attached to a receipt that was
+// re-issued by a proxy (see [ProxyRoute]). It returns false if no receipt is
+// attached.
+func UpstreamReceipt(rcpt receipt.AnyReceipt) (receipt.AnyReceipt, bool) {
+	nd, ok := rcpt.Meta()[upstreamMetaKey].(ipld.Node)
+	if !ok {
+		return nil, false
+	}
+	lnk, err := nd.AsLink()
+	if err != nil {
+		return nil, false
+	}
+	upstream, err := receipt.NewAnyReceiptReader().Read(lnk, rcpt.Blocks())
+	if err != nil {
+		return nil, false
+	}
+	return upstream, true
+}
+
+// nodeBuilder is an [ipld.Builder] for a node that has already been built.
+type nodeBuilder struct {
+	ipld.Node
+}
+
+func (n nodeBuilder) ToIPLD() (ipld.Node, error) {
+	return n.Node, nil
+}
+
+// nodeFailure is a [failure.IPLDBuilderFailure] for a failure that has already
+// been built.
+type nodeFailure struct {
+	ipld.Node
+}
+
+func (n nodeFailure) ToIPLD() (ipld.Node, error) {
+	return n.Node, nil
+}
+
+func (n nodeFailure) Name() string {
+	return n.field("name")
+}
+
+func (n nodeFailure) Error() string {
+	return n.field("message")
+}
+
+func (n nodeFailure) field(key string) string {
+	nd, err := n.Node.LookupByString(key)
+	if err != nil {
+		return ""
+	}
+	s, _ := nd.AsString()
+	return s
+}
+
+var _ proxy = (*server)(nil)
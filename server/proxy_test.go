@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/transport"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+type failingChannel struct{}
+
+func (failingChannel) Request(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestProxy(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+	upstream := helpers.Must(NewServer(
+		fixtures.Service,
+		WithServiceMethod(uploadadd.Can(), Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+		})),
+	))
+	conn := helpers.Must(client.NewConnection(fixtures.Service, upstream))
+
+	invoke := func(t *testing.T, can ucan.Ability) invocation.Invocation {
+		return helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, ucan.NewCapability(can, fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+	}
+
+	requireSuccess := func(t *testing.T, rcpt receipt.AnyReceipt) {
+		o, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+		require.NotNil(t, o)
+	}
+
+	t.Run("forwards by namespace", func(t *testing.T) {
+		proxy := helpers.Must(NewServer(fixtures.Bob, WithProxy(ProxyRoute{Namespace: "upload", Connection: conn})))
+
+		inv := invoke(t, uploadadd.Can())
+		rcpt := helpers.Must(proxy.Run(t.Context(), inv))
+		requireSuccess(t, rcpt)
+		require.Equal(t, fixtures.Service.DID(), rcpt.Issuer().DID())
+		require.Equal(t, inv.Link(), rcpt.Ran().Link())
+
+		rcpt = helpers.Must(proxy.Run(t.Context(), invoke(t, "store/add")))
+		_, x := result.Unwrap(rcpt.Out())
+		require.Equal(t, "HandlerNotFoundError", *asFailure(t, x).Name)
+		require.Equal(t, fixtures.Bob.DID(), rcpt.Issuer().DID())
+	})
+
+	t.Run("forwards by audience", func(t *testing.T) {
+		proxy := helpers.Must(NewServer(
+			fixtures.Bob,
+			WithProxy(
+				ProxyRoute{Audience: fixtures.Mallory.DID(), Connection: helpers.Must(client.NewConnection(fixtures.Mallory, failingChannel{}))},
+				ProxyRoute{Audience: fixtures.Service.DID(), Connection: conn},
+			),
+			WithErrorHandler(func(err HandlerExecutionError[any]) {}),
+		))
+
+		rcpt := helpers.Must(proxy.Run(t.Context(), invoke(t, uploadadd.Can())))
+		requireSuccess(t, rcpt)
+		require.Equal(t, fixtures.Service.DID(), rcpt.Issuer().DID())
+
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Mallory, ucan.NewCapability(uploadadd.Can(), fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		rcpt = helpers.Must(proxy.Run(t.Context(), inv))
+		_, x := result.Unwrap(rcpt.Out())
+		require.Equal(t, "HandlerExecutionError", *asFailure(t, x).Name)
+	})
+
+	t.Run("reissues receipts", func(t *testing.T) {
+		proxy := helpers.Must(NewServer(fixtures.Bob, WithProxy(ProxyRoute{Audience: fixtures.Service.DID(), Connection: conn, Reissue: true})))
+
+		inv := invoke(t, uploadadd.Can())
+		res := helpers.Must(client.Execute(t.Context(), []invocation.Invocation{inv}, helpers.Must(client.NewConnection(fixtures.Bob, proxy))))
+		rcptlnk, ok := res.Get(inv.Link())
+		require.True(t, ok)
+		rcpt := helpers.Must(receipt.NewAnyReceiptReader().Read(rcptlnk, res.Blocks()))
+		requireSuccess(t, rcpt)
+		require.Equal(t, fixtures.Bob.DID(), rcpt.Issuer().DID())
+		require.True(t, helpers.Must(rcpt.VerifySignature(fixtures.Bob.Verifier())))
+
+		upstreamRcpt, ok := UpstreamReceipt(rcpt)
+		require.True(t, ok)
+		require.Equal(t, fixtures.Service.DID(), upstreamRcpt.Issuer().DID())
+		require.Equal(t, inv.Link(), upstreamRcpt.Ran().Link())
+		require.True(t, helpers.Must(upstreamRcpt.VerifySignature(fixtures.Service.Verifier())))
+		require.Equal(t, upstreamRcpt.Out(), rcpt.Out())
+
+		// the upstream receipt is not a delegation proof
+		require.Empty(t, rcpt.Proofs())
+	})
+
+	t.Run("runs interceptors before forwarding", func(t *testing.T) {
+		var forwarded []string
+		proxy := helpers.Must(NewServer(
+			fixtures.Bob,
+			WithProxy(ProxyRoute{Connection: conn}),
+			WithInterceptor(func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext, next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+				if inv.Issuer().DID() == fixtures.Mallory.DID() {
+					return transaction.NewTransaction(result.Error[ipld.Builder](failure.FromError(NewHandlerNotFoundError(inv.Capabilities()[0])))), nil
+				}
+				tx, err := next(ctx, inv, ictx)
+				if err == nil {
+					forwarded = append(forwarded, inv.Link().String())
+				}
+				return tx, err
+			}),
+		))
+
+		inv := invoke(t, uploadadd.Can())
+		rcpt := helpers.Must(proxy.Run(t.Context(), inv))
+		requireSuccess(t, rcpt)
+		require.Equal(t, fixtures.Service.DID(), rcpt.Issuer().DID())
+		require.Equal(t, []string{inv.Link().String()}, forwarded)
+
+		// short-circuited by the interceptor, so the proxy issues the receipt
+		inv = helpers.Must(invocation.Invoke(fixtures.Mallory, fixtures.Service, ucan.NewCapability(uploadadd.Can(), fixtures.Mallory.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		rcpt = helpers.Must(proxy.Run(t.Context(), inv))
+		_, x := result.Unwrap(rcpt.Out())
+		require.Equal(t, "HandlerNotFoundError", *asFailure(t, x).Name)
+		require.Equal(t, fixtures.Bob.DID(), rcpt.Issuer().DID())
+		require.Len(t, forwarded, 1)
+	})
+
+	t.Run("interceptors change the upstream transaction", func(t *testing.T) {
+		region := "eu"
+		proxy := helpers.Must(NewServer(
+			fixtures.Bob,
+			WithProxy(ProxyRoute{Connection: conn}),
+			WithInterceptor(func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext, next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+				tx, err := next(ctx, inv, ictx)
+				if err != nil {
+					return nil, err
+				}
+				return transaction.NewTransaction(tx.Out(), transaction.WithEffects(tx.Fx()), transaction.WithMeta(map[string]any{"region": &region})), nil
+			}),
+		))
+
+		rcpt := helpers.Must(proxy.Run(t.Context(), invoke(t, uploadadd.Can())))
+		requireSuccess(t, rcpt)
+		require.Equal(t, fixtures.Bob.DID(), rcpt.Issuer().DID())
+		require.Equal(t, region, helpers.Must(rcpt.Meta()["region"].(ipld.Node).AsString()))
+
+		upstreamRcpt, ok := UpstreamReceipt(rcpt)
+		require.True(t, ok)
+		require.Equal(t, fixtures.Service.DID(), upstreamRcpt.Issuer().DID())
+		require.Equal(t, upstreamRcpt.Out(), rcpt.Out())
+	})
+
+	t.Run("forwards group invocations", func(t *testing.T) {
+		proxy := helpers.Must(NewServer(fixtures.Bob, WithProxy(ProxyRoute{Namespace: "upload", Connection: conn})))
+
+		nb := uploadAddCaveats{Root: helpers.RandomCID()}
+		inv := helpers.Must(invocation.InvokeCapabilities(fixtures.Alice, fixtures.Service, []ucan.Capability[uploadAddCaveats]{
+			ucan.NewCapability(uploadadd.Can(), fixtures.Alice.DID().String(), nb),
+			ucan.NewCapability("upload/put", fixtures.Alice.DID().String(), nb),
+		}))
+		rcpt := helpers.Must(proxy.Run(t.Context(), inv))
+		// the upstream service has no method for the group
+		_, x := result.Unwrap(rcpt.Out())
+		require.Equal(t, "HandlerNotFoundError", *asFailure(t, x).Name)
+		require.Equal(t, fixtures.Service.DID(), rcpt.Issuer().DID())
+		require.Equal(t, "upload/add&upload/put", helpers.Must(helpers.Must(helpers.Must(x.LookupByString("capability")).LookupByString("can")).AsString()))
+	})
+
+	t.Run("upstream failure", func(t *testing.T) {
+		var caught []HandlerExecutionError[any]
+		proxy := helpers.Must(NewServer(
+			fixtures.Bob,
+			WithProxy(ProxyRoute{Connection: helpers.Must(client.NewConnection(fixtures.Service, failingChannel{}))}),
+			WithErrorHandler(func(err HandlerExecutionError[any]) {
+				caught = append(caught, err)
+			}),
+		))
+
+		rcpt := helpers.Must(proxy.Run(t.Context(), invoke(t, uploadadd.Can())))
+		_, x := result.Unwrap(rcpt.Out())
+		require.Equal(t, "HandlerExecutionError", *asFailure(t, x).Name)
+		require.Len(t, caught, 1)
+		require.ErrorContains(t, caught[0], "connection refused")
+	})
+
+	t.Run("invalid routes", func(t *testing.T) {
+		_, err := NewServer(fixtures.Bob, WithProxy(ProxyRoute{Namespace: "upload"}))
+		require.ErrorContains(t, err, "missing connection")
+
+		_, err = NewServer(fixtures.Bob, WithProxy(ProxyRoute{Namespace: "upload/*", Connection: conn}))
+		require.ErrorContains(t, err, "invalid proxy route namespace")
+	})
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// RateLimitScope determines how invocations are grouped for rate limiting.
+// Scopes may be combined, for example RateLimitIssuer|RateLimitAbility limits
+// invocations of each ability by each issuer separately. A zero scope limits
+// all invocations together.
+type RateLimitScope uint
+
+const (
+	// RateLimitIssuer groups invocations by issuer DID.
+	RateLimitIssuer RateLimitScope = 1 << iota
+	// RateLimitResource groups invocations by resource (`with`).
+	RateLimitResource
+	// RateLimitAbility groups invocations by ability.
+	RateLimitAbility
+)
+
+// RateLimit is a token bucket limit on the invocations executed by the server.
+// Each group of invocations (see [RateLimitScope]) has a bucket that holds at
+// most Burst tokens and is refilled with Limit tokens every Period. An
+// invocation takes a token from its bucket after it has been authorized, and is
+// rejected with a [RateLimited] failure if the bucket is empty.
+//
+// A quota can be expressed as a limit with a long period, for example 1000
+// invocations per issuer per day:
+//
+//	server.RateLimit{Scope: server.RateLimitIssuer, Limit: 1000, Period: 24 * time.Hour}
+type RateLimit struct {
+	Scope RateLimitScope
+	// Abilities the limit applies to. If empty, it applies to all abilities.
+	Abilities []ucan.Ability
+	// Limit is the number of invocations allowed per Period.
+	Limit  int
+	Period time.Duration
+	// Burst is the maximum number of invocations allowed in quick succession.
+	// If less than 1, it is the same as Limit.
+	Burst int
+}
+
+// RateLimitStore stores the state of rate limit buckets.
+type RateLimitStore interface {
+	// Take takes a token from the bucket identified by key, which holds at most
+	// burst tokens and is refilled with one token every interval. A bucket that
+	// does not exist is full. It returns zero if a token was taken, or the time
+	// until a token will be available if the bucket is empty, in which case the
+	// bucket is not changed.
+	//
+	// Implementations must ensure that concurrent calls for the same key do not
+	// take more tokens than are available.
+	Take(ctx context.Context, key string, burst int, interval time.Duration) (time.Duration, error)
+}
+
+// rateLimiter is implemented by invocation contexts of servers configured with
+// rate limits.
+type rateLimiter interface {
+	takeToken(ctx context.Context, issuer did.DID, capability ucan.Capability[any]) (time.Duration, error)
+}
+
+type rateLimits struct {
+	limits []RateLimit
+	store  RateLimitStore
+}
+
+// takeToken takes a token from the bucket of each limit that applies to the
+// passed invocation, in the order the limits were configured. It stops at the
+// first bucket that is empty, so an invocation that is rejected still counts
+// against the limits before it.
+func (rl *rateLimits) takeToken(ctx context.Context, issuer did.DID, capability ucan.Capability[any]) (time.Duration, error) {
+	for i, limit := range rl.limits {
+		if len(limit.Abilities) > 0 && !slices.Contains(limit.Abilities, capability.Can()) {
+			continue
+		}
+		burst := limit.Burst
+		if burst < 1 {
+			burst = limit.Limit
+		}
+		interval := limit.Period / time.Duration(limit.Limit)
+
+		wait, err := rl.store.Take(ctx, rateLimitKey(i, limit.Scope, issuer, capability), burst, interval)
+		if err != nil {
+			return 0, fmt.Errorf("taking rate limit token: %w", err)
+		}
+		if wait > 0 {
+			return wait, nil
+		}
+	}
+	return 0, nil
+}
+
+// rateLimitKey identifies a bucket by the position of the limit in the server
+// configuration and the parts of the invocation selected by the scope.
+func rateLimitKey(i int, scope RateLimitScope, issuer did.DID, capability ucan.Capability[any]) string {
+	parts := []string{fmt.Sprintf("%d", i)}
+	if scope&RateLimitIssuer != 0 {
+		parts = append(parts, issuer.String())
+	}
+	if scope&RateLimitResource != 0 {
+		parts = append(parts, capability.With())
+	}
+	if scope&RateLimitAbility != 0 {
+		parts = append(parts, capability.Can())
+	}
+	return strings.Join(parts, " ")
+}
+
+// MemoryRateLimitStoreSize is the default maximum number of buckets held by a
+// [MemoryRateLimitStore].
+const MemoryRateLimitStoreSize = 10_000
+
+// MemoryRateLimitStore is an in-memory [RateLimitStore] that holds a fixed
+// number of buckets. When full, the least recently used bucket is evicted, which
+// resets it, so the size should be chosen to accommodate the expected number of
+// active issuers, resources or abilities.
+type MemoryRateLimitStore struct {
+	mutex sync.Mutex
+	// buckets hold the time at which the bucket will be full
+	buckets *lru.Cache[string, time.Time]
+}
+
+// NewMemoryRateLimitStore creates a new in-memory store of rate limit buckets.
+// The size parameter controls the maximum number of buckets that can be held.
+// Pass a value less than 1 to use the default size [MemoryRateLimitStoreSize].
+func NewMemoryRateLimitStore(size int) (*MemoryRateLimitStore, error) {
+	if size <= 0 {
+		size = MemoryRateLimitStoreSize
+	}
+	cache, err := lru.New[string, time.Time](size)
+	if err != nil {
+		return nil, fmt.Errorf("creating rate limit LRU: %w", err)
+	}
+	return &MemoryRateLimitStore{buckets: cache}, nil
+}
+
+func (m *MemoryRateLimitStore) Take(ctx context.Context, key string, burst int, interval time.Duration) (time.Duration, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// The bucket is tracked as the time at which it will be full. Each token
+	// taken moves that time forward by one interval, and the bucket is empty
+	// when it is more than burst intervals in the future.
+	now := time.Now()
+	full, ok := m.buckets.Get(key)
+	if !ok || full.Before(now) {
+		full = now
+	}
+	next := full.Add(interval)
+	if wait := next.Sub(now) - time.Duration(burst)*interval; wait > 0 {
+		return wait, nil
+	}
+	m.buckets.Add(key, next)
+	return 0, nil
+}
+
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
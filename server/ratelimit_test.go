@@ -0,0 +1,103 @@
+package server
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/principal"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimits(t *testing.T) {
+	newServer := func(t *testing.T, calls *atomic.Int64, limits ...RateLimit) ServerView[Service] {
+		return newUploadAddServer(t, uploadAddOk(calls), WithRateLimits(nil, limits...))
+	}
+
+	execute := func(t *testing.T, srv ServerView[Service], issuer principal.Signer, with ucan.Resource) ipld.Node {
+		_, x := result.Unwrap(executeInvocation(t, srv, newUploadAddInvocation(t, issuer, with)).Out())
+		return x
+	}
+
+	t.Run("limits issuer", func(t *testing.T) {
+		var calls atomic.Int64
+		srv := newServer(t, &calls, RateLimit{Scope: RateLimitIssuer, Limit: 2, Period: time.Hour})
+
+		require.Nil(t, execute(t, srv, fixtures.Alice, fixtures.Alice.DID().String()))
+		require.Nil(t, execute(t, srv, fixtures.Alice, fixtures.Alice.DID().String()))
+
+		x := execute(t, srv, fixtures.Alice, fixtures.Alice.DID().String())
+		require.NotNil(t, x)
+		rlerr := helpers.Must(ipld.Rebind[sdm.RateLimitedErrorModel](x, sdm.RateLimitedErrorType()))
+		require.Equal(t, "RateLimited", *rlerr.Name)
+		require.Greater(t, rlerr.RetryAfter, int64(0))
+		require.LessOrEqual(t, rlerr.RetryAfter, int64(30*60))
+		require.Equal(t, int64(2), calls.Load())
+
+		// other issuers have their own bucket
+		require.Nil(t, execute(t, srv, fixtures.Bob, fixtures.Bob.DID().String()))
+		require.Equal(t, int64(3), calls.Load())
+	})
+
+	t.Run("unauthorized invocations are not counted", func(t *testing.T) {
+		var calls atomic.Int64
+		srv := newServer(t, &calls, RateLimit{Scope: RateLimitIssuer, Limit: 1, Period: time.Hour})
+
+		x := execute(t, srv, fixtures.Mallory, fixtures.Alice.DID().String())
+		require.NotNil(t, x)
+		require.NotEqual(t, "RateLimited", *asFailure(t, x).Name)
+
+		require.Nil(t, execute(t, srv, fixtures.Mallory, fixtures.Mallory.DID().String()))
+		require.Equal(t, int64(1), calls.Load())
+	})
+
+	t.Run("limits only configured abilities", func(t *testing.T) {
+		var calls atomic.Int64
+		srv := newServer(t, &calls, RateLimit{Abilities: []ucan.Ability{"upload/remove"}, Limit: 1, Period: time.Hour})
+
+		require.Nil(t, execute(t, srv, fixtures.Alice, fixtures.Alice.DID().String()))
+		require.Nil(t, execute(t, srv, fixtures.Alice, fixtures.Alice.DID().String()))
+		require.Equal(t, int64(2), calls.Load())
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		_, err := NewServer(fixtures.Service, WithRateLimits(nil, RateLimit{Limit: 0, Period: time.Second}))
+		require.Error(t, err)
+
+		// the replenishment interval would be zero
+		_, err = NewServer(fixtures.Service, WithRateLimits(nil, RateLimit{Limit: 10, Period: time.Nanosecond}))
+		require.Error(t, err)
+	})
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := helpers.Must(NewMemoryRateLimitStore(0))
+	interval := 50 * time.Millisecond
+
+	for range 2 {
+		wait, err := store.Take(t.Context(), "key", 2, interval)
+		require.NoError(t, err)
+		require.Zero(t, wait)
+	}
+
+	wait, err := store.Take(t.Context(), "key", 2, interval)
+	require.NoError(t, err)
+	require.Greater(t, wait, time.Duration(0))
+	require.LessOrEqual(t, wait, interval)
+
+	// other buckets are unaffected
+	wait, err = store.Take(t.Context(), "other", 2, interval)
+	require.NoError(t, err)
+	require.Zero(t, wait)
+
+	time.Sleep(interval)
+	wait, err = store.Take(t.Context(), "key", 2, interval)
+	require.NoError(t, err)
+	require.Zero(t, wait)
+}
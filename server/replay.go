@@ -0,0 +1,222 @@
+package server
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server/transaction"
+)
+
+// ReplayStore records invocations that have been executed, so that replays can
+// be detected.
+type ReplayStore interface {
+	// Add records the invocation identified by the passed link as seen. The
+	// record must be retained until at least the passed expiration time. A zero
+	// expiration time indicates the invocation never expires.
+	//
+	// It returns false if the invocation was already recorded and the record
+	// has not expired. Implementations must ensure that concurrent calls for
+	// the same link return true at most once.
+	Add(ctx context.Context, invocation ipld.Link, expiration time.Time) (bool, error)
+	// Remove discards the record of the invocation identified by the passed
+	// link, so that it may be executed again. Removing an invocation that is
+	// not recorded must not fail.
+	Remove(ctx context.Context, invocation ipld.Link) error
+}
+
+// DefaultReplayRetention is the maximum time until an invocation expires for it
+// to be accepted by replay protection, unless configured otherwise.
+const DefaultReplayRetention = time.Hour
+
+// ReplayProtection creates an interceptor that rejects invocations that have
+// already been executed with a [Replayed] failure.
+//
+// Invocations are identified by their CID, which covers the issuer and nonce
+// as well as the rest of the invocation. An invocation is recorded in the
+// passed store once it has been authorized by a service method created with
+// [Provide], so that invocations that are not authorized are never recorded,
+// and is retained until it expires, after which it is rejected by the
+// validator instead. If the handler returns an error the record is removed, so
+// that the invocation may be retried. Invocations handled by other service
+// methods are not recorded.
+//
+// Invocations that do not expire, or that expire more than maxRetention from
+// now, are rejected with an [InvalidExpiration] failure, so that records are
+// not retained indefinitely. Pass a value less than or equal to zero to use
+// [DefaultReplayRetention].
+func ReplayProtection(store ReplayStore, maxRetention time.Duration) Interceptor {
+	if maxRetention <= 0 {
+		maxRetention = DefaultReplayRetention
+	}
+	return func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext, next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+		exp := inv.Expiration()
+		if exp == nil || time.Unix(int64(*exp), 0).After(time.Now().Add(maxRetention)) {
+			return transaction.NewTransaction(result.Error[ipld.Builder, failure.IPLDBuilderFailure](NewInvalidExpirationError(inv.Link(), maxRetention))), nil
+		}
+		guard := &replayGuard{store: store, invocation: inv.Link(), expiration: time.Unix(int64(*exp), 0)}
+		return next(context.WithValue(ctx, replayGuardKey{}, guard), inv, ictx)
+	}
+}
+
+// replayGuardKey is the context key of the replay guard of an invocation.
+type replayGuardKey struct{}
+
+// replayGuard records an invocation in a replay store once it has been
+// authorized.
+type replayGuard struct {
+	store      ReplayStore
+	invocation ipld.Link
+	expiration time.Time
+}
+
+// guardReplay records the passed invocation if the context is that of replay
+// protection of it (see [ReplayProtection]). It returns a [Replayed] failure if
+// the invocation has already been recorded, or a nil guard if replay
+// protection does not apply.
+func guardReplay(ctx context.Context, inv invocation.Invocation) (*replayGuard, Replayed, error) {
+	guard, ok := ctx.Value(replayGuardKey{}).(*replayGuard)
+	if !ok || guard.invocation.String() != inv.Link().String() {
+		return nil, nil, nil
+	}
+	added, err := guard.store.Add(ctx, guard.invocation, guard.expiration)
+	if err != nil {
+		return nil, nil, fmt.Errorf("recording invocation: %w", err)
+	}
+	if !added {
+		return nil, NewReplayedError(guard.invocation), nil
+	}
+	return guard, nil, nil
+}
+
+// release removes the record of the invocation, so that it may be retried.
+func (g *replayGuard) release(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	if err := g.store.Remove(ctx, g.invocation); err != nil {
+		return fmt.Errorf("removing invocation record: %w", err)
+	}
+	return nil
+}
+
+// MemoryReplayStoreSize is the default maximum number of invocations recorded
+// by a [MemoryReplayStore].
+const MemoryReplayStoreSize = 10_000
+
+// MemoryReplayStore is an in-memory [ReplayStore] that records at most a fixed
+// number of invocations. Expired records are discarded as new invocations are
+// added. If the store is full of unexpired records, the record closest to
+// expiry is evicted, after which a replay of that invocation would not be
+// detected, so the size should be chosen to accommodate the expected number of
+// invocations received within the typical expiry window.
+type MemoryReplayStore struct {
+	mutex   sync.Mutex
+	size    int
+	records map[string]*replayRecord
+	queue   replayQueue
+}
+
+// NewMemoryReplayStore creates a new in-memory store of invocations that have
+// been executed. The size parameter controls the maximum number of invocations
+// that can be recorded. Pass a value less than 1 to use the default size
+// [MemoryReplayStoreSize].
+func NewMemoryReplayStore(size int) *MemoryReplayStore {
+	if size <= 0 {
+		size = MemoryReplayStoreSize
+	}
+	return &MemoryReplayStore{size: size, records: map[string]*replayRecord{}}
+}
+
+func (m *MemoryReplayStore) Add(ctx context.Context, invocation ipld.Link, expiration time.Time) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	exp := int64(math.MaxInt64)
+	if !expiration.IsZero() {
+		if !expiration.After(now) {
+			return true, nil
+		}
+		exp = expiration.UnixNano()
+	}
+
+	key := invocation.String()
+	if r, ok := m.records[key]; ok {
+		if r.expiration > now.UnixNano() {
+			return false, nil
+		}
+		heap.Remove(&m.queue, r.index)
+		delete(m.records, key)
+	}
+
+	// discard expired records
+	for len(m.queue) > 0 && m.queue[0].expiration <= now.UnixNano() {
+		r := heap.Pop(&m.queue).(*replayRecord)
+		delete(m.records, r.key)
+	}
+	// evict the record closest to expiry if still full
+	for len(m.queue) >= m.size {
+		r := heap.Pop(&m.queue).(*replayRecord)
+		delete(m.records, r.key)
+	}
+
+	r := &replayRecord{key: key, expiration: exp}
+	heap.Push(&m.queue, r)
+	m.records[key] = r
+	return true, nil
+}
+
+func (m *MemoryReplayStore) Remove(ctx context.Context, invocation ipld.Link) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := invocation.String()
+	if r, ok := m.records[key]; ok {
+		heap.Remove(&m.queue, r.index)
+		delete(m.records, key)
+	}
+	return nil
+}
+
+var _ ReplayStore = (*MemoryReplayStore)(nil)
+
+type replayRecord struct {
+	key        string
+	expiration int64
+	index      int
+}
+
+// replayQueue is a min-heap of records ordered by expiration.
+type replayQueue []*replayRecord
+
+func (q replayQueue) Len() int           { return len(q) }
+func (q replayQueue) Less(i, j int) bool { return q[i].expiration < q[j].expiration }
+
+func (q replayQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *replayQueue) Push(x any) {
+	r := x.(*replayRecord)
+	r.index = len(*q)
+	*q = append(*q, r)
+}
+
+func (q *replayQueue) Pop() any {
+	old := *q
+	n := len(old)
+	r := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return r
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayProtection(t *testing.T) {
+	t.Run("rejects replay", func(t *testing.T) {
+		var calls atomic.Int64
+		server := newUploadAddServer(t, uploadAddOk(&calls), WithReplayProtection(NewMemoryReplayStore(0), 0))
+
+		// default expiration set by ucan.Issue is 30s from now
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+		require.NotNil(t, inv.Expiration())
+
+		require.Equal(t, "", receiptFailure(t, executeInvocation(t, server, inv)))
+		require.Equal(t, "Replayed", receiptFailure(t, executeInvocation(t, server, inv)))
+		require.Equal(t, int64(1), calls.Load())
+
+		// a new invocation of the same capability is not a replay
+		inv2 := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+		require.Equal(t, "", receiptFailure(t, executeInvocation(t, server, inv2)))
+		require.Equal(t, int64(2), calls.Load())
+	})
+
+	t.Run("expired invocation is not recorded", func(t *testing.T) {
+		store := NewMemoryReplayStore(0)
+		server := newUploadAddServer(t, nil, WithReplayProtection(store, 0))
+
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String(), delegation.WithExpiration(int(time.Now().Add(-time.Minute).Unix())))
+		require.Equal(t, "Unauthorized", receiptFailure(t, executeInvocation(t, server, inv)))
+		require.Empty(t, store.records)
+	})
+
+	t.Run("rejects invocation without expiration", func(t *testing.T) {
+		var calls atomic.Int64
+		store := NewMemoryReplayStore(0)
+		server := newUploadAddServer(t, uploadAddOk(&calls), WithReplayProtection(store, time.Minute))
+
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String(), delegation.WithNoExpiration())
+		require.Nil(t, inv.Expiration())
+		require.Equal(t, "InvalidExpiration", receiptFailure(t, executeInvocation(t, server, inv)))
+
+		// nor one that expires after the maximum retention
+		inv = newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String(), delegation.WithExpiration(int(time.Now().Add(time.Hour).Unix())))
+		require.Equal(t, "InvalidExpiration", receiptFailure(t, executeInvocation(t, server, inv)))
+
+		require.Equal(t, int64(0), calls.Load())
+		require.Empty(t, store.records)
+	})
+
+	t.Run("unauthorized invocations are not recorded", func(t *testing.T) {
+		store := NewMemoryReplayStore(2)
+		server := newUploadAddServer(t, nil, WithReplayProtection(store, 0))
+
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+		require.Equal(t, "", receiptFailure(t, executeInvocation(t, server, inv)))
+
+		// a flood of invocations that are not authorized does not evict the record
+		for range 5 {
+			flood := newUploadAddInvocation(t, fixtures.Bob, fixtures.Alice.DID().String())
+			require.Equal(t, "Unauthorized", receiptFailure(t, executeInvocation(t, server, flood)))
+		}
+		require.Len(t, store.records, 1)
+		require.Equal(t, "Replayed", receiptFailure(t, executeInvocation(t, server, inv)))
+	})
+
+	t.Run("handler error allows retry", func(t *testing.T) {
+		var calls atomic.Int64
+		store := NewMemoryReplayStore(0)
+		server := newUploadAddServer(t, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			if calls.Add(1) == 1 {
+				return nil, nil, errors.New("boom")
+			}
+			return uploadAddOk(nil)(ctx, cap, inv, ictx)
+		}, WithReplayProtection(store, 0))
+
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+		require.Equal(t, "HandlerExecutionError", receiptFailure(t, executeInvocation(t, server, inv)))
+		require.Empty(t, store.records)
+
+		require.Equal(t, "", receiptFailure(t, executeInvocation(t, server, inv)))
+		require.Equal(t, "Replayed", receiptFailure(t, executeInvocation(t, server, inv)))
+		require.Equal(t, int64(2), calls.Load())
+	})
+}
+
+func TestMemoryReplayStore(t *testing.T) {
+	t.Run("records until expiration", func(t *testing.T) {
+		store := NewMemoryReplayStore(0)
+		lnk := helpers.RandomCID()
+
+		added := helpers.Must(store.Add(t.Context(), lnk, time.Now().Add(50*time.Millisecond)))
+		require.True(t, added)
+		added = helpers.Must(store.Add(t.Context(), lnk, time.Now().Add(50*time.Millisecond)))
+		require.False(t, added)
+
+		time.Sleep(100 * time.Millisecond)
+		added = helpers.Must(store.Add(t.Context(), lnk, time.Now().Add(time.Minute)))
+		require.True(t, added)
+		require.Len(t, store.records, 1)
+		require.Len(t, store.queue, 1)
+	})
+
+	t.Run("no expiration", func(t *testing.T) {
+		store := NewMemoryReplayStore(0)
+		lnk := helpers.RandomCID()
+
+		require.True(t, helpers.Must(store.Add(t.Context(), lnk, time.Time{})))
+		require.False(t, helpers.Must(store.Add(t.Context(), lnk, time.Time{})))
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		store := NewMemoryReplayStore(2)
+		a, b, c := helpers.RandomCID(), helpers.RandomCID(), helpers.RandomCID()
+
+		require.True(t, helpers.Must(store.Add(t.Context(), a, time.Now().Add(time.Minute))))
+		require.True(t, helpers.Must(store.Add(t.Context(), b, time.Now().Add(time.Hour))))
+		require.True(t, helpers.Must(store.Add(t.Context(), c, time.Now().Add(time.Hour))))
+		require.Len(t, store.records, 2)
+
+		// closest to expiry was evicted
+		require.True(t, helpers.Must(store.Add(t.Context(), a, time.Now().Add(time.Minute))))
+		require.False(t, helpers.Must(store.Add(t.Context(), c, time.Now().Add(time.Hour))))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		store := NewMemoryReplayStore(0)
+		a, b := helpers.RandomCID(), helpers.RandomCID()
+
+		require.True(t, helpers.Must(store.Add(t.Context(), a, time.Now().Add(time.Minute))))
+		require.True(t, helpers.Must(store.Add(t.Context(), b, time.Now().Add(time.Hour))))
+		require.NoError(t, store.Remove(t.Context(), a))
+		require.NoError(t, store.Remove(t.Context(), helpers.RandomCID()))
+		require.Len(t, store.records, 1)
+		require.Len(t, store.queue, 1)
+
+		require.True(t, helpers.Must(store.Add(t.Context(), a, time.Now().Add(time.Minute))))
+		require.False(t, helpers.Must(store.Add(t.Context(), b, time.Now().Add(time.Hour))))
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		store := NewMemoryReplayStore(0)
+		lnk := helpers.RandomCID()
+
+		var wg sync.WaitGroup
+		var added atomic.Int64
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if helpers.Must(store.Add(t.Context(), lnk, time.Now().Add(time.Minute))) {
+					added.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int64(1), added.Load())
+	})
+}
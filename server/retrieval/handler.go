@@ -41,7 +41,7 @@ func Provide[C any, O ipld.Builder, X failure.IPLDBuilderFailure](
 			response = res
 			return out, fx, err
 		})
-		tx, err := method(ctx, inv, ictx)
+		tx, err := method.Execute(ctx, inv, ictx)
 		return tx, response, err
 	}
 }
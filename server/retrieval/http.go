@@ -0,0 +1,24 @@
+package retrieval
+
+import (
+	"net/http"
+
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/server"
+	thttp "github.com/storacha/go-ucanto/transport/http"
+)
+
+// NewHTTPHandler creates a [http.Handler] for the passed retrieval server. It
+// accepts GET and HEAD requests only, unless configured otherwise with
+// [thttp.WithAllowedMethods]. For HEAD requests the response body returned by
+// the handler is closed without being read.
+func NewHTTPHandler(server *Server, options ...thttp.HandlerOption) http.Handler {
+	options = append([]thttp.HandlerOption{thttp.WithAllowedMethods(http.MethodGet, http.MethodHead)}, options...)
+	return thttp.NewHandler(server, options...)
+}
+
+// NewReceiptHandler creates a [http.Handler] that serves receipts from the
+// passed store. See [server.NewReceiptHandler].
+func NewReceiptHandler(store receipt.Store) http.Handler {
+	return server.NewReceiptHandler(store)
+}
@@ -0,0 +1,34 @@
+package retrieval
+
+import (
+	"context"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/server"
+	"github.com/storacha/go-ucanto/server/transaction"
+)
+
+// Intercept wraps the passed retrieval service method with the passed
+// [server.Interceptor]s, allowing the same middleware to be used for both
+// standard and retrieval servers. The first interceptor is the outermost.
+//
+// If an interceptor short-circuits execution (does not call next) the
+// retrieval [Response] is the zero value, and the HTTP response consists of
+// the agent message only.
+func Intercept(method ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure], interceptors ...server.Interceptor) ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure] {
+	if len(interceptors) == 0 {
+		return method
+	}
+	return func(ctx context.Context, inv invocation.Invocation, ictx server.InvocationContext, req Request) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], Response, error) {
+		var resp Response
+		next := func(ctx context.Context, inv invocation.Invocation, ictx server.InvocationContext) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+			tx, r, err := method(ctx, inv, ictx, req)
+			resp = r
+			return tx, err
+		}
+		tx, err := server.Intercept(next, interceptors...)(ctx, inv, ictx)
+		return tx, resp, err
+	}
+}
@@ -0,0 +1,78 @@
+package retrieval
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/server"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+var testEcho = validator.NewCapability(
+	"test/echo",
+	schema.DIDString(),
+	schema.Struct[ucan.NoCaveats](nil, nil),
+	nil,
+)
+
+func TestIntercept(t *testing.T) {
+	method := WithServiceMethod(
+		testEcho.Can(),
+		Provide(testEcho, func(ctx context.Context, cap ucan.Capability[ucan.NoCaveats], inv invocation.Invocation, ictx server.InvocationContext, req Request) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, Response, error) {
+			return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, NewResponse(http.StatusTeapot, nil, nil), nil
+		}),
+	)
+
+	inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, ucan.NewCapability(testEcho.Can(), fixtures.Alice.DID().String(), ucan.NoCaveats{})))
+
+	t.Run("passes through response", func(t *testing.T) {
+		var called bool
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			method,
+			WithInterceptor(func(ctx context.Context, inv invocation.Invocation, ictx server.InvocationContext, next server.ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+				called = true
+				return next(ctx, inv, ictx)
+			}),
+		))
+
+		rcpt, resp, err := Run(t.Context(), srv, inv, Request{})
+		require.NoError(t, err)
+		require.True(t, called)
+		require.Equal(t, http.StatusTeapot, resp.Status)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+	})
+
+	t.Run("short-circuit", func(t *testing.T) {
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			method,
+			WithInterceptor(func(ctx context.Context, inv invocation.Invocation, ictx server.InvocationContext, next server.ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+				x := failure.FromError(NewAgentMessageInvocationCountError())
+				return transaction.NewTransaction(result.Error[ipld.Builder](x)), nil
+			}),
+		))
+
+		rcpt, resp, err := Run(t.Context(), srv, inv, Request{})
+		require.NoError(t, err)
+		require.Equal(t, Response{}, resp)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+	})
+}
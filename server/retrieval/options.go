@@ -2,12 +2,15 @@ package retrieval
 
 import (
 	"context"
+	"time"
 
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/core/invocation"
 	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
 	"github.com/storacha/go-ucanto/core/result"
 	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/principal"
 	"github.com/storacha/go-ucanto/server"
 	"github.com/storacha/go-ucanto/server/transaction"
 	"github.com/storacha/go-ucanto/ucan"
@@ -30,6 +33,19 @@ type srvConfig struct {
 	catch                 server.ErrorHandlerFunc
 	logReceipt            server.ReceiptLoggerFunc
 	delegationCache       delegation.Store
+	interceptors          []server.Interceptor
+	replayStore           server.ReplayStore
+	replayRetention       time.Duration
+	receiptStore          receipt.Store
+	rateLimits            []server.RateLimit
+	rateLimitStore        server.RateLimitStore
+	timeout               time.Duration
+	timeouts              map[ucan.Ability]time.Duration
+	receiptSigner         principal.Signer
+	receiptProofs         []delegation.Delegation
+	maxBlocks             int
+	maxBlockSize          uint64
+	proofLimits           validator.ProofLimits
 }
 
 func WithServiceMethod[O ipld.Builder, X failure.IPLDBuilderFailure](can string, handler ServiceMethod[O, X]) Option {
@@ -44,7 +60,7 @@ func WithServiceMethod[O ipld.Builder, X failure.IPLDBuilderFailure](can string,
 				func(o O) ipld.Builder { return o },
 				func(x X) failure.IPLDBuilderFailure { return x },
 			)
-			return transaction.NewTransaction(out, transaction.WithEffects(tx.Fx())), resp, nil
+			return transaction.NewTransaction(out, transaction.WithEffects(tx.Fx()), transaction.WithMeta(tx.Meta())), resp, nil
 		}
 		return nil
 	}
@@ -141,3 +157,109 @@ func WithDelegationCache(cache delegation.Store) Option {
 		return nil
 	}
 }
+
+// WithInterceptor configures middleware that wraps every service method. It may
+// be passed multiple times. Interceptors run in the order they are configured,
+// the first being the outermost.
+func WithInterceptor(interceptors ...server.Interceptor) Option {
+	return func(cfg *srvConfig) error {
+		cfg.interceptors = append(cfg.interceptors, interceptors...)
+		return nil
+	}
+}
+
+// WithReplayProtection configures the server to reject invocations that have
+// already been executed with a [server.Replayed] failure, see
+// [server.WithReplayProtection]. Replay protection is applied before any
+// interceptors configured with [WithInterceptor].
+func WithReplayProtection(store server.ReplayStore, maxRetention time.Duration) Option {
+	return func(cfg *srvConfig) error {
+		cfg.replayStore = store
+		cfg.replayRetention = maxRetention
+		return nil
+	}
+}
+
+// WithRateLimits configures limits on the rate at which invocations are
+// executed, see [server.WithRateLimits].
+func WithRateLimits(store server.RateLimitStore, limits ...server.RateLimit) Option {
+	return func(cfg *srvConfig) error {
+		cfg.rateLimitStore = store
+		cfg.rateLimits = limits
+		return nil
+	}
+}
+
+// WithTimeout configures the maximum time a handler may take to execute an
+// invocation, see [server.WithTimeout]. If a handler times out, the response it
+// returns after the timeout, including any body, is discarded.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *srvConfig) error {
+		cfg.timeout = timeout
+		return nil
+	}
+}
+
+// WithAbilityTimeout configures the maximum time the handler for the passed
+// ability may take to execute an invocation, see [server.WithAbilityTimeout].
+func WithAbilityTimeout(can ucan.Ability, timeout time.Duration) Option {
+	return func(cfg *srvConfig) error {
+		if cfg.timeouts == nil {
+			cfg.timeouts = map[ucan.Ability]time.Duration{}
+		}
+		cfg.timeouts[can] = timeout
+		return nil
+	}
+}
+
+// WithMaxBlocks configures the maximum number of blocks in the agent message
+// of a request, see [server.WithMaxBlocks].
+func WithMaxBlocks(n int) Option {
+	return func(cfg *srvConfig) error {
+		cfg.maxBlocks = n
+		return nil
+	}
+}
+
+// WithMaxBlockSize configures the maximum size in bytes of a block in the agent
+// message of a request, see [server.WithMaxBlockSize].
+func WithMaxBlockSize(n uint64) Option {
+	return func(cfg *srvConfig) error {
+		cfg.maxBlockSize = n
+		return nil
+	}
+}
+
+// WithProofLimits configures limits on the depth and width of the proof chains
+// explored when authorizing an invocation, see [server.WithProofLimits].
+func WithProofLimits(limits validator.ProofLimits) Option {
+	return func(cfg *srvConfig) error {
+		cfg.proofLimits = limits
+		return nil
+	}
+}
+
+// WithReceiptSigner configures the server to sign receipts with an operational
+// signer whose authority is proven by the passed delegations, see
+// [server.WithReceiptSigner].
+func WithReceiptSigner(signer principal.Signer, proofs ...delegation.Delegation) Option {
+	return func(cfg *srvConfig) error {
+		if err := server.ValidateReceiptSigner(signer, proofs...); err != nil {
+			return err
+		}
+		cfg.receiptSigner = signer
+		cfg.receiptProofs = proofs
+		return nil
+	}
+}
+
+// WithReceiptStore configures a store that every receipt issued by the server
+// is persisted to, keyed by invocation CID. An error persisting a receipt
+// causes the server to fail the request, in the same way as an error returned
+// from a receipt logger. See [server.NewReceiptHandler] for serving stored receipts.
+func WithReceiptStore(store receipt.Store) Option {
+	return func(cfg *srvConfig) error {
+		cfg.receiptStore = store
+		return nil
+	}
+}
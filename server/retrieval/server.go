@@ -30,8 +30,13 @@ import (
 	hcmsg "github.com/storacha/go-ucanto/transport/headercar/message"
 	thttp "github.com/storacha/go-ucanto/transport/http"
 	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/storacha/go-ucanto/server/retrieval")
+
 type Request struct {
 	// Relative URL requested.
 	URL *url.URL
@@ -124,6 +129,21 @@ func NewServer(id principal.Signer, options ...Option) (*Server, error) {
 	if cfg.logReceipt != nil {
 		srvOpts = append(srvOpts, server.WithReceiptLogger(cfg.logReceipt))
 	}
+	if cfg.receiptStore != nil {
+		srvOpts = append(srvOpts, server.WithReceiptStore(cfg.receiptStore))
+	}
+	if len(cfg.rateLimits) > 0 {
+		srvOpts = append(srvOpts, server.WithRateLimits(cfg.rateLimitStore, cfg.rateLimits...))
+	}
+	if cfg.timeout > 0 {
+		srvOpts = append(srvOpts, server.WithTimeout(cfg.timeout))
+	}
+	for can, timeout := range cfg.timeouts {
+		srvOpts = append(srvOpts, server.WithAbilityTimeout(can, timeout))
+	}
+	if cfg.receiptSigner != nil {
+		srvOpts = append(srvOpts, server.WithReceiptSigner(cfg.receiptSigner, cfg.receiptProofs...))
+	}
 	if cfg.validateAuthorization != nil {
 		srvOpts = append(srvOpts, server.WithRevocationChecker(cfg.validateAuthorization))
 	}
@@ -139,15 +159,37 @@ func NewServer(id principal.Signer, options ...Option) (*Server, error) {
 	if len(cfg.authorityProofs) > 0 {
 		srvOpts = append(srvOpts, server.WithAuthorityProofs(cfg.authorityProofs...))
 	}
+	if cfg.maxBlocks > 0 {
+		srvOpts = append(srvOpts, server.WithMaxBlocks(cfg.maxBlocks))
+	}
+	if cfg.maxBlockSize > 0 {
+		srvOpts = append(srvOpts, server.WithMaxBlockSize(cfg.maxBlockSize))
+	}
+	if cfg.proofLimits != (validator.ProofLimits{}) {
+		srvOpts = append(srvOpts, server.WithProofLimits(cfg.proofLimits))
+	}
 
 	srv, err := server.NewServer(id, srvOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("creating server: %w", err)
 	}
 
+	interceptors := cfg.interceptors
+	if cfg.replayStore != nil {
+		interceptors = append([]server.Interceptor{server.ReplayProtection(cfg.replayStore, cfg.replayRetention)}, interceptors...)
+	}
+
+	service := cfg.service
+	if len(interceptors) > 0 {
+		service = make(Service, len(cfg.service))
+		for can, method := range cfg.service {
+			service[can] = Intercept(method, interceptors...)
+		}
+	}
+
 	return &Server{
 		server:          srv,
-		service:         cfg.service,
+		service:         service,
 		delegationCache: dlgCache,
 	}, nil
 }
@@ -198,11 +240,86 @@ func (srv *Server) LogReceipt(ctx context.Context, rcpt receipt.AnyReceipt, inv
 	return srv.server.LogReceipt(ctx, rcpt, inv)
 }
 
+// receiptStorer is implemented by servers that persist the receipts they
+// issue.
+type receiptStorer interface {
+	StoreReceipt(ctx context.Context, rcpt receipt.AnyReceipt) error
+}
+
+func (srv *Server) StoreReceipt(ctx context.Context, rcpt receipt.AnyReceipt) error {
+	if rs, ok := srv.server.(receiptStorer); ok {
+		return rs.StoreReceipt(ctx, rcpt)
+	}
+	return nil
+}
+
+// timeoutLimiter is implemented by servers that limit the time handlers may
+// take to execute an invocation.
+type timeoutLimiter interface {
+	Timeout(can ucan.Ability) time.Duration
+}
+
+func (srv *Server) Timeout(can ucan.Ability) time.Duration {
+	if tl, ok := srv.server.(timeoutLimiter); ok {
+		return tl.Timeout(can)
+	}
+	return 0
+}
+
+// receiptSigner is implemented by servers that sign receipts with an
+// operational signer instead of the service identity.
+type receiptSigner interface {
+	ReceiptSigner() (principal.Signer, delegation.Proofs)
+}
+
+func (srv *Server) ReceiptSigner() (principal.Signer, delegation.Proofs) {
+	if rs, ok := srv.server.(receiptSigner); ok {
+		return rs.ReceiptSigner()
+	}
+	return srv.server.ID(), nil
+}
+
+// messageLimiter is implemented by servers that bound the contents of the
+// agent messages they execute.
+type messageLimiter interface {
+	CheckMessage(msg message.AgentMessage) transport.HTTPError
+}
+
+func (srv *Server) CheckMessage(msg message.AgentMessage) transport.HTTPError {
+	if ml, ok := srv.server.(messageLimiter); ok {
+		return ml.CheckMessage(msg)
+	}
+	return nil
+}
+
+// proofLimiter is implemented by servers that bound the proof chains explored
+// when authorizing invocations.
+type proofLimiter interface {
+	ProofLimits() validator.ProofLimits
+}
+
+func (srv *Server) ProofLimits() validator.ProofLimits {
+	if pl, ok := srv.server.(proofLimiter); ok {
+		return pl.ProofLimits()
+	}
+	return validator.ProofLimits{}
+}
+
 var _ CachingServer = (*Server)(nil)
+var _ receiptStorer = (*Server)(nil)
+var _ timeoutLimiter = (*Server)(nil)
+var _ receiptSigner = (*Server)(nil)
+var _ messageLimiter = (*Server)(nil)
+var _ proofLimiter = (*Server)(nil)
 
 func Handle(ctx context.Context, srv CachingServer, request transport.HTTPRequest) (transport.HTTPResponse, error) {
+	ctx = server.ExtractTraceContext(ctx, request.Headers())
+	ctx, span := tracer.Start(ctx, "ucanto.retrieval.Handle", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
 	resp, err := handle(ctx, srv, request)
 	if err != nil {
+		server.RecordError(span, err)
 		return nil, err
 	}
 
@@ -216,6 +333,7 @@ func Handle(ctx context.Context, srv CachingServer, request transport.HTTPReques
 	// ensure the Vary header is set for ALL responses
 	// https://developer.mozilla.org/en-US/docs/Web/HTTP/Reference/Headers/Vary
 	headers.Add("Vary", hcmsg.HeaderName)
+	server.InjectTraceContext(ctx, headers)
 
 	if resp.Body() == nil {
 		return thttp.NewResponse(resp.Status(), http.NoBody, headers), nil
@@ -235,6 +353,12 @@ func handle(ctx context.Context, srv CachingServer, request transport.HTTPReques
 		return thttp.NewResponse(http.StatusBadRequest, io.NopCloser(strings.NewReader(msg)), nil), nil
 	}
 
+	if ml, ok := srv.(messageLimiter); ok {
+		if lerr := ml.CheckMessage(msg); lerr != nil {
+			return thttp.NewResponse(lerr.Status(), io.NopCloser(strings.NewReader(lerr.Error())), lerr.Headers()), nil
+		}
+	}
+
 	// retrieval server supports only 1 invocation in the agent message, since
 	// only a single handler can use the body.
 	invs := msg.Invocations()
@@ -242,7 +366,7 @@ func handle(ctx context.Context, srv CachingServer, request transport.HTTPReques
 		var rcpts []receipt.AnyReceipt
 		res := result.NewFailure(NewAgentMessageInvocationCountError())
 		for _, l := range invs {
-			rcpt, err := receipt.Issue(srv.ID(), res, ran.FromLink(l))
+			rcpt, err := server.IssueReceipt(srv, res, ran.FromLink(l))
 			if err != nil {
 				return nil, fmt.Errorf("issuing invocation error receipt: %w", err)
 			}
@@ -311,7 +435,7 @@ func Execute(ctx context.Context, srv CachingServer, msg message.AgentMessage, r
 		var rcpts []receipt.AnyReceipt
 		res := result.NewFailure(NewAgentMessageInvocationCountError())
 		for _, l := range invs {
-			rcpt, err := receipt.Issue(srv.ID(), res, ran.FromLink(l))
+			rcpt, err := server.IssueReceipt(srv, res, ran.FromLink(l))
 			if err != nil {
 				return nil, Response{}, err
 			}
@@ -427,34 +551,105 @@ func ExtractInvocation(ctx context.Context, root ipld.Link, msg message.AgentMes
 	return invocation.NewInvocationView(root, bs)
 }
 
+// outcome is the result of a handler of a retrieval service method.
+type outcome struct {
+	tx   transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure]
+	resp Response
+}
+
+// Close closes the body of the response, which is called if the handler
+// returns after it has timed out (see [server.ExecuteStreamingHandler]).
+func (o outcome) Close() error {
+	if o.resp.Body == nil {
+		return nil
+	}
+	return o.resp.Body.Close()
+}
+
+// releasingBody is a response body that releases the context of the handler
+// that returned it when it is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
 // Run is similar to [server.Run] except the receipts that are issued do not
 // include the invocation block(s) in order to save bytes when transmitting the
 // receipt in HTTP headers.
-func Run(ctx context.Context, srv server.Server[Service], invocation server.ServiceInvocation, req Request) (receipt.AnyReceipt, Response, error) {
+func Run(ctx context.Context, srv server.Server[Service], invocation server.ServiceInvocation, req Request) (rcpt receipt.AnyReceipt, resp Response, err error) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "ucanto.retrieval.Run", trace.WithAttributes(server.InvocationAttributes(invocation)...))
+	defer func() {
+		if rs, ok := srv.(receiptStorer); ok && err == nil {
+			if serr := rs.StoreReceipt(ctx, rcpt); serr != nil {
+				if resp.Body != nil {
+					resp.Body.Close()
+				}
+				rcpt, resp, err = nil, Response{}, fmt.Errorf("storing receipt: %w", serr)
+			}
+		}
+		if err != nil {
+			server.RecordError(span, err)
+		} else {
+			server.RecordReceipt(ctx, span, invocation, rcpt, start)
+		}
+		span.End()
+	}()
+
 	caps := invocation.Capabilities()
 	// Invocation needs to have one single capability
 	if len(caps) != 1 {
 		capErr := server.NewInvocationCapabilityError(invocation.Capabilities())
-		rcpt, err := receipt.Issue(srv.ID(), result.NewFailure(capErr), ran.FromLink(invocation.Link()))
+		rcpt, err := server.IssueReceipt(srv, result.NewFailure(capErr), ran.FromLink(invocation.Link()))
 		return rcpt, Response{}, err
 	}
 
 	cap := caps[0]
-	handle, ok := srv.Service()[cap.Can()]
+	handle, ok := server.ResolveMethod(srv.Service(), cap.Can())
 	if !ok {
 		notFoundErr := server.NewHandlerNotFoundError(cap)
-		rcpt, err := receipt.Issue(srv.ID(), result.NewFailure(notFoundErr), ran.FromLink(invocation.Link()))
+		rcpt, err := server.IssueReceipt(srv, result.NewFailure(notFoundErr), ran.FromLink(invocation.Link()))
 		return rcpt, Response{}, err
 	}
 
-	tx, resp, err := handle(ctx, invocation, srv.Context(), req)
+	if pl, ok := srv.(proofLimiter); ok {
+		if proofs := pl.ProofLimits(); proofs != (validator.ProofLimits{}) {
+			ctx = validator.WithProofLimits(ctx, proofs)
+		}
+	}
+
+	var timeout time.Duration
+	if tl, ok := srv.(timeoutLimiter); ok {
+		timeout = tl.Timeout(cap.Can())
+	}
+	// the body of a successful response may be streamed from the context of the
+	// handler, which is canceled when it is closed
+	out, release, err := server.ExecuteStreamingHandler(ctx, timeout, func(ctx context.Context) (outcome, error) {
+		tx, resp, err := handle(ctx, invocation, srv.Context(), req)
+		return outcome{tx, resp}, err
+	})
+	tx, resp := out.tx, out.resp
+	if resp.Body != nil {
+		resp.Body = releasingBody{resp.Body, release}
+	} else {
+		release()
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			return nil, Response{}, err
 		}
+		if errors.Is(err, server.ErrHandlerTimeout) {
+			rcpt, err := server.IssueReceipt(srv, result.NewFailure(server.NewTimeoutError(cap, timeout)), ran.FromLink(invocation.Link()))
+			return rcpt, Response{}, err
+		}
 		execErr := server.NewHandlerExecutionError(err, cap)
 		srv.Catch(execErr)
-		rcpt, err := receipt.Issue(srv.ID(), result.NewFailure(execErr), ran.FromLink(invocation.Link()))
+		rcpt, err := server.IssueReceipt(srv, result.NewFailure(execErr), ran.FromLink(invocation.Link()))
 		if err != nil {
 			return nil, Response{}, err
 		}
@@ -466,13 +661,18 @@ func Run(ctx context.Context, srv server.Server[Service], invocation server.Serv
 	if fx != nil {
 		opts = append(opts, receipt.WithJoin(fx.Join()), receipt.WithFork(fx.Fork()...))
 	}
+	if meta := tx.Meta(); len(meta) > 0 {
+		opts = append(opts, receipt.WithMeta(meta))
+	}
 
-	rcpt, err := receipt.Issue(srv.ID(), tx.Out(), ran.FromLink(invocation.Link()), opts...)
+	rcpt, err = server.IssueReceipt(srv, tx.Out(), ran.FromLink(invocation.Link()), opts...)
 	if err != nil {
+		outcome{tx, resp}.Close()
 		return nil, Response{}, err
 	}
 
 	if err := srv.LogReceipt(ctx, rcpt, invocation); err != nil {
+		outcome{tx, resp}.Close()
 		return nil, Response{}, err
 	}
 
@@ -0,0 +1,226 @@
+package retrieval
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	udm "github.com/storacha/go-ucanto/core/result/ok/datamodel"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/server"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	hcreq "github.com/storacha/go-ucanto/transport/headercar/request"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerIsolation(t *testing.T) {
+	testWait := validator.NewCapability(
+		"test/wait",
+		schema.DIDString(),
+		schema.Struct[ok.Unit](udm.UnitType(), nil),
+		nil,
+	)
+	inv := helpers.Must(testWait.Invoke(fixtures.Alice, fixtures.Service, fixtures.Alice.DID().String(), ok.Unit{}))
+
+	failureName := func(t *testing.T, x ipld.Node) string {
+		n := helpers.Must(x.LookupByString("name"))
+		return helpers.Must(n.AsString())
+	}
+
+	t.Run("recovers panic", func(t *testing.T) {
+		var caught []server.HandlerExecutionError[any]
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(
+				testWait.Can(),
+				Provide(testWait, func(ctx context.Context, cap ucan.Capability[ok.Unit], inv invocation.Invocation, ictx server.InvocationContext, req Request) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, Response, error) {
+					panic("boom")
+				}),
+			),
+			WithErrorHandler(func(err server.HandlerExecutionError[any]) {
+				caught = append(caught, err)
+			}),
+		))
+
+		rcpt, _, err := Run(t.Context(), srv, inv, Request{})
+		require.NoError(t, err)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "HandlerExecutionError", failureName(t, x))
+		require.Len(t, caught, 1)
+		require.Implements(t, (*server.HandlerPanic)(nil), caught[0].Cause())
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(
+				testWait.Can(),
+				Provide(testWait, func(ctx context.Context, cap ucan.Capability[ok.Unit], inv invocation.Invocation, ictx server.InvocationContext, req Request) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, Response, error) {
+					<-ctx.Done()
+					return nil, nil, Response{}, ctx.Err()
+				}),
+			),
+			WithAbilityTimeout(testWait.Can(), 10*time.Millisecond),
+		))
+
+		rcpt, resp, err := Run(t.Context(), srv, inv, Request{})
+		require.NoError(t, err)
+		require.Equal(t, Response{}, resp)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "Timeout", failureName(t, x))
+	})
+
+	t.Run("closes body returned after timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		body := &closeRecorder{closed: make(chan struct{})}
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(
+				testWait.Can(),
+				Provide(testWait, func(ctx context.Context, cap ucan.Capability[ok.Unit], inv invocation.Invocation, ictx server.InvocationContext, req Request) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, Response, error) {
+					// ignores context cancellation
+					<-release
+					return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, NewResponse(http.StatusOK, nil, body), nil
+				}),
+			),
+			WithAbilityTimeout(testWait.Can(), 10*time.Millisecond),
+		))
+
+		rcpt, resp, err := Run(t.Context(), srv, inv, Request{})
+		require.NoError(t, err)
+		require.Equal(t, Response{}, resp)
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "Timeout", failureName(t, x))
+
+		close(release)
+		select {
+		case <-body.closed:
+		case <-time.After(time.Second):
+			t.Fatal("body returned after timeout was not closed")
+		}
+	})
+
+	t.Run("streams body bound to handler context", func(t *testing.T) {
+		var hctx context.Context
+		srv := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(
+				testWait.Can(),
+				Provide(testWait, func(ctx context.Context, cap ucan.Capability[ok.Unit], inv invocation.Invocation, ictx server.InvocationContext, req Request) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, Response, error) {
+					hctx = ctx
+					body := &contextBody{ctx: ctx, Reader: strings.NewReader("hello world")}
+					return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, NewResponse(http.StatusOK, nil, body), nil
+				}),
+			),
+			WithAbilityTimeout(testWait.Can(), time.Second),
+		))
+
+		rcpt, resp, err := Run(t.Context(), srv, inv, Request{})
+		require.NoError(t, err)
+		_, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "hello world", string(data))
+
+		require.NoError(t, hctx.Err())
+		require.NoError(t, resp.Body.Close())
+		require.Error(t, hctx.Err())
+	})
+}
+
+// contextBody is a body that cannot be read once its context is canceled, like
+// the body of an HTTP request made with that context.
+type contextBody struct {
+	io.Reader
+	ctx context.Context
+}
+
+func (b *contextBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return b.Reader.Read(p)
+}
+
+func (b *contextBody) Close() error {
+	return nil
+}
+
+func TestLimits(t *testing.T) {
+	testWait := validator.NewCapability(
+		"test/wait",
+		schema.DIDString(),
+		schema.Struct[ok.Unit](udm.UnitType(), nil),
+		nil,
+	)
+	method := WithServiceMethod(
+		testWait.Can(),
+		Provide(testWait, func(ctx context.Context, cap ucan.Capability[ok.Unit], inv invocation.Invocation, ictx server.InvocationContext, req Request) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, Response, error) {
+			return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, Response{}, nil
+		}),
+	)
+
+	t.Run("blocks", func(t *testing.T) {
+		srv := helpers.Must(NewServer(fixtures.Service, method, WithMaxBlocks(1)))
+		inv := helpers.Must(testWait.Invoke(fixtures.Alice, fixtures.Service, fixtures.Alice.DID().String(), ok.Unit{}))
+		req := helpers.Must(hcreq.Encode(helpers.Must(message.Build([]invocation.Invocation{inv}, nil))))
+
+		res := helpers.Must(Handle(t.Context(), srv, req))
+		require.Equal(t, http.StatusRequestEntityTooLarge, res.Status())
+		body := helpers.Must(io.ReadAll(res.Body()))
+		require.Contains(t, string(body), "more than the maximum of 1 blocks")
+	})
+
+	t.Run("proof depth", func(t *testing.T) {
+		srv := helpers.Must(NewServer(fixtures.Service, method, WithProofLimits(validator.ProofLimits{MaxDepth: 1})))
+
+		alice2bob := helpers.Must(testWait.Delegate(fixtures.Alice, fixtures.Bob, fixtures.Alice.DID().String(), ok.Unit{}))
+		bob2mallory := helpers.Must(testWait.Delegate(fixtures.Bob, fixtures.Mallory, fixtures.Alice.DID().String(), ok.Unit{}, delegation.WithProof(delegation.FromDelegation(alice2bob))))
+
+		inv := helpers.Must(testWait.Invoke(fixtures.Bob, fixtures.Service, fixtures.Alice.DID().String(), ok.Unit{}, delegation.WithProof(delegation.FromDelegation(alice2bob))))
+		rcpt, _, err := Run(t.Context(), srv, inv, Request{})
+		require.NoError(t, err)
+		_, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+
+		inv = helpers.Must(testWait.Invoke(fixtures.Mallory, fixtures.Service, fixtures.Alice.DID().String(), ok.Unit{}, delegation.WithProof(delegation.FromDelegation(bob2mallory))))
+		rcpt, _, err = Run(t.Context(), srv, inv, Request{})
+		require.NoError(t, err)
+		_, x = result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		msg := helpers.Must(helpers.Must(x.LookupByString("message")).AsString())
+		require.Contains(t, msg, "exceed the maximum proof chain depth of 1")
+	})
+}
+
+// closeRecorder is a response body that records whether it has been closed.
+type closeRecorder struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (c *closeRecorder) Close() error {
+	close(c.closed)
+	return nil
+}
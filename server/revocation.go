@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/did"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+)
+
+// RevokeAbility is the ability used to revoke a delegation.
+const RevokeAbility = "ucan/revoke"
+
+// Revoke is the `ucan/revoke` capability. The resource is the DID of the
+// principal revoking the delegation, which must be an issuer in its proof
+// chain, and the caveats link to the delegation, which must be included in the
+// agent message along with its proofs.
+var Revoke = validator.NewCapability(
+	RevokeAbility,
+	schema.DIDString(),
+	schema.Struct[sdm.RevokeModel](sdm.RevokeType(), nil),
+	validator.DefaultDerives,
+)
+
+// Revocation records the revocation of a delegation.
+type Revocation struct {
+	// Delegation is the revoked delegation.
+	Delegation ucan.Link
+	// Revoker is the issuer in the proof chain of the delegation that revoked
+	// it.
+	Revoker did.DID
+	// Cause is the `ucan/revoke` invocation that revoked the delegation.
+	Cause ucan.Link
+}
+
+// RevocationStore records revoked delegations.
+type RevocationStore interface {
+	// Add records the passed revocation. Adding a revocation of a delegation
+	// that has already been revoked must not fail.
+	Add(ctx context.Context, revocation Revocation) error
+	// Get returns the revocation of the delegation identified by the passed
+	// link, or false if it has not been revoked.
+	Get(ctx context.Context, delegation ucan.Link) (Revocation, bool, error)
+}
+
+// MemoryRevocationStore is an in-memory [RevocationStore]. Revocations are
+// never discarded, so it is best suited to tests and services that issue few
+// delegations.
+type MemoryRevocationStore struct {
+	mutex       sync.RWMutex
+	revocations map[string]Revocation
+}
+
+// NewMemoryRevocationStore creates a new in-memory store of revoked
+// delegations.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revocations: map[string]Revocation{}}
+}
+
+func (m *MemoryRevocationStore) Add(ctx context.Context, revocation Revocation) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := revocation.Delegation.String()
+	if _, ok := m.revocations[key]; !ok {
+		m.revocations[key] = revocation
+	}
+	return nil
+}
+
+func (m *MemoryRevocationStore) Get(ctx context.Context, delegation ucan.Link) (Revocation, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	revocation, ok := m.revocations[delegation.String()]
+	return revocation, ok, nil
+}
+
+// RevocationChecker creates a function that checks authorizations for
+// revocation (see [WithRevocationChecker]). An authorization is revoked if any
+// delegation in it has been revoked, including the delegations of its proofs
+// and the attestations used to authorize non did:key issuers. Authorizations
+// that cannot be checked because the store fails are considered revoked, since
+// it cannot be established that they are not.
+func RevocationChecker(store RevocationStore) validator.RevocationCheckerFunc[any] {
+	return func(ctx context.Context, auth validator.Authorization[any]) validator.Revoked {
+		return checkRevoked(ctx, store, auth)
+	}
+}
+
+func checkRevoked(ctx context.Context, store RevocationStore, auth validator.Authorization[any]) validator.Revoked {
+	dlg := auth.Delegation()
+	if _, revoked, err := store.Get(ctx, dlg.Link()); err != nil || revoked {
+		return validator.NewRevokedError(dlg)
+	}
+	for _, p := range auth.Proofs() {
+		if revoked := checkRevoked(ctx, store, p); revoked != nil {
+			return revoked
+		}
+	}
+	for _, a := range auth.Attestations() {
+		if revoked := checkRevoked(ctx, store, a); revoked != nil {
+			return revoked
+		}
+	}
+	return nil
+}
+
+// revoke creates the service method for `ucan/revoke` invocations.
+//
+// The revocation is accepted if the delegation is included and the resource
+// of the invocation is the issuer of the delegation or of any delegation in its
+// proof chain. Proofs that are not included are resolved with the proof
+// resolver of the service.
+func revoke(store RevocationStore) ProvidedMethod[ok.Unit] {
+	return Provide(Revoke, func(ctx context.Context, cap ucan.Capability[sdm.RevokeModel], inv invocation.Invocation, ictx InvocationContext) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, error) {
+		dlglnk := cap.Nb().Ucan
+		invalid := func(format string, a ...any) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, error) {
+			return result.Error[ok.Unit, failure.IPLDBuilderFailure](NewInvalidRevocationError(dlglnk, fmt.Sprintf(format, a...))), nil, nil
+		}
+
+		br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(inv.Blocks()))
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, found, err := br.Get(dlglnk); err != nil {
+			return nil, nil, err
+		} else if !found {
+			return invalid("delegation not found in message")
+		}
+		dlg, err := delegation.NewDelegationView(dlglnk, br)
+		if err != nil {
+			return invalid("decoding delegation: %s", err.Error())
+		}
+
+		revoker, err := did.Parse(cap.With())
+		if err != nil {
+			return nil, nil, err
+		}
+		if !isChainIssuer(ctx, revoker, dlg, br, ictx) {
+			return invalid("%s is not an issuer in the proof chain", revoker)
+		}
+
+		if err := store.Add(ctx, Revocation{Delegation: dlglnk, Revoker: revoker, Cause: inv.Link()}); err != nil {
+			return nil, nil, err
+		}
+		return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, nil
+	})
+}
+
+// isChainIssuer reports whether the passed principal is the issuer of the
+// delegation or of any delegation in its proof chain. The chain is explored
+// within the proof limits of the context (see [WithProofLimits]) and each
+// delegation in it is explored once.
+func isChainIssuer(ctx context.Context, issuer did.DID, dlg delegation.Delegation, br blockstore.BlockReader, ictx InvocationContext) bool {
+	return exploreChain(ctx, issuer, dlg, br, ictx, validator.ProofLimitsFromContext(ctx), 1, map[string]struct{}{})
+}
+
+func exploreChain(ctx context.Context, issuer did.DID, dlg delegation.Delegation, br blockstore.BlockReader, ictx InvocationContext, limits validator.ProofLimits, depth int, visited map[string]struct{}) bool {
+	if dlg.Issuer().DID() == issuer {
+		return true
+	}
+	visited[dlg.Link().String()] = struct{}{}
+	if limits.MaxDepth > 0 && depth >= limits.MaxDepth {
+		return false
+	}
+	if limits.MaxWidth > 0 && len(dlg.Proofs()) > limits.MaxWidth {
+		return false
+	}
+	for _, p := range dlg.Proofs() {
+		if _, ok := visited[p.String()]; ok {
+			continue
+		}
+		prf, err := delegation.NewDelegationView(p, br)
+		if err != nil {
+			var uerr validator.UnavailableProof
+			prf, uerr = ictx.ResolveProof(ctx, p)
+			if uerr != nil {
+				visited[p.String()] = struct{}{}
+				continue
+			}
+		}
+		if exploreChain(ctx, issuer, prf, br, ictx, limits, depth+1, visited) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocation(t *testing.T) {
+	newServer := func(t *testing.T, store RevocationStore, options ...Option) ServerView[Service] {
+		return newUploadAddServer(t, nil, append([]Option{WithRevocation(store)}, options...)...)
+	}
+
+	execute := func(t *testing.T, server ServerView[Service], inv invocation.Invocation) result.Result[ipld.Node, ipld.Node] {
+		return executeInvocation(t, server, inv).Out()
+	}
+
+	delegate := func(t *testing.T, issuer ucan.Signer, audience ucan.Principal, proofs ...delegation.Delegation) delegation.Delegation {
+		var prfs []delegation.Proof
+		for _, p := range proofs {
+			prfs = append(prfs, delegation.FromDelegation(p))
+		}
+		return helpers.Must(delegation.Delegate(
+			issuer,
+			audience,
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/add", fixtures.Bob.DID().String(), ucan.NoCaveats{})},
+			delegation.WithProof(prfs...),
+		))
+	}
+
+	invoke := func(t *testing.T, issuer ucan.Signer, proof delegation.Delegation) invocation.Invocation {
+		return newUploadAddInvocation(t, issuer, fixtures.Bob.DID().String(), delegation.WithProof(delegation.FromDelegation(proof)))
+	}
+
+	t.Run("revokes delegation", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		server := newServer(t, store)
+		dlg := delegate(t, fixtures.Bob, fixtures.Alice)
+
+		_, x := result.Unwrap(execute(t, server, invoke(t, fixtures.Alice, dlg)))
+		require.Nil(t, x)
+
+		rev := helpers.Must(client.Revoke(fixtures.Bob, fixtures.Service, dlg))
+		_, x = result.Unwrap(execute(t, server, rev))
+		require.Nil(t, x)
+
+		revocation, ok, err := store.Get(t.Context(), dlg.Link())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, fixtures.Bob.DID(), revocation.Revoker)
+		require.Equal(t, rev.Link(), revocation.Cause)
+
+		_, x = result.Unwrap(execute(t, server, invoke(t, fixtures.Alice, dlg)))
+		require.NotNil(t, x)
+		f := asFailure(t, x)
+		require.Equal(t, "Unauthorized", *f.Name)
+		require.Contains(t, f.Message, "has been revoked")
+	})
+
+	t.Run("revokes delegation in proof chain", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		server := newServer(t, store)
+		alice := delegate(t, fixtures.Bob, fixtures.Alice)
+		mallory := delegate(t, fixtures.Alice, fixtures.Mallory, alice)
+
+		// Bob is the issuer of the proof of the delegation to Mallory
+		rev := helpers.Must(client.Revoke(fixtures.Bob, fixtures.Service, mallory))
+		_, x := result.Unwrap(execute(t, server, rev))
+		require.Nil(t, x)
+
+		_, x = result.Unwrap(execute(t, server, invoke(t, fixtures.Mallory, mallory)))
+		require.NotNil(t, x)
+		require.Equal(t, "Unauthorized", *asFailure(t, x).Name)
+
+		_, x = result.Unwrap(execute(t, server, invoke(t, fixtures.Alice, alice)))
+		require.Nil(t, x)
+	})
+
+	t.Run("revoking proof revokes delegations", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		server := newServer(t, store)
+		alice := delegate(t, fixtures.Bob, fixtures.Alice)
+		mallory := delegate(t, fixtures.Alice, fixtures.Mallory, alice)
+
+		rev := helpers.Must(client.Revoke(fixtures.Bob, fixtures.Service, alice))
+		_, x := result.Unwrap(execute(t, server, rev))
+		require.Nil(t, x)
+
+		_, x = result.Unwrap(execute(t, server, invoke(t, fixtures.Mallory, mallory)))
+		require.NotNil(t, x)
+		require.Equal(t, "Unauthorized", *asFailure(t, x).Name)
+	})
+
+	t.Run("revoker not in proof chain", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		server := newServer(t, store)
+		alice := delegate(t, fixtures.Bob, fixtures.Alice)
+		mallory := delegate(t, fixtures.Alice, fixtures.Mallory, alice)
+
+		// Mallory is the audience, not an issuer
+		rev := helpers.Must(client.Revoke(fixtures.Mallory, fixtures.Service, mallory))
+		_, x := result.Unwrap(execute(t, server, rev))
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidRevocation", *asFailure(t, x).Name)
+
+		_, ok, err := store.Get(t.Context(), mallory.Link())
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("proof chain beyond depth limit", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		server := newServer(t, store, WithProofLimits(validator.ProofLimits{MaxDepth: 1}))
+		alice := delegate(t, fixtures.Bob, fixtures.Alice)
+		mallory := delegate(t, fixtures.Alice, fixtures.Mallory, alice)
+
+		rev := helpers.Must(client.Revoke(fixtures.Bob, fixtures.Service, mallory))
+		_, x := result.Unwrap(execute(t, server, rev))
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidRevocation", *asFailure(t, x).Name)
+	})
+
+	t.Run("resolves each proof once", func(t *testing.T) {
+		resolved := 0
+		alice := delegate(t, fixtures.Bob, fixtures.Alice)
+		server := newServer(t, NewMemoryRevocationStore(), WithProofResolver(func(ctx context.Context, proof ucan.Link) (delegation.Delegation, validator.UnavailableProof) {
+			resolved++
+			if proof.String() != alice.Link().String() {
+				return nil, validator.NewUnavailableProofError(proof, nil)
+			}
+			return alice, nil
+		}))
+		mallory := helpers.Must(delegation.Delegate(
+			fixtures.Alice,
+			fixtures.Mallory,
+			[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/add", fixtures.Bob.DID().String(), ucan.NoCaveats{})},
+			delegation.WithProof(delegation.FromLink(alice.Link()), delegation.FromLink(alice.Link())),
+		))
+
+		rev := helpers.Must(client.Revoke(fixtures.Mallory, fixtures.Service, mallory))
+		_, x := result.Unwrap(execute(t, server, rev))
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidRevocation", *asFailure(t, x).Name)
+		require.Equal(t, 1, resolved)
+	})
+
+	t.Run("delegation not included", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		server := newServer(t, store)
+		dlg := delegate(t, fixtures.Bob, fixtures.Alice)
+
+		rev := helpers.Must(Revoke.Invoke(fixtures.Bob, fixtures.Service, fixtures.Bob.DID().String(), sdm.RevokeModel{Ucan: dlg.Link()}))
+		_, x := result.Unwrap(execute(t, server, rev))
+		require.NotNil(t, x)
+		require.Equal(t, "InvalidRevocation", *asFailure(t, x).Name)
+	})
+
+	t.Run("runs configured revocation checker", func(t *testing.T) {
+		checked := 0
+		server := newServer(t, NewMemoryRevocationStore(), WithRevocationChecker(func(ctx context.Context, auth validator.Authorization[any]) validator.Revoked {
+			checked++
+			return nil
+		}))
+		dlg := delegate(t, fixtures.Bob, fixtures.Alice)
+
+		_, x := result.Unwrap(execute(t, server, invoke(t, fixtures.Alice, dlg)))
+		require.Nil(t, x)
+		require.NotZero(t, checked)
+	})
+}
+
+type failingRevocationStore struct{}
+
+func (failingRevocationStore) Add(ctx context.Context, revocation Revocation) error {
+	return errors.New("boom")
+}
+
+func (failingRevocationStore) Get(ctx context.Context, delegation ucan.Link) (Revocation, bool, error) {
+	return Revocation{}, false, errors.New("boom")
+}
+
+func TestRevocationChecker(t *testing.T) {
+	authorize := func(t *testing.T, inv invocation.Invocation, checker validator.RevocationCheckerFunc[any]) (validator.Authorization[uploadAddCaveats], validator.Unauthorized) {
+		vctx := validator.NewValidationContext(
+			fixtures.Service.Verifier(),
+			uploadAdd,
+			validator.IsSelfIssued,
+			checker,
+			validator.ProofUnavailable,
+			ParsePrincipal,
+			validator.FailDIDKeyResolution,
+			validator.NotExpiredNotTooEarly,
+		)
+		return validator.Access(t.Context(), inv, vctx)
+	}
+
+	dlg := helpers.Must(delegation.Delegate(
+		fixtures.Bob,
+		fixtures.Alice,
+		[]ucan.Capability[ucan.NoCaveats]{ucan.NewCapability("upload/add", fixtures.Bob.DID().String(), ucan.NoCaveats{})},
+	))
+	inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Bob.DID().String(), delegation.WithProof(delegation.FromDelegation(dlg)))
+
+	t.Run("not revoked", func(t *testing.T) {
+		_, err := authorize(t, inv, RevocationChecker(NewMemoryRevocationStore()))
+		require.Nil(t, err)
+	})
+
+	t.Run("revoked invocation", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		require.NoError(t, store.Add(t.Context(), Revocation{Delegation: inv.Link(), Revoker: fixtures.Alice.DID(), Cause: helpers.RandomCID()}))
+		_, err := authorize(t, inv, RevocationChecker(store))
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "has been revoked")
+	})
+
+	t.Run("revoked proof", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		require.NoError(t, store.Add(t.Context(), Revocation{Delegation: dlg.Link(), Revoker: fixtures.Bob.DID(), Cause: helpers.RandomCID()}))
+		_, err := authorize(t, inv, RevocationChecker(store))
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), dlg.Link().String())
+	})
+
+	t.Run("store failure", func(t *testing.T) {
+		_, err := authorize(t, inv, RevocationChecker(failingRevocationStore{}))
+		require.NotNil(t, err)
+	})
+}
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/storacha/go-ucanto/core/dag/blockstore"
 	"github.com/storacha/go-ucanto/core/delegation"
@@ -22,12 +24,15 @@ import (
 	"github.com/storacha/go-ucanto/did"
 	"github.com/storacha/go-ucanto/principal"
 	"github.com/storacha/go-ucanto/principal/ed25519/verifier"
+	sdm "github.com/storacha/go-ucanto/server/datamodel"
 	"github.com/storacha/go-ucanto/server/transaction"
 	"github.com/storacha/go-ucanto/transport"
 	"github.com/storacha/go-ucanto/transport/car"
 	thttp "github.com/storacha/go-ucanto/transport/http"
 	"github.com/storacha/go-ucanto/ucan"
 	"github.com/storacha/go-ucanto/validator"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // InvocationContext is the context provided to service methods.
@@ -53,6 +58,18 @@ type ServiceMethod[O ipld.Builder, X failure.IPLDBuilderFailure] func(
 	InvocationContext,
 ) (transaction.Transaction[O, X], error)
 
+// Execute calls the service method.
+func (m ServiceMethod[O, X]) Execute(ctx context.Context, inv invocation.Invocation, ictx InvocationContext) (transaction.Transaction[O, X], error) {
+	return m(ctx, inv, ictx)
+}
+
+// Method is a handler of invocations that can be configured as a service
+// method, such as a [ServiceMethod] or the [ProvidedMethod] created by
+// [Provide].
+type Method[O ipld.Builder, X failure.IPLDBuilderFailure] interface {
+	Execute(ctx context.Context, invocation invocation.Invocation, context InvocationContext) (transaction.Transaction[O, X], error)
+}
+
 // Service is a mapping of service names to handlers, used to define a
 // service implementation.
 type Service = map[ucan.Ability]ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]
@@ -91,6 +108,10 @@ type ErrorHandlerFunc func(err HandlerExecutionError[any])
 // back to the client, use judiciously.
 type ReceiptLoggerFunc func(ctx context.Context, rcpt receipt.AnyReceipt, inv invocation.Invocation) error
 
+// DefaultMaxConcurrency is the maximum number of invocations from a single
+// agent message that are executed concurrently when not configured otherwise.
+const DefaultMaxConcurrency = 16
+
 func NewServer(id principal.Signer, options ...Option) (ServerView[Service], error) {
 	cfg := srvConfig{service: Service{}}
 	for _, opt := range options {
@@ -101,7 +122,7 @@ func NewServer(id principal.Signer, options ...Option) (ServerView[Service], err
 
 	codec := cfg.codec
 	if codec == nil {
-		codec = car.NewInboundCodec()
+		codec = car.NewInboundCodec(cfg.limits.decodeOptions()...)
 	}
 
 	canIssue := cfg.canIssue
@@ -122,6 +143,15 @@ func NewServer(id principal.Signer, options ...Option) (ServerView[Service], err
 			return nil
 		}
 	}
+	if cfg.revocationStore != nil {
+		checkRevocation, next := RevocationChecker(cfg.revocationStore), validateAuthorization
+		validateAuthorization = func(ctx context.Context, auth validator.Authorization[any]) validator.Revoked {
+			if revoked := checkRevocation(ctx, auth); revoked != nil {
+				return revoked
+			}
+			return next(ctx, auth)
+		}
+	}
 
 	resolveProof := cfg.resolveProof
 	if resolveProof == nil {
@@ -143,8 +173,139 @@ func NewServer(id principal.Signer, options ...Option) (ServerView[Service], err
 		validateTimeBounds = validator.NotExpiredNotTooEarly
 	}
 
-	ctx := serverContext{id, canIssue, validateAuthorization, resolveProof, parsePrincipal, resolveDIDKey, validateTimeBounds, cfg.authorityProofs, cfg.altAudiences}
-	svr := &server{id, cfg.service, ctx, codec, catch, cfg.logReceipt}
+	var svr *server
+	if cfg.introspection {
+		err := addServiceMethod(&cfg, IntrospectAbility, introspect(func() []AbilityDescription {
+			return Abilities(svr)
+		}))
+		if err != nil {
+			return nil, err
+		}
+		if cfg.descriptions == nil {
+			cfg.descriptions = map[ucan.Ability]AbilityDescription{}
+		}
+		cfg.descriptions[IntrospectAbility] = Describe(Introspect, sdm.IntrospectOkType(), nil)
+	}
+
+	// methods that authorize invocations in place of the service methods in dry
+	// runs, nil for methods that cannot be dry run
+	var authorizers Service
+	if cfg.dryRun {
+		err := addServiceMethod(&cfg, DryRunAbility, dryRun(func(inv invocation.Invocation, can ucan.Ability) (ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure], bool) {
+			if _, ok := svr.route(inv, can); ok || can == DryRunAbility {
+				return nil, false
+			}
+			method, ok := ResolveMethod(authorizers, can)
+			return method, ok && method != nil
+		}))
+		if err != nil {
+			return nil, err
+		}
+		if cfg.descriptions == nil {
+			cfg.descriptions = map[ucan.Ability]AbilityDescription{}
+		}
+		cfg.descriptions[DryRunAbility] = Describe(DryRun, sdm.DryRunOkType(), nil)
+	}
+
+	// abilities provided by methods created by Provide are described by their
+	// capability, unless they are configured with a description
+	for can, p := range cfg.providers {
+		if _, ok := cfg.descriptions[can]; ok || isFallback(can) {
+			continue
+		}
+		if cfg.descriptions == nil {
+			cfg.descriptions = map[ucan.Ability]AbilityDescription{}
+		}
+		desc := p.Description()
+		desc.Can = can
+		cfg.descriptions[can] = desc
+	}
+
+	interceptors := cfg.interceptors
+	if cfg.replayStore != nil {
+		interceptors = append([]Interceptor{ReplayProtection(cfg.replayStore, cfg.replayRetention)}, interceptors...)
+	}
+
+	service := cfg.service
+	if len(interceptors) > 0 {
+		service = make(Service, len(cfg.service))
+		for can, method := range cfg.service {
+			service[can] = Intercept(method, interceptors...)
+		}
+	}
+
+	if cfg.dryRun {
+		authorizers = make(Service, len(cfg.service))
+		for can := range cfg.service {
+			authorizers[can] = nil
+			if p, ok := cfg.providers[can]; ok {
+				authorizers[can] = Intercept(authorizeMethod(p), interceptors...)
+			}
+		}
+	}
+
+	tasks := service
+	if len(cfg.taskExecutors) > 0 {
+		tasks = maps.Clone(service)
+		for can, method := range cfg.taskExecutors {
+			tasks[can] = Intercept(method, interceptors...)
+		}
+	}
+
+	maxConcurrency := cfg.maxConcurrency
+	if maxConcurrency < 1 {
+		maxConcurrency = DefaultMaxConcurrency
+	}
+
+	var limits *rateLimits
+	if len(cfg.rateLimits) > 0 {
+		store := cfg.rateLimitStore
+		if store == nil {
+			ms, err := NewMemoryRateLimitStore(MemoryRateLimitStoreSize)
+			if err != nil {
+				return nil, err
+			}
+			store = ms
+		}
+		limits = &rateLimits{limits: cfg.rateLimits, store: store}
+	}
+
+	receiptSigner := cfg.receiptSigner
+	var receiptProofs delegation.Proofs
+	if receiptSigner == nil {
+		if _, ok := id.(verifierIdentity); ok {
+			return nil, fmt.Errorf("service identity %s cannot sign receipts, a receipt signer must be configured", id.DID())
+		}
+		receiptSigner = id
+	} else {
+		for _, p := range cfg.receiptProofs {
+			receiptProofs = append(receiptProofs, delegation.FromDelegation(p))
+		}
+	}
+
+	ctx := serverContext{id, canIssue, validateAuthorization, resolveProof, parsePrincipal, resolveDIDKey, validateTimeBounds, cfg.authorityProofs, cfg.altAudiences, limits, cfg.verificationCache, receiptSigner, receiptProofs}
+	svr = &server{
+		id:             id,
+		service:        service,
+		context:        ctx,
+		codec:          codec,
+		catch:          catch,
+		logReceipt:     cfg.logReceipt,
+		receipts:       cfg.receiptStore,
+		maxConcurrency: maxConcurrency,
+		scheduler:      cfg.taskScheduler,
+		tasks:          tasks,
+		running:        map[string]struct{}{},
+		descriptions:   cfg.descriptions,
+		timeout:        cfg.timeout,
+		timeouts:       cfg.timeouts,
+		proxyRoutes:    cfg.proxyRoutes,
+		intercept:      interceptors,
+		receiptSigner:  receiptSigner,
+		receiptProofs:  receiptProofs,
+		lims:           cfg.limits,
+		statuses:       cfg.statusPolicy,
+	}
 	return svr, nil
 }
 
@@ -163,12 +324,24 @@ type serverContext struct {
 	validateTimeBounds    validator.TimeBoundsValidatorFunc
 	authorityProofs       []delegation.Delegation
 	altAudiences          []ucan.Principal
+	// limits are the rate limits applied to authorized invocations, nil if none
+	limits *rateLimits
+	// verificationCache caches verifications of delegations, nil if none
+	verificationCache validator.VerificationCache
+	// receiptSigner signs receipts and tasks, it is the service identity unless
+	// configured
+	receiptSigner principal.Signer
+	receiptProofs delegation.Proofs
 }
 
 func (ctx serverContext) ID() principal.Signer {
 	return ctx.id
 }
 
+func (ctx serverContext) ReceiptSigner() (principal.Signer, delegation.Proofs) {
+	return ctx.receiptSigner, ctx.receiptProofs
+}
+
 func (sctx serverContext) CanIssue(capability ucan.Capability[any], issuer did.DID) bool {
 	return sctx.canIssue(capability, issuer)
 }
@@ -201,6 +374,17 @@ func (sctx serverContext) AlternativeAudiences() []ucan.Principal {
 	return sctx.altAudiences
 }
 
+func (sctx serverContext) VerificationCache() validator.VerificationCache {
+	return sctx.verificationCache
+}
+
+func (sctx serverContext) takeToken(ctx context.Context, issuer did.DID, capability ucan.Capability[any]) (time.Duration, error) {
+	if sctx.limits == nil {
+		return 0, nil
+	}
+	return sctx.limits.takeToken(ctx, issuer, capability)
+}
+
 type server struct {
 	id         principal.Signer
 	service    Service
@@ -208,6 +392,37 @@ type server struct {
 	codec      transport.InboundCodec
 	catch      ErrorHandlerFunc
 	logReceipt ReceiptLoggerFunc
+	receipts   receipt.Store
+	// maxConcurrency is the maximum number of invocations executed concurrently
+	maxConcurrency int
+	// scheduler schedules execution of effects, nil if disabled
+	scheduler TaskScheduler
+	// tasks is the service used to execute effects, it includes task executors
+	tasks Service
+	// running are the tasks being executed, by invocation CID
+	running      map[string]struct{}
+	runningMutex sync.Mutex
+	// descriptions are the descriptions of service abilities, by ability
+	descriptions map[ucan.Ability]AbilityDescription
+	// timeout is the default handler timeout, zero if none
+	timeout time.Duration
+	// timeouts are handler timeouts by ability, overriding the default
+	timeouts map[ucan.Ability]time.Duration
+	// proxyRoutes are the routes of invocations forwarded to upstream services
+	proxyRoutes []ProxyRoute
+	// intercept are the interceptors applied to service methods, which also run
+	// before invocations are forwarded
+	intercept []Interceptor
+	// receiptSigner signs receipts, it is the service identity unless configured
+	receiptSigner principal.Signer
+	// receiptProofs prove the authority of the receipt signer, nil if it is the
+	// service identity
+	receiptProofs delegation.Proofs
+	// lims bound the resources a request may consume
+	lims limits
+	// statuses maps the outcomes of invocations to the HTTP status of the
+	// response, nil if responses always have a 200 status
+	statuses StatusPolicy
 }
 
 func (srv *server) ID() principal.Signer {
@@ -246,94 +461,309 @@ func (srv *server) LogReceipt(ctx context.Context, rcpt receipt.AnyReceipt, inv
 	return srv.logReceipt(ctx, rcpt, inv)
 }
 
+// receiptStorer is implemented by servers that persist the receipts they
+// issue.
+type receiptStorer interface {
+	// StoreReceipt persists an issued receipt so that it can be retrieved later
+	// by invocation CID.
+	StoreReceipt(ctx context.Context, rcpt receipt.AnyReceipt) error
+}
+
+func (srv *server) StoreReceipt(ctx context.Context, rcpt receipt.AnyReceipt) error {
+	if srv.receipts == nil {
+		return nil
+	}
+
+	return srv.receipts.Put(ctx, rcpt)
+}
+
+// concurrencyLimiter is implemented by servers that bound the number of
+// invocations from a single agent message that are executed concurrently.
+type concurrencyLimiter interface {
+	// MaxConcurrency is the maximum number of invocations from a single agent
+	// message that will be executed concurrently.
+	MaxConcurrency() int
+}
+
+func (srv *server) MaxConcurrency() int {
+	return srv.maxConcurrency
+}
+
+// timeoutLimiter is implemented by servers that limit the time handlers may
+// take to execute an invocation.
+type timeoutLimiter interface {
+	// Timeout is the maximum time the handler for the passed ability may take
+	// to execute an invocation. Zero means no limit.
+	Timeout(can ucan.Ability) time.Duration
+}
+
+// handlerTimeout returns the timeout of the handler for the passed ability,
+// zero if the server does not limit it.
+func handlerTimeout(server Server[Service], can ucan.Ability) time.Duration {
+	if tl, ok := server.(timeoutLimiter); ok {
+		return tl.Timeout(can)
+	}
+	return 0
+}
+
+func (srv *server) Timeout(can ucan.Ability) time.Duration {
+	if timeout, ok := srv.timeouts[can]; ok {
+		return timeout
+	}
+	return srv.timeout
+}
+
+func (srv *server) ReceiptSigner() (principal.Signer, delegation.Proofs) {
+	return srv.receiptSigner, srv.receiptProofs
+}
+
 var _ transport.Channel = (*server)(nil)
+var _ receiptSigner = (*server)(nil)
+var _ concurrencyLimiter = (*server)(nil)
+var _ receiptStorer = (*server)(nil)
+var _ timeoutLimiter = (*server)(nil)
 var _ ServerView[Service] = (*server)(nil)
 
 func Handle(ctx context.Context, server Server[Service], request transport.HTTPRequest) (transport.HTTPResponse, error) {
+	start := time.Now()
+	ctx = ExtractTraceContext(ctx, request.Headers())
+	ctx, span := tracer.Start(ctx, "ucanto.server.Handle", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	defer func() {
+		requestDuration.Record(ctx, time.Since(start).Seconds())
+	}()
+
 	selection, aerr := server.Codec().Accept(request)
 	if aerr != nil {
+		RecordError(span, aerr)
 		return thttp.NewResponse(aerr.Status(), io.NopCloser(strings.NewReader(aerr.Error())), aerr.Headers()), nil
 	}
 
+	var lims limits
+	if l, ok := server.(limiter); ok {
+		lims = l.limits()
+	}
+	request, body, lerr := limitRequest(request, lims)
+	if lerr != nil {
+		RecordError(span, lerr)
+		return thttp.NewResponse(lerr.Status(), io.NopCloser(strings.NewReader(lerr.Error())), lerr.Headers()), nil
+	}
+
+	_, dspan := tracer.Start(ctx, "ucanto.server.Decode")
 	msg, err := selection.Decoder().Decode(request)
 	if err != nil {
+		RecordError(dspan, err)
+		dspan.End()
+		RecordError(span, err)
+		if lerr := decodeError(err, body, lims); lerr != nil {
+			return thttp.NewResponse(lerr.Status(), io.NopCloser(strings.NewReader(lerr.Error())), lerr.Headers()), nil
+		}
 		return thttp.NewResponse(http.StatusBadRequest, io.NopCloser(strings.NewReader("The server failed to decode the request payload. Please format the payload according to the specified media type.")), nil), nil
 	}
+	dspan.End()
 
-	result, err := Execute(ctx, server, msg)
+	if lerr := checkMessage(msg, lims); lerr != nil {
+		RecordError(span, lerr)
+		return thttp.NewResponse(lerr.Status(), io.NopCloser(strings.NewReader(lerr.Error())), lerr.Headers()), nil
+	}
+
+	result, invs, rcpts, err := execute(ctx, server, msg)
 	if err != nil {
+		RecordError(span, err)
 		return nil, err
 	}
 
-	return selection.Encoder().Encode(result)
+	_, espan := tracer.Start(ctx, "ucanto.server.Encode")
+	defer espan.End()
+	resp, err := selection.Encoder().Encode(result)
+	if err != nil {
+		RecordError(espan, err)
+		RecordError(span, err)
+		return nil, err
+	}
+	if sp, ok := server.(statusPolicer); ok {
+		if policy := sp.statusPolicy(); policy != nil {
+			resp = applyStatusPolicy(resp, policy, invs, rcpts)
+		}
+	}
+	InjectTraceContext(ctx, resp.Headers())
+	return resp, nil
 }
 
+// Execute runs the invocations in the passed message and returns a message
+// with their receipts. If the server handles `ucan/conclude` (see
+// [WithConclusionHandler]) receipts reported in the message are concluded, and
+// the returned message includes a receipt acknowledging each conclusion.
 func Execute(ctx context.Context, server Server[Service], msg message.AgentMessage) (message.AgentMessage, error) {
+	out, _, _, err := execute(ctx, server, msg)
+	return out, err
+}
+
+// execute runs the invocations in the passed message and returns a message
+// with their receipts, along with the invocations and receipts.
+func execute(ctx context.Context, server Server[Service], msg message.AgentMessage) (message.AgentMessage, []invocation.Invocation, []receipt.AnyReceipt, error) {
+	ctx, span := tracer.Start(ctx, "ucanto.server.Execute", trace.WithAttributes(
+		attribute.Int("ucanto.invocations", len(msg.Invocations())),
+	))
+	defer span.End()
+
 	br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(msg.Blocks()))
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	var invs []invocation.Invocation
 	for _, invlnk := range msg.Invocations() {
 		inv, err := invocation.NewInvocationView(invlnk, br)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 		invs = append(invs, inv)
 	}
 
-	var rcpts []receipt.AnyReceipt
-	var rerr error
+	conclusions, err := concludeReported(server, msg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	invs = append(invs, conclusions...)
+
+	rcpts, err := executeAll(ctx, server, invs)
+	if err != nil {
+		RecordError(span, err)
+		return nil, nil, nil, err
+	}
+
+	out, err := message.Build(nil, rcpts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return out, invs, rcpts, nil
+}
+
+// executeAll runs the passed invocations with at most the maximum concurrency
+// of the server running at any one time. Receipts are returned in the same order as the
+// invocations. If any invocation fails with a (non-result) error, invocations
+// that are still running are canceled, those that have not yet started are
+// skipped and the error is returned.
+func executeAll(ctx context.Context, server Server[Service], invs []invocation.Invocation) ([]receipt.AnyReceipt, error) {
+	limit := DefaultMaxConcurrency
+	if cl, ok := server.(concurrencyLimiter); ok {
+		limit = cl.MaxConcurrency()
+	}
+	if limit < 1 {
+		limit = DefaultMaxConcurrency
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	rcpts := make([]receipt.AnyReceipt, len(invs))
+	errs := make([]error, len(invs))
+	sem := make(chan struct{}, limit)
 	var wg sync.WaitGroup
-	var lock sync.RWMutex
-	for _, inv := range invs {
+
+dispatch:
+	for i, inv := range invs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		// do not start new work once a sibling has failed
+		if ctx.Err() != nil {
+			<-sem
+			break dispatch
+		}
 		wg.Add(1)
-		go func(inv invocation.Invocation) {
+		go func(i int, inv invocation.Invocation) {
 			defer wg.Done()
+			defer func() { <-sem }()
 			rcpt, err := Run(ctx, server, inv)
 			if err != nil {
-				rerr = err
+				errs[i] = err
+				cancel(err)
 				return
 			}
-
-			lock.Lock()
-			rcpts = append(rcpts, rcpt)
-			lock.Unlock()
-		}(inv)
+			rcpts[i] = rcpt
+		}(i, inv)
 	}
 	wg.Wait()
 
-	if rerr != nil {
-		return nil, rerr
+	// Errors caused by cancelation are consequences of the first failure (or
+	// of the parent context being canceled) so only report them via the cause.
+	var failures []error
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) > 0 {
+		return nil, errors.Join(failures...)
+	}
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
 	}
 
-	return message.Build(nil, rcpts)
+	return rcpts, nil
 }
 
-func Run(ctx context.Context, server Server[Service], invocation ServiceInvocation) (receipt.AnyReceipt, error) {
-	caps := invocation.Capabilities()
-	// Invocation needs to have one single capability
-	if len(caps) != 1 {
+func Run(ctx context.Context, server Server[Service], invocation ServiceInvocation) (rcpt receipt.AnyReceipt, err error) {
+	server = hostFor(server, invocation)
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "ucanto.server.Run", trace.WithAttributes(InvocationAttributes(invocation)...))
+	defer func() {
+		if rs, ok := server.(receiptStorer); ok && err == nil {
+			if serr := rs.StoreReceipt(ctx, rcpt); serr != nil {
+				rcpt, err = nil, fmt.Errorf("storing receipt: %w", serr)
+			}
+		}
+		if err != nil {
+			RecordError(span, err)
+		} else {
+			RecordReceipt(ctx, span, invocation, rcpt, start)
+		}
+		span.End()
+	}()
+
+	// Invocation needs to have one single capability, or a group of them
+	cap, ok := invokedCapability(invocation.Capabilities())
+	if !ok {
 		err := NewInvocationCapabilityError(invocation.Capabilities())
-		return receipt.Issue(server.ID(), result.NewFailure(err), ran.FromInvocation(invocation))
+		return IssueReceipt(server, result.NewFailure(err), ran.FromInvocation(invocation))
 	}
 
-	cap := caps[0]
-	handle, ok := server.Service()[cap.Can()]
+	if p, ok := server.(proxy); ok {
+		if route, ok := p.route(invocation, cap.Can()); ok {
+			return forward(ctx, server, p, route, cap, invocation)
+		}
+	}
+
+	handle, ok := ResolveMethod(server.Service(), cap.Can())
 	if !ok {
 		err := NewHandlerNotFoundError(cap)
-		return receipt.Issue(server.ID(), result.NewFailure(err), ran.FromInvocation(invocation))
+		return IssueReceipt(server, result.NewFailure(err), ran.FromInvocation(invocation))
 	}
 
-	tx, err := handle(ctx, invocation, server.Context())
+	if pl, ok := server.(proofLimiter); ok {
+		if proofs := pl.ProofLimits(); proofs != (validator.ProofLimits{}) {
+			ctx = validator.WithProofLimits(ctx, proofs)
+		}
+	}
+
+	timeout := handlerTimeout(server, cap.Can())
+	tx, err := ExecuteHandler(ctx, timeout, func(ctx context.Context) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+		return handle(ctx, invocation, server.Context())
+	})
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			return nil, err
 		}
+		if errors.Is(err, ErrHandlerTimeout) {
+			return IssueReceipt(server, result.NewFailure(NewTimeoutError(cap, timeout)), ran.FromInvocation(invocation))
+		}
 		herr := NewHandlerExecutionError(err, cap)
 		server.Catch(herr)
-		return receipt.Issue(server.ID(), result.NewFailure(herr), ran.FromInvocation(invocation))
+		return IssueReceipt(server, result.NewFailure(herr), ran.FromInvocation(invocation))
 	}
 
 	fx := tx.Fx()
@@ -341,19 +771,49 @@ func Run(ctx context.Context, server Server[Service], invocation ServiceInvocati
 	if fx != nil {
 		opts = append(opts, receipt.WithJoin(fx.Join()), receipt.WithFork(fx.Fork()...))
 	}
+	if meta := tx.Meta(); len(meta) > 0 {
+		opts = append(opts, receipt.WithMeta(meta))
+	}
 
-	rcpt, err := receipt.Issue(server.ID(), tx.Out(), ran.FromInvocation(invocation), opts...)
+	rcpt, err = IssueReceipt(server, tx.Out(), ran.FromInvocation(invocation), opts...)
 	if err != nil {
 		herr := NewHandlerExecutionError(err, cap)
 		server.Catch(herr)
-		return receipt.Issue(server.ID(), result.NewFailure(herr), ran.FromInvocation(invocation))
+		return IssueReceipt(server, result.NewFailure(herr), ran.FromInvocation(invocation))
 	}
 
 	if err := server.LogReceipt(ctx, rcpt, invocation); err != nil {
 		herr := NewHandlerExecutionError(err, cap)
 		server.Catch(herr)
-		return receipt.Issue(server.ID(), result.NewFailure(herr), ran.FromInvocation(invocation))
+		return IssueReceipt(server, result.NewFailure(herr), ran.FromInvocation(invocation))
+	}
+
+	if tr, ok := server.(taskRunner); ok {
+		tr.scheduleTasks(ctx, rcpt)
 	}
 
 	return rcpt, nil
 }
+
+// invokedCapability returns the capability that selects the service method
+// handling an invocation of the passed capabilities. An invocation of several
+// capabilities on the same resource, such as one created by a parser created
+// with validator.And, is of a group whose ability is that of its capabilities
+// (see validator.GroupAbility), regardless of their order. It returns false if
+// there are no capabilities, or several on different resources.
+func invokedCapability(caps []ucan.Capability[any]) (ucan.Capability[any], bool) {
+	if len(caps) == 0 {
+		return nil, false
+	}
+	if len(caps) == 1 {
+		return caps[0], true
+	}
+	abilities := make([]ucan.Ability, 0, len(caps))
+	for _, c := range caps {
+		if c.With() != caps[0].With() {
+			return nil, false
+		}
+		abilities = append(abilities, c.Can())
+	}
+	return ucan.NewCapability[any](validator.GroupAbility(abilities...), caps[0].With(), validator.Group(caps)), true
+}
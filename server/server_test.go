@@ -3,9 +3,15 @@ package server
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	ipldprime "github.com/ipld/go-ipld-prime"
@@ -17,11 +23,14 @@ import (
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/core/invocation"
 	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/message"
 	"github.com/storacha/go-ucanto/core/receipt"
 	"github.com/storacha/go-ucanto/core/receipt/fx"
 	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
 	fdm "github.com/storacha/go-ucanto/core/result/failure/datamodel"
 	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/server/transaction"
 	"github.com/storacha/go-ucanto/testing/fixtures"
 	"github.com/storacha/go-ucanto/testing/helpers"
 	"github.com/storacha/go-ucanto/transport/car/request"
@@ -435,6 +444,214 @@ func TestExecute(t *testing.T) {
 	})
 }
 
+func TestExecuteBatch(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+
+	newInvocations := func(t *testing.T, n int) []invocation.Invocation {
+		var invs []invocation.Invocation
+		for range n {
+			cap := uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})
+			invs = append(invs, helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, cap)))
+		}
+		return invs
+	}
+
+	t.Run("receipts in invocation order", func(t *testing.T) {
+		server := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(
+				uploadadd.Can(),
+				Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+					// finish in a different order to the one invocations were received
+					time.Sleep(time.Duration(rand.IntN(10)) * time.Millisecond)
+					return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+				}),
+			),
+		))
+
+		invs := newInvocations(t, 20)
+		msg := helpers.Must(message.Build(invs, nil))
+		out, err := Execute(t.Context(), server, msg)
+		require.NoError(t, err)
+
+		rcpts := out.Receipts()
+		require.Len(t, rcpts, len(invs))
+		for i, inv := range invs {
+			rcptlnk, ok := out.Get(inv.Link())
+			require.True(t, ok)
+			require.Equal(t, rcptlnk, rcpts[i])
+		}
+	})
+
+	t.Run("concurrency limit", func(t *testing.T) {
+		var running, peak atomic.Int64
+		server := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(
+				uploadadd.Can(),
+				Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+					n := running.Add(1)
+					defer running.Add(-1)
+					for {
+						p := peak.Load()
+						if n <= p || peak.CompareAndSwap(p, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+				}),
+			),
+			WithMaxConcurrency(3),
+		))
+		require.Equal(t, 3, server.(concurrencyLimiter).MaxConcurrency())
+
+		invs := newInvocations(t, 12)
+		msg := helpers.Must(message.Build(invs, nil))
+		out, err := Execute(t.Context(), server, msg)
+		require.NoError(t, err)
+		require.Len(t, out.Receipts(), len(invs))
+		require.LessOrEqual(t, peak.Load(), int64(3))
+	})
+
+	t.Run("default concurrency limit", func(t *testing.T) {
+		server := helpers.Must(NewServer(fixtures.Service))
+		require.Equal(t, DefaultMaxConcurrency, server.(concurrencyLimiter).MaxConcurrency())
+	})
+
+	t.Run("fatal error cancels siblings", func(t *testing.T) {
+		fatal := errors.New("fatal")
+		var canceled atomic.Int64
+		invs := newInvocations(t, 4)
+		server := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(
+				uploadadd.Can(),
+				Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+					if inv.Link().String() == invs[0].Link().String() {
+						return nil, nil, fmt.Errorf("%w: %w", fatal, context.Canceled)
+					}
+					select {
+					case <-ctx.Done():
+						canceled.Add(1)
+						return nil, nil, ctx.Err()
+					case <-time.After(5 * time.Second):
+						return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+					}
+				}),
+			),
+		))
+
+		msg := helpers.Must(message.Build(invs, nil))
+		_, err := Execute(t.Context(), server, msg)
+		require.ErrorIs(t, err, fatal)
+		require.Equal(t, int64(len(invs)-1), canceled.Load())
+	})
+}
+
+func TestInterceptor(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+
+	type ctxKey struct{}
+
+	uploadAddMethod := WithServiceMethod(
+		uploadadd.Can(),
+		Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+			status, _ := ctx.Value(ctxKey{}).(string)
+			return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: status}), nil, nil
+		}),
+	)
+
+	rt := helpers.RandomCID()
+	cap := uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: rt})
+
+	t.Run("order", func(t *testing.T) {
+		var calls []string
+		named := func(name string) Interceptor {
+			return func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext, next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+				calls = append(calls, "before "+name)
+				tx, err := next(ctx, inv, ictx)
+				calls = append(calls, "after "+name)
+				return tx, err
+			}
+		}
+
+		server := helpers.Must(NewServer(
+			fixtures.Service,
+			WithInterceptor(named("a"), named("b")),
+			uploadAddMethod,
+			WithInterceptor(named("c")),
+		))
+
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, cap))
+		_, err := server.Run(t.Context(), inv)
+		require.NoError(t, err)
+		require.Equal(t, []string{"before a", "before b", "before c", "after c", "after b", "after a"}, calls)
+	})
+
+	t.Run("request scoped values and meta", func(t *testing.T) {
+		server := helpers.Must(NewServer(
+			fixtures.Service,
+			uploadAddMethod,
+			WithInterceptor(func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext, next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+				tx, err := next(context.WithValue(ctx, ctxKey{}, "intercepted"), inv, ictx)
+				if err != nil {
+					return nil, err
+				}
+				audited := true
+				return transaction.NewTransaction(
+					tx.Out(),
+					transaction.WithEffects(tx.Fx()),
+					transaction.WithMeta(map[string]any{"audited": &audited}),
+				), nil
+			}),
+		))
+
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, cap))
+		rcpt, err := server.Run(t.Context(), inv)
+		require.NoError(t, err)
+
+		audited, ok := rcpt.Meta()["audited"].(ipld.Node)
+		require.True(t, ok)
+		require.True(t, helpers.Must(audited.AsBool()))
+
+		reader := helpers.Must(receipt.NewReceiptReader[uploadAddSuccess, ipld.Node](rcptsch))
+		typed := helpers.Must(reader.Read(rcpt.Root().Link(), rcpt.Blocks()))
+		o, x := result.Unwrap(typed.Out())
+		require.Nil(t, x)
+		require.Equal(t, "intercepted", o.Status)
+	})
+
+	t.Run("short-circuit", func(t *testing.T) {
+		server := helpers.Must(NewServer(
+			fixtures.Service,
+			uploadAddMethod,
+			WithInterceptor(func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext, next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+				x := uploadAddFailure{name: "QuotaExceeded", message: "no more uploads for you"}
+				return transaction.NewTransaction(result.Error[ipld.Builder, failure.IPLDBuilderFailure](x)), nil
+			}),
+		))
+
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, cap))
+		rcpt, err := server.Run(t.Context(), inv)
+		require.NoError(t, err)
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "QuotaExceeded", *asFailure(t, x).Name)
+	})
+}
+
 func TestHandle(t *testing.T) {
 	t.Run("content type error", func(t *testing.T) {
 		server := helpers.Must(NewServer(fixtures.Service))
@@ -473,3 +690,138 @@ func TestHandle(t *testing.T) {
 		require.Equal(t, res.Status(), http.StatusBadRequest)
 	})
 }
+
+func TestHTTPHandler(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+
+	server := helpers.Must(NewServer(
+		fixtures.Service,
+		WithServiceMethod(
+			uploadadd.Can(),
+			Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+				return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+			}),
+		),
+	))
+
+	httpServer := httptest.NewServer(NewHTTPHandler(server))
+	t.Cleanup(httpServer.Close)
+
+	t.Run("execute", func(t *testing.T) {
+		endpoint := helpers.Must(url.Parse(httpServer.URL))
+		conn := helpers.Must(client.NewConnection(fixtures.Service, thttp.NewChannel(endpoint, thttp.WithClient(httpServer.Client()))))
+
+		rt := helpers.RandomCID()
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: rt})))
+		resp := helpers.Must(client.Execute(t.Context(), []invocation.Invocation{inv}, conn))
+
+		rcptlnk, ok := resp.Get(inv.Link())
+		require.True(t, ok)
+		reader := helpers.Must(receipt.NewReceiptReader[uploadAddSuccess, ipld.Node](rcptsch))
+		rcpt := helpers.Must(reader.Read(rcptlnk, resp.Blocks()))
+		out, x := result.Unwrap(rcpt.Out())
+		require.Nil(t, x)
+		require.Equal(t, rt, out.Root)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		res := helpers.Must(httpServer.Client().Get(httpServer.URL))
+		res.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	})
+}
+
+func TestReceiptStore(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+
+	store := helpers.Must(receipt.NewMemoryStore(0))
+	server := helpers.Must(NewServer(
+		fixtures.Service,
+		WithServiceMethod(
+			uploadadd.Can(),
+			Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+				return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+			}),
+		),
+		WithReceiptStore(store),
+	))
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /", NewHTTPHandler(server))
+	mux.Handle("GET /receipt/{cid}", NewReceiptHandler(store))
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+
+	endpoint := helpers.Must(url.Parse(httpServer.URL))
+	receipts := endpoint.JoinPath("receipt")
+
+	t.Run("stores receipts", func(t *testing.T) {
+		conn := helpers.Must(client.NewConnection(fixtures.Service, server))
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		// failure receipts are also stored
+		notfound := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, ucan.NewCapability("upload/list", fixtures.Alice.DID().String(), ucan.NoCaveats{})))
+		resp := helpers.Must(client.Execute(t.Context(), []invocation.Invocation{inv, notfound}, conn))
+
+		for _, i := range []invocation.Invocation{inv, notfound} {
+			rcptlnk, ok := resp.Get(i.Link())
+			require.True(t, ok)
+
+			rcpt, ok, err := client.FetchReceipt(t.Context(), httpServer.Client(), receipts, i.Link())
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, rcptlnk, rcpt.Root().Link())
+			ran, ok := rcpt.Ran().Invocation()
+			require.True(t, ok)
+			require.Equal(t, i.Link(), ran.Link())
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, ok, err := client.FetchReceipt(t.Context(), httpServer.Client(), receipts, helpers.RandomCID())
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("invalid CID", func(t *testing.T) {
+		res := helpers.Must(httpServer.Client().Get(receipts.JoinPath("not-a-cid").String()))
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("store error fails request", func(t *testing.T) {
+		server := helpers.Must(NewServer(
+			fixtures.Service,
+			WithServiceMethod(
+				uploadadd.Can(),
+				Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+					return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+				}),
+			),
+			WithReceiptStore(failingReceiptStore{}),
+		))
+
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		_, err := server.Run(t.Context(), inv)
+		require.ErrorContains(t, err, "storing receipt")
+	})
+}
+
+type failingReceiptStore struct{}
+
+func (failingReceiptStore) Put(ctx context.Context, rcpt receipt.AnyReceipt) error {
+	return errors.New("store unavailable")
+}
+
+func (failingReceiptStore) Get(ctx context.Context, invocation ipld.Link) (receipt.AnyReceipt, bool, error) {
+	return nil, false, errors.New("store unavailable")
+}
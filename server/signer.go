@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/ran"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal"
+	"github.com/storacha/go-ucanto/ucan/crypto/signature"
+)
+
+// WithReceiptSigner configures the server to sign receipts with an operational
+// signer instead of the service identity, so that the service identity key
+// does not need to be available to the server (see [VerifierIdentity]). The
+// proofs are delegations that prove the authority of the signer to issue
+// receipts on behalf of the service and are attached to every receipt. One of
+// them must be delegated to the signer and the chain must be rooted in a
+// delegation issued by the service, typically of the "*" ability on the
+// service DID.
+//
+// Clients verify receipts signed by an operational signer with
+// client.VerifyReceipt.
+func WithReceiptSigner(signer principal.Signer, proofs ...delegation.Delegation) Option {
+	return func(cfg *srvConfig) error {
+		if err := ValidateReceiptSigner(signer, proofs...); err != nil {
+			return err
+		}
+		cfg.receiptSigner = signer
+		cfg.receiptProofs = proofs
+		return nil
+	}
+}
+
+// ValidateReceiptSigner checks that the passed proofs of the authority of a
+// receipt signer are not empty and that one of them is delegated to the
+// signer.
+func ValidateReceiptSigner(signer principal.Signer, proofs ...delegation.Delegation) error {
+	if len(proofs) == 0 {
+		return fmt.Errorf("missing proof of authority for receipt signer: %s", signer.DID())
+	}
+	for _, p := range proofs {
+		if p.Audience().DID() == signer.DID() {
+			return nil
+		}
+	}
+	return fmt.Errorf("no proof delegated to receipt signer: %s", signer.DID())
+}
+
+// receiptSigner is implemented by servers and invocation contexts that sign
+// receipts with an operational signer instead of the service identity.
+type receiptSigner interface {
+	// ReceiptSigner is the signer used to sign receipts, along with the proofs
+	// of its authority to issue receipts on behalf of the service. There are no
+	// proofs if the signer is the service itself.
+	ReceiptSigner() (principal.Signer, delegation.Proofs)
+}
+
+// signerOf returns the signer of receipts issued by the passed server or
+// invocation context, which is its identity unless it has a receipt signer.
+func signerOf(v interface{ ID() principal.Signer }) (principal.Signer, delegation.Proofs) {
+	if rs, ok := v.(receiptSigner); ok {
+		return rs.ReceiptSigner()
+	}
+	return v.ID(), nil
+}
+
+// IssueReceipt issues a receipt signed by the receipt signer of the passed
+// server (see [WithReceiptSigner]). Proofs of the authority of the signer are
+// added to the receipt.
+func IssueReceipt[S any, O, X ipld.Builder](server Server[S], out result.Result[O, X], ran ran.Ran, opts ...receipt.Option) (receipt.AnyReceipt, error) {
+	signer, proofs := signerOf(server)
+	if len(proofs) > 0 {
+		opts = append(opts, receipt.WithProofs(proofs))
+	}
+	return receipt.Issue(signer, out, ran, opts...)
+}
+
+// verifierIdentity is a service identity without a private key.
+type verifierIdentity struct {
+	vfr principal.Verifier
+}
+
+// VerifierIdentity creates a service identity for [NewServer] from the
+// verifier of the service key, for servers that do not hold the service
+// identity key. It cannot sign, so the server must be configured with a
+// receipt signer (see [WithReceiptSigner]), which also issues the tasks of the
+// server (see [NewServiceTask]). Signing with the identity panics.
+func VerifierIdentity(vfr principal.Verifier) principal.Signer {
+	return verifierIdentity{vfr}
+}
+
+func (v verifierIdentity) DID() did.DID {
+	return v.vfr.DID()
+}
+
+func (v verifierIdentity) Code() uint64 {
+	return v.vfr.Code()
+}
+
+// Sign panics, since the key of the service identity is not available. Anything
+// the server issues must be signed by its receipt signer instead.
+func (v verifierIdentity) Sign(msg []byte) signature.SignatureView {
+	panic(fmt.Sprintf("service identity %s cannot sign, sign with the receipt signer instead", v.DID()))
+}
+
+func (v verifierIdentity) SignatureAlgorithm() string {
+	return ""
+}
+
+func (v verifierIdentity) SignatureCode() uint64 {
+	return signature.NON_STANDARD
+}
+
+func (v verifierIdentity) Verifier() principal.Verifier {
+	return v.vfr
+}
+
+func (v verifierIdentity) Encode() []byte {
+	return nil
+}
+
+func (v verifierIdentity) Raw() []byte {
+	return nil
+}
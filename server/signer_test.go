@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/receipt/ran"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/principal/ed25519/signer"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptSigner(t *testing.T) {
+	authorize := func(t *testing.T, issuer ucan.Signer, audience ucan.Principal, can ucan.Ability, options ...delegation.Option) delegation.Delegation {
+		caps := []ucan.Capability[ucan.NoCaveats]{ucan.NewCapability(can, fixtures.Service.DID().String(), ucan.NoCaveats{})}
+		return helpers.Must(delegation.Delegate(issuer, audience, caps, options...))
+	}
+
+	execute := func(t *testing.T, srv ServerView[Service]) receipt.AnyReceipt {
+		rcpt := executeInvocation(t, srv, newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String()))
+		require.Equal(t, "", receiptFailure(t, rcpt))
+		return rcpt
+	}
+
+	t.Run("service signer", func(t *testing.T) {
+		rcpt := execute(t, newUploadAddServer(t, nil))
+		require.Equal(t, fixtures.Service.DID(), rcpt.Issuer().DID())
+		require.Empty(t, rcpt.Proofs())
+		require.NoError(t, client.VerifyReceipt(rcpt, fixtures.Service.Verifier()))
+		require.Error(t, client.VerifyReceipt(rcpt, fixtures.Bob.Verifier()))
+	})
+
+	t.Run("delegated signer", func(t *testing.T) {
+		op := helpers.Must(signer.Generate())
+		dlg := authorize(t, fixtures.Service, op, "*")
+		rcpt := execute(t, newUploadAddServer(t, nil, WithReceiptSigner(op, dlg)))
+		require.Equal(t, op.DID(), rcpt.Issuer().DID())
+		require.Len(t, rcpt.Proofs(), 1)
+		require.Equal(t, dlg.Link(), rcpt.Proofs()[0].Link())
+		require.NoError(t, client.VerifyReceipt(rcpt, fixtures.Service.Verifier()))
+	})
+
+	t.Run("delegation chain", func(t *testing.T) {
+		root := helpers.Must(signer.Generate())
+		op := helpers.Must(signer.Generate())
+		dlg := authorize(t, fixtures.Service, root, "*")
+		rcpt := execute(t, newUploadAddServer(t, nil, WithReceiptSigner(op, authorize(t, root, op, "upload/*", delegation.WithProof(delegation.FromDelegation(dlg))))))
+		require.NoError(t, client.VerifyReceipt(rcpt, fixtures.Service.Verifier()))
+	})
+
+	t.Run("unauthorized signer", func(t *testing.T) {
+		op := helpers.Must(signer.Generate())
+		rcpt := execute(t, newUploadAddServer(t, nil, WithReceiptSigner(op, authorize(t, fixtures.Mallory, op, "*"))))
+		require.ErrorContains(t, client.VerifyReceipt(rcpt, fixtures.Service.Verifier()), "not authorized")
+	})
+
+	t.Run("ability not delegated", func(t *testing.T) {
+		op := helpers.Must(signer.Generate())
+		rcpt := execute(t, newUploadAddServer(t, nil, WithReceiptSigner(op, authorize(t, fixtures.Service, op, "store/*"))))
+		require.ErrorContains(t, client.VerifyReceipt(rcpt, fixtures.Service.Verifier()), "not authorized")
+	})
+
+	t.Run("expired delegation", func(t *testing.T) {
+		op := helpers.Must(signer.Generate())
+		exp := int(time.Now().Add(-time.Minute).Unix())
+		rcpt := execute(t, newUploadAddServer(t, nil, WithReceiptSigner(op, authorize(t, fixtures.Service, op, "*", delegation.WithExpiration(exp)))))
+		require.ErrorContains(t, client.VerifyReceipt(rcpt, fixtures.Service.Verifier()), "not authorized")
+	})
+
+	t.Run("invocation not included", func(t *testing.T) {
+		op := helpers.Must(signer.Generate())
+		inv := newUploadAddInvocation(t, fixtures.Alice, fixtures.Alice.DID().String())
+		issue := func(t *testing.T, can ucan.Ability) receipt.AnyReceipt {
+			dlg := delegation.FromDelegation(authorize(t, fixtures.Service, op, can))
+			return helpers.Must(receipt.Issue(op, result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), ran.FromLink(inv.Link()), receipt.WithProofs(delegation.Proofs{dlg})))
+		}
+
+		// the ability of the invocation is unknown, so it cannot authorize
+		require.ErrorContains(t, client.VerifyReceipt(issue(t, "upload/*"), fixtures.Service.Verifier()), "not authorized")
+		require.NoError(t, client.VerifyReceipt(issue(t, client.ReceiptAbility), fixtures.Service.Verifier()))
+		require.NoError(t, client.VerifyReceipt(issue(t, "ucan/*"), fixtures.Service.Verifier()))
+		require.NoError(t, client.VerifyReceipt(issue(t, "*"), fixtures.Service.Verifier()))
+	})
+
+	t.Run("verifier identity", func(t *testing.T) {
+		id := VerifierIdentity(fixtures.Service.Verifier())
+		_, err := NewServer(id)
+		require.ErrorContains(t, err, "receipt signer must be configured")
+
+		op := helpers.Must(signer.Generate())
+		srv := helpers.Must(NewServer(id,
+			WithServiceMethod(uploadAdd.Can(), Provide(uploadAdd, uploadAddOk(nil))),
+			WithReceiptSigner(op, authorize(t, fixtures.Service, op, "*")),
+			WithConclusionHandler(func(ctx context.Context, rcpt receipt.AnyReceipt, task invocation.Invocation, ictx InvocationContext) error {
+				return nil
+			}),
+		))
+		rcpt := execute(t, srv)
+		require.Equal(t, op.DID(), rcpt.Issuer().DID())
+		require.NoError(t, client.VerifyReceipt(rcpt, fixtures.Service.Verifier()))
+
+		// tasks are issued by the receipt signer
+		task := helpers.Must(invocation.Invoke(op, fixtures.Alice, ucan.NewCapability("test/task", fixtures.Alice.DID().String(), ucan.NoCaveats{})))
+		concluded := helpers.Must(receipt.Issue(fixtures.Alice, result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), ran.FromInvocation(task)))
+		inv := helpers.Must(client.Conclude(fixtures.Alice, fixtures.Service, concluded))
+		require.Equal(t, "", receiptFailure(t, executeInvocation(t, srv, inv)))
+	})
+
+	t.Run("verifier identity cannot sign", func(t *testing.T) {
+		id := VerifierIdentity(fixtures.Service.Verifier())
+		require.PanicsWithValue(t, "service identity "+fixtures.Service.DID().String()+" cannot sign, sign with the receipt signer instead", func() {
+			id.Sign([]byte("hello"))
+		})
+	})
+
+	t.Run("verifier identity tasks", func(t *testing.T) {
+		accept := validator.NewCapability("test/accept", schema.DIDString(), schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil), nil)
+		var wg sync.WaitGroup
+		scheduler := TaskSchedulerFunc(func(ctx context.Context, task func(context.Context)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				task(context.WithoutCancel(ctx))
+			}()
+		})
+
+		var accepted atomic.Int64
+		op := helpers.Must(signer.Generate())
+		store := helpers.Must(receipt.NewMemoryStore(0))
+		srv := helpers.Must(NewServer(VerifierIdentity(fixtures.Service.Verifier()),
+			WithServiceMethod(uploadAdd.Can(), Provide(uploadAdd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+				task, err := NewServiceTask(ictx, accept.New(ictx.ID().DID().String(), cap.Nb()))
+				if err != nil {
+					return nil, nil, err
+				}
+				return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "pending"}), fx.NewEffects(fx.WithJoin(fx.FromInvocation(task))), nil
+			})),
+			WithTaskExecutor(accept.Can(), Provide(accept, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, error) {
+				accepted.Add(1)
+				return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, nil
+			})),
+			WithReceiptSigner(op, authorize(t, fixtures.Service, op, "*")),
+			WithTaskExecution(scheduler),
+			WithReceiptStore(store),
+		))
+
+		rcpt := execute(t, srv)
+		wg.Wait()
+
+		task, ok := rcpt.Fx().Join().Invocation()
+		require.True(t, ok)
+		require.Equal(t, op.DID(), task.Issuer().DID())
+		require.Equal(t, fixtures.Service.DID(), task.Audience().DID())
+		require.Equal(t, int64(1), accepted.Load())
+		trcpt, found, err := store.Get(t.Context(), task.Link())
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, "", receiptFailure(t, trcpt))
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		op := helpers.Must(signer.Generate())
+		_, err := NewServer(fixtures.Service, WithReceiptSigner(op))
+		require.ErrorContains(t, err, "missing proof")
+
+		_, err = NewServer(fixtures.Service, WithReceiptSigner(op, authorize(t, fixtures.Service, fixtures.Bob, "*")))
+		require.ErrorContains(t, err, "no proof delegated to receipt signer")
+	})
+}
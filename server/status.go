@@ -0,0 +1,130 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/transport"
+	thttp "github.com/storacha/go-ucanto/transport/http"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// Outcome is the outcome of an invocation in a request.
+type Outcome struct {
+	// Ability is the ability that was invoked.
+	Ability ucan.Ability
+	// Failure is the name of the failure the invocation resulted in, or empty
+	// if it succeeded.
+	Failure string
+}
+
+// StatusPolicy maps the outcomes of the invocations in a request to the HTTP
+// status of the response. See [WithStatusPolicy].
+type StatusPolicy func(outcomes []Outcome) int
+
+// DefaultFailureStatuses are the HTTP statuses of failures used by
+// [DefaultStatusPolicy].
+var DefaultFailureStatuses = map[string]int{
+	"InvocationCapabilityError": http.StatusBadRequest,
+	"Unauthorized":              http.StatusForbidden,
+	"InvalidAudienceError":      http.StatusForbidden,
+	"HandlerNotFoundError":      http.StatusNotFound,
+	"Replayed":                  http.StatusConflict,
+	"InvalidExpiration":         http.StatusBadRequest,
+	"RateLimited":               http.StatusTooManyRequests,
+	"HandlerExecutionError":     http.StatusInternalServerError,
+	"Timeout":                   http.StatusGatewayTimeout,
+}
+
+// DefaultStatusPolicy maps outcomes to statuses using [DefaultFailureStatuses].
+var DefaultStatusPolicy = StatusByFailure(DefaultFailureStatuses)
+
+// StatusByFailure creates a policy that responds with the status of the
+// failure that every invocation in the request resulted in, when the failures
+// of all invocations map to the same status. Otherwise, including when any
+// invocation succeeded, the response has a 200 status.
+func StatusByFailure(statuses map[string]int) StatusPolicy {
+	return func(outcomes []Outcome) int {
+		status := 0
+		for _, o := range outcomes {
+			s, ok := statuses[o.Failure]
+			if o.Failure == "" || !ok || (status != 0 && s != status) {
+				return http.StatusOK
+			}
+			status = s
+		}
+		if status == 0 {
+			return http.StatusOK
+		}
+		return status
+	}
+}
+
+// WithStatusPolicy configures the server to respond with an HTTP status that
+// reflects the outcome of the invocations in a request, as determined by the
+// passed policy, for example [DefaultStatusPolicy]. The response also has
+// headers summarising the outcomes (see [thttp.ReceiptsHeader] and
+// [thttp.FailuresHeader]). The body of the response is unchanged, so clients
+// must accept responses with the statuses the policy produces (see
+// [thttp.WithReceiptStatusCodes]).
+//
+// By default, responses have a 200 status regardless of the outcome of the
+// invocations.
+func WithStatusPolicy(policy StatusPolicy) Option {
+	return func(cfg *srvConfig) error {
+		cfg.statusPolicy = policy
+		return nil
+	}
+}
+
+// statusPolicer is implemented by servers configured with a status policy.
+type statusPolicer interface {
+	statusPolicy() StatusPolicy
+}
+
+func (srv *server) statusPolicy() StatusPolicy {
+	return srv.statuses
+}
+
+// applyStatusPolicy returns the passed response with the status determined by
+// the policy and headers summarising the outcomes of the invocations.
+func applyStatusPolicy(resp transport.HTTPResponse, policy StatusPolicy, invs []invocation.Invocation, rcpts []receipt.AnyReceipt) transport.HTTPResponse {
+	outcomes := make([]Outcome, 0, len(rcpts))
+	failures := map[string]int{}
+	for i, rcpt := range rcpts {
+		var o Outcome
+		if caps := invs[i].Capabilities(); len(caps) > 0 {
+			o.Ability = caps[0].Can()
+		}
+		result.MatchResultR0(rcpt.Out(), func(ipld.Node) {}, func(x ipld.Node) {
+			o.Failure = failureName(x)
+			failures[o.Failure]++
+		})
+		outcomes = append(outcomes, o)
+	}
+
+	headers := resp.Headers().Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Set(thttp.ReceiptsHeader, strconv.Itoa(len(rcpts)))
+	if len(failures) > 0 {
+		var summary []string
+		for _, name := range slices.Sorted(maps.Keys(failures)) {
+			summary = append(summary, fmt.Sprintf("%s=%d", name, failures[name]))
+		}
+		headers.Set(thttp.FailuresHeader, strings.Join(summary, ", "))
+	}
+
+	return thttp.NewResponse(policy(outcomes), resp.Body(), headers)
+}
+
+var _ statusPolicer = (*server)(nil)
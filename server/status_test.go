@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/storacha/go-ucanto/client"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/transport/car/request"
+	thttp "github.com/storacha/go-ucanto/transport/http"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusPolicy(t *testing.T) {
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+	method := WithServiceMethod(uploadadd.Can(), Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+		return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+	}))
+
+	authorized := func(t *testing.T) invocation.Invocation {
+		cap := uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})
+		return helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, cap))
+	}
+	unauthorized := func(t *testing.T) invocation.Invocation {
+		cap := uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})
+		return helpers.Must(invocation.Invoke(fixtures.Bob, fixtures.Service, cap))
+	}
+	notFound := func(t *testing.T) invocation.Invocation {
+		cap := ucan.NewCapability("store/add", fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})
+		return helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, cap))
+	}
+
+	handle := func(t *testing.T, server ServerView[Service], invs ...invocation.Invocation) (int, http.Header) {
+		req := helpers.Must(request.Encode(helpers.Must(message.Build(invs, nil))))
+		res := helpers.Must(Handle(t.Context(), server, req))
+		return res.Status(), res.Headers()
+	}
+
+	server := helpers.Must(NewServer(fixtures.Service, method, WithStatusPolicy(DefaultStatusPolicy)))
+
+	t.Run("success", func(t *testing.T) {
+		status, headers := handle(t, server, authorized(t), authorized(t))
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "2", headers.Get(thttp.ReceiptsHeader))
+		require.Empty(t, headers.Get(thttp.FailuresHeader))
+	})
+
+	t.Run("all unauthorized", func(t *testing.T) {
+		status, headers := handle(t, server, unauthorized(t), unauthorized(t))
+		require.Equal(t, http.StatusForbidden, status)
+		require.Equal(t, "Unauthorized=2", headers.Get(thttp.FailuresHeader))
+	})
+
+	t.Run("handler not found", func(t *testing.T) {
+		status, _ := handle(t, server, notFound(t))
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("mixed outcomes", func(t *testing.T) {
+		status, headers := handle(t, server, authorized(t), unauthorized(t), notFound(t))
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "3", headers.Get(thttp.ReceiptsHeader))
+		require.Equal(t, "HandlerNotFoundError=1, Unauthorized=1", headers.Get(thttp.FailuresHeader))
+
+		status, _ = handle(t, server, unauthorized(t), notFound(t))
+		require.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("no policy", func(t *testing.T) {
+		server := helpers.Must(NewServer(fixtures.Service, method))
+		status, headers := handle(t, server, unauthorized(t))
+		require.Equal(t, http.StatusOK, status)
+		require.Empty(t, headers.Get(thttp.ReceiptsHeader))
+	})
+
+	t.Run("client accepts receipt statuses", func(t *testing.T) {
+		httpServer := httptest.NewServer(NewHTTPHandler(server))
+		t.Cleanup(httpServer.Close)
+		endpoint := helpers.Must(url.Parse(httpServer.URL))
+		conn := helpers.Must(client.NewConnection(fixtures.Service, thttp.NewChannel(endpoint, thttp.WithClient(httpServer.Client()), thttp.WithReceiptStatusCodes())))
+
+		inv := unauthorized(t)
+		resp := helpers.Must(client.Execute(t.Context(), []invocation.Invocation{inv}, conn))
+		rcptlnk, ok := resp.Get(inv.Link())
+		require.True(t, ok)
+		rcpt := helpers.Must(receipt.NewAnyReceiptReader().Read(rcptlnk, resp.Blocks()))
+		_, x := result.Unwrap(rcpt.Out())
+		require.Equal(t, "Unauthorized", *asFailure(t, x).Name)
+	})
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/principal"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// TaskScheduler schedules tasks for asynchronous execution.
+type TaskScheduler interface {
+	// Schedule arranges for the passed task to be called at some point in the
+	// future. The passed context is the context of the invocation that created
+	// the task, and is likely to be canceled before the task runs.
+	Schedule(ctx context.Context, task func(ctx context.Context))
+}
+
+// TaskSchedulerFunc is an adapter to allow the use of ordinary functions as
+// task schedulers.
+type TaskSchedulerFunc func(ctx context.Context, task func(ctx context.Context))
+
+func (fn TaskSchedulerFunc) Schedule(ctx context.Context, task func(ctx context.Context)) {
+	fn(ctx, task)
+}
+
+// goScheduler runs each task in a new goroutine, with a context that retains
+// the values of the invocation context but is not canceled with it.
+type goScheduler struct{}
+
+func (goScheduler) Schedule(ctx context.Context, task func(ctx context.Context)) {
+	go task(context.WithoutCancel(ctx))
+}
+
+// NewTask creates an invocation issued by the service to itself, for use as a
+// fork or join effect. For example, a handler may return a pending result that
+// joins on a task:
+//
+//	task, err := server.NewTask(ictx.ID(), blob.Accept.New(ictx.ID().DID().String(), nb))
+//	...
+//	return result.Ok[O, X](pending), fx.NewEffects(fx.WithJoin(fx.FromInvocation(task))), nil
+//
+// When task execution is enabled (see [WithTaskExecution]) the server executes
+// the task once the receipt for the original invocation has been issued.
+//
+// The task is signed by the passed signer, so it cannot be created with the
+// identity of a server that does not hold its key (see [VerifierIdentity]).
+// Use [NewServiceTask] to create tasks in handlers of any server.
+func NewTask[C ucan.CaveatBuilder](id principal.Signer, capability ucan.Capability[C], options ...delegation.Option) (invocation.Invocation, error) {
+	return invocation.Invoke(id, id, capability, options...)
+}
+
+// NewServiceTask creates a task like [NewTask] for the service of the passed
+// invocation context. It is issued by the receipt signer of the service if it
+// has one (see [WithReceiptSigner]), with the proofs of its authority, and by
+// the service itself otherwise:
+//
+//	task, err := server.NewServiceTask(ictx, blob.Accept.New(ictx.ID().DID().String(), nb))
+func NewServiceTask[C ucan.CaveatBuilder](ictx InvocationContext, capability ucan.Capability[C], options ...delegation.Option) (invocation.Invocation, error) {
+	signer, proofs := signerOf(ictx)
+	if len(proofs) > 0 {
+		options = append(options, delegation.WithProof(proofs...))
+	}
+	return invocation.Invoke(signer, ictx.ID(), capability, options...)
+}
+
+// taskRunner is implemented by servers that execute the effects of the
+// receipts they issue.
+type taskRunner interface {
+	scheduleTasks(ctx context.Context, rcpt receipt.AnyReceipt)
+}
+
+// taskServer is a view of the server used to execute tasks. Its service
+// includes the methods registered as task executors.
+type taskServer struct {
+	*server
+}
+
+func (ts taskServer) Service() Service {
+	return ts.tasks
+}
+
+func (srv *server) scheduleTasks(ctx context.Context, rcpt receipt.AnyReceipt) {
+	if srv.scheduler == nil {
+		return
+	}
+	effects := rcpt.Fx()
+	if effects == nil {
+		return
+	}
+
+	var tasks []invocation.Invocation
+	seen := map[string]struct{}{}
+	for _, e := range slices.Concat(effects.Fork(), []fx.Effect{effects.Join()}) {
+		// effects that are links cannot be executed here, they are assumed to be
+		// executed elsewhere
+		inv, ok := e.Invocation()
+		if !ok {
+			continue
+		}
+		if _, ok := seen[inv.Link().String()]; ok {
+			continue
+		}
+		seen[inv.Link().String()] = struct{}{}
+		tasks = append(tasks, inv)
+	}
+
+	for _, task := range tasks {
+		srv.scheduler.Schedule(ctx, func(ctx context.Context) {
+			if err := srv.runTask(ctx, task); err != nil {
+				var cap ucan.Capability[any]
+				if caps := task.Capabilities(); len(caps) > 0 {
+					cap = caps[0]
+				} else {
+					cap = ucan.NewCapability[any]("", "", nil)
+				}
+				srv.Catch(NewHandlerExecutionError(err, cap))
+			}
+		})
+	}
+}
+
+func (srv *server) runTask(ctx context.Context, task invocation.Invocation) error {
+	// a task may be returned as an effect more than once, for example when an
+	// invocation is retried, but should only be executed once. Tasks that are
+	// running are tracked so that concurrent duplicates are skipped before their
+	// receipt is stored.
+	key := task.Link().String()
+	srv.runningMutex.Lock()
+	if _, ok := srv.running[key]; ok {
+		srv.runningMutex.Unlock()
+		return nil
+	}
+	srv.running[key] = struct{}{}
+	srv.runningMutex.Unlock()
+	defer func() {
+		srv.runningMutex.Lock()
+		delete(srv.running, key)
+		srv.runningMutex.Unlock()
+	}()
+
+	if srv.receipts != nil {
+		_, ok, err := srv.receipts.Get(ctx, task.Link())
+		if err != nil {
+			return fmt.Errorf("checking for task receipt: %w", err)
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	_, err := Run(ctx, taskServer{srv}, task)
+	if err != nil {
+		return fmt.Errorf("running task %s: %w", task.Link(), err)
+	}
+	return nil
+}
+
+var _ taskRunner = (*server)(nil)
+var _ taskRunner = taskServer{}
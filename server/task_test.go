@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/receipt/ran"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/server/transaction"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskExecution(t *testing.T) {
+	allocate := validator.NewCapability("test/allocate", schema.DIDString(), schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil), nil)
+	accept := validator.NewCapability("test/accept", schema.DIDString(), schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil), nil)
+	notify := validator.NewCapability("test/notify", schema.DIDString(), schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil), nil)
+
+	var wg sync.WaitGroup
+	scheduler := TaskSchedulerFunc(func(ctx context.Context, task func(context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task(context.WithoutCancel(ctx))
+		}()
+	})
+
+	var mutex sync.Mutex
+	var executed []string
+	record := func(can string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		executed = append(executed, can)
+	}
+
+	newServer := func(t *testing.T, store receipt.Store, options ...Option) ServerView[Service] {
+		options = append([]Option{
+			WithServiceMethod(
+				allocate.Can(),
+				Provide(allocate, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, error) {
+					record(allocate.Can())
+					join, err := NewTask(ictx.ID(), accept.New(ictx.ID().DID().String(), cap.Nb()))
+					if err != nil {
+						return nil, nil, err
+					}
+					fork, err := NewTask(ictx.ID(), notify.New(ictx.ID().DID().String(), cap.Nb()))
+					if err != nil {
+						return nil, nil, err
+					}
+					effects := fx.NewEffects(fx.WithFork(fx.FromInvocation(fork)), fx.WithJoin(fx.FromInvocation(join)))
+					return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), effects, nil
+				}),
+			),
+			WithServiceMethod(
+				notify.Can(),
+				Provide(notify, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, error) {
+					record(notify.Can())
+					return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, nil
+				}),
+			),
+			WithTaskExecutor(
+				accept.Can(),
+				Provide(accept, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[ok.Unit, failure.IPLDBuilderFailure], fx.Effects, error) {
+					record(accept.Can())
+					return result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), nil, nil
+				}),
+			),
+			WithReceiptStore(store),
+		}, options...)
+		return helpers.Must(NewServer(fixtures.Service, options...))
+	}
+
+	t.Run("executes effects", func(t *testing.T) {
+		executed = nil
+		store := helpers.Must(receipt.NewMemoryStore(0))
+		server := newServer(t, store, WithTaskExecution(scheduler))
+
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, allocate.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		rcpt := helpers.Must(server.Run(t.Context(), inv))
+		wg.Wait()
+
+		require.ElementsMatch(t, []string{allocate.Can(), accept.Can(), notify.Can()}, executed)
+
+		effects := []fx.Effect{rcpt.Fx().Join()}
+		effects = append(effects, rcpt.Fx().Fork()...)
+		for _, e := range effects {
+			trcpt, ok, err := store.Get(t.Context(), e.Link())
+			require.NoError(t, err)
+			require.True(t, ok, "missing receipt for effect: %s", e.Link())
+			_, x := result.Unwrap(trcpt.Out())
+			require.Nil(t, x)
+		}
+	})
+
+	t.Run("task executors are not exposed", func(t *testing.T) {
+		executed = nil
+		store := helpers.Must(receipt.NewMemoryStore(0))
+		server := newServer(t, store, WithTaskExecution(scheduler))
+
+		inv := helpers.Must(invocation.Invoke(fixtures.Service, fixtures.Service, accept.New(fixtures.Service.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		rcpt := helpers.Must(server.Run(t.Context(), inv))
+		wg.Wait()
+
+		_, x := result.Unwrap(rcpt.Out())
+		require.NotNil(t, x)
+		require.Equal(t, "HandlerNotFoundError", *asFailure(t, x).Name)
+		require.Empty(t, executed)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		executed = nil
+		store := helpers.Must(receipt.NewMemoryStore(0))
+		server := newServer(t, store)
+
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, allocate.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		rcpt := helpers.Must(server.Run(t.Context(), inv))
+		wg.Wait()
+
+		require.Equal(t, []string{allocate.Can()}, executed)
+		_, ok, err := store.Get(t.Context(), rcpt.Fx().Join().Link())
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("executes task once", func(t *testing.T) {
+		executed = nil
+		store := helpers.Must(receipt.NewMemoryStore(0))
+		srv := newServer(t, store, WithTaskExecution(scheduler))
+
+		task := helpers.Must(NewTask(fixtures.Service, notify.New(fixtures.Service.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		effects := fx.NewEffects(fx.WithFork(fx.FromInvocation(task), fx.FromInvocation(task)))
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, allocate.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		rcpt := helpers.Must(receipt.Issue(fixtures.Service, result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), ran.FromInvocation(inv), receipt.WithFork(effects.Fork()...)))
+
+		srv.(*server).scheduleTasks(t.Context(), rcpt)
+		wg.Wait()
+		srv.(*server).scheduleTasks(t.Context(), rcpt)
+		wg.Wait()
+
+		require.Len(t, executed, 1)
+	})
+
+	t.Run("executes concurrent duplicate task once", func(t *testing.T) {
+		executed = nil
+		store := helpers.Must(receipt.NewMemoryStore(0))
+		// slow tasks down so that duplicates run concurrently
+		srv := newServer(t, store, WithTaskExecution(scheduler), WithInterceptor(func(ctx context.Context, inv invocation.Invocation, ictx InvocationContext, next ServiceMethod[ipld.Builder, failure.IPLDBuilderFailure]) (transaction.Transaction[ipld.Builder, failure.IPLDBuilderFailure], error) {
+			time.Sleep(50 * time.Millisecond)
+			return next(ctx, inv, ictx)
+		}))
+
+		task := helpers.Must(NewTask(fixtures.Service, notify.New(fixtures.Service.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, allocate.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})))
+		rcpt := helpers.Must(receipt.Issue(fixtures.Service, result.Ok[ok.Unit, failure.IPLDBuilderFailure](ok.Unit{}), ran.FromInvocation(inv), receipt.WithFork(fx.FromInvocation(task))))
+
+		for range 10 {
+			srv.(*server).scheduleTasks(t.Context(), rcpt)
+		}
+		wg.Wait()
+
+		require.Len(t, executed, 1)
+	})
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/result"
+)
+
+const instrumentationName = "github.com/storacha/go-ucanto/server"
+
+// Attribute keys used in spans and metrics emitted by the server.
+const (
+	AbilityKey     = attribute.Key("ucanto.ability")
+	ResourceKey    = attribute.Key("ucanto.resource")
+	IssuerKey      = attribute.Key("ucanto.issuer")
+	InvocationKey  = attribute.Key("ucanto.invocation")
+	OutcomeKey     = attribute.Key("ucanto.outcome")
+	FailureNameKey = attribute.Key("ucanto.failure.name")
+)
+
+const (
+	outcomeOk    = "ok"
+	outcomeError = "error"
+)
+
+var (
+	tracer = otel.Tracer(instrumentationName)
+	meter  = otel.Meter(instrumentationName)
+
+	invocationDuration metric.Float64Histogram
+	invocationFailures metric.Int64Counter
+	requestDuration    metric.Float64Histogram
+)
+
+func init() {
+	var err error
+	invocationDuration, err = meter.Float64Histogram(
+		"ucanto.server.invocation.duration",
+		metric.WithDescription("Duration of invocation execution, including authorization and receipt issuance."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	invocationFailures, err = meter.Int64Counter(
+		"ucanto.server.invocation.failures",
+		metric.WithDescription("Number of invocations that resulted in a failure receipt, by failure name."),
+		metric.WithUnit("{invocation}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	requestDuration, err = meter.Float64Histogram(
+		"ucanto.server.request.duration",
+		metric.WithDescription("Duration of handling an inbound request, including decode and encode."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+}
+
+// ExtractTraceContext extracts W3C trace context from inbound request headers
+// so that server spans join the trace started by the client.
+func ExtractTraceContext(ctx context.Context, headers http.Header) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(headers))
+}
+
+// InjectTraceContext injects the trace context of the current span into
+// outbound response headers.
+func InjectTraceContext(ctx context.Context, headers http.Header) {
+	if headers == nil {
+		return
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(headers))
+}
+
+// InvocationAttributes returns span attributes describing the passed
+// invocation.
+func InvocationAttributes(inv invocation.Invocation) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		InvocationKey.String(inv.Link().String()),
+		IssuerKey.String(inv.Issuer().DID().String()),
+	}
+	if caps := inv.Capabilities(); len(caps) > 0 {
+		attrs = append(attrs, AbilityKey.String(caps[0].Can()), ResourceKey.String(caps[0].With()))
+	}
+	return attrs
+}
+
+// RecordReceipt records the outcome of an invocation on the span and in
+// invocation metrics.
+func RecordReceipt(ctx context.Context, span trace.Span, inv invocation.Invocation, rcpt receipt.AnyReceipt, start time.Time) {
+	var ability string
+	if caps := inv.Capabilities(); len(caps) > 0 {
+		ability = caps[0].Can()
+	}
+
+	outcome, name := outcomeOk, ""
+	if rcpt != nil {
+		result.MatchResultR0(rcpt.Out(), func(ipld.Node) {}, func(x ipld.Node) {
+			outcome, name = outcomeError, failureName(x)
+		})
+	}
+
+	span.SetAttributes(OutcomeKey.String(outcome))
+	if outcome == outcomeError {
+		span.SetAttributes(FailureNameKey.String(name))
+		span.SetStatus(codes.Error, name)
+		invocationFailures.Add(ctx, 1, metric.WithAttributes(AbilityKey.String(ability), FailureNameKey.String(name)))
+	}
+	invocationDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(AbilityKey.String(ability), OutcomeKey.String(outcome)))
+}
+
+// RecordError records a (non-result) error on the span and marks it as failed.
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// failureName extracts the name of a failure from its IPLD representation,
+// returning "Unknown" if there is no name.
+func failureName(n ipld.Node) string {
+	if n == nil {
+		return "Unknown"
+	}
+	nn, err := n.LookupByString("name")
+	if err != nil {
+		return "Unknown"
+	}
+	name, err := nn.AsString()
+	if err != nil || name == "" {
+		return "Unknown"
+	}
+	return name
+}
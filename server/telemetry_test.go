@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/transport/car"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/storacha/go-ucanto/validator"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTelemetry(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	metrics := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metrics)))
+
+	prevProp := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prevProp) })
+
+	uploadadd := validator.NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[uploadAddCaveats](uploadAddCaveatsType(), nil),
+		nil,
+	)
+
+	server := helpers.Must(NewServer(
+		fixtures.Service,
+		WithServiceMethod(
+			uploadadd.Can(),
+			Provide(uploadadd, func(ctx context.Context, cap ucan.Capability[uploadAddCaveats], inv invocation.Invocation, ictx InvocationContext) (result.Result[uploadAddSuccess, uploadAddFailure], fx.Effects, error) {
+				return result.Ok[uploadAddSuccess, uploadAddFailure](uploadAddSuccess{Root: cap.Nb().Root, Status: "done"}), nil, nil
+			}),
+		),
+	))
+
+	cap := uploadadd.New(fixtures.Alice.DID().String(), uploadAddCaveats{Root: helpers.RandomCID()})
+	inv := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, cap))
+	notfound := helpers.Must(invocation.Invoke(fixtures.Alice, fixtures.Service, ucan.NewCapability("upload/list", fixtures.Alice.DID().String(), ucan.NoCaveats{})))
+	msg := helpers.Must(message.Build([]invocation.Invocation{inv, notfound}, nil))
+
+	const traceID = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	req := helpers.Must(car.NewOutboundCodec().Encode(msg))
+	req.Headers().Set("traceparent", "00-"+traceID+"-bbbbbbbbbbbbbbbb-01")
+
+	res, err := Handle(t.Context(), server, req)
+	require.NoError(t, err)
+	require.Contains(t, res.Headers().Get("traceparent"), traceID)
+
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, s := range spans.Ended() {
+		require.Equal(t, traceID, s.SpanContext().TraceID().String())
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+	for _, name := range []string{
+		"ucanto.server.Handle",
+		"ucanto.server.Decode",
+		"ucanto.server.Execute",
+		"ucanto.validator.Access",
+		"ucanto.server.Handler",
+		"ucanto.server.Encode",
+	} {
+		require.Len(t, byName[name], 1, name)
+	}
+	require.Len(t, byName["ucanto.server.Run"], 2)
+
+	outcomes := map[string]string{}
+	for _, s := range byName["ucanto.server.Run"] {
+		attrs := attribute.NewSet(s.Attributes()...)
+		ability, _ := attrs.Value(AbilityKey)
+		outcome, _ := attrs.Value(OutcomeKey)
+		issuer, _ := attrs.Value(IssuerKey)
+		require.Equal(t, fixtures.Alice.DID().String(), issuer.AsString())
+		outcomes[ability.AsString()] = outcome.AsString()
+		if outcome.AsString() == outcomeError {
+			name, _ := attrs.Value(FailureNameKey)
+			require.Equal(t, "HandlerNotFoundError", name.AsString())
+		}
+	}
+	require.Equal(t, map[string]string{"upload/add": outcomeOk, "upload/list": outcomeError}, outcomes)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, metrics.Collect(t.Context(), &rm))
+	found := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			found[m.Name] = true
+		}
+	}
+	require.True(t, found["ucanto.server.invocation.duration"])
+	require.True(t, found["ucanto.server.invocation.failures"])
+	require.True(t, found["ucanto.server.request.duration"])
+}
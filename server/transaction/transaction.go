@@ -10,11 +10,14 @@ import (
 type Transaction[O any, X any] interface {
 	Out() result.Result[O, X]
 	Fx() fx.Effects
+	// Meta is additional metadata that should be added to the receipt.
+	Meta() map[string]any
 }
 
 type transaction[O, X any] struct {
-	out result.Result[O, X]
-	fx  fx.Effects
+	out  result.Result[O, X]
+	fx   fx.Effects
+	meta map[string]any
 }
 
 func (t transaction[O, X]) Out() result.Result[O, X] {
@@ -25,11 +28,16 @@ func (t transaction[O, X]) Fx() fx.Effects {
 	return t.fx
 }
 
+func (t transaction[O, X]) Meta() map[string]any {
+	return t.meta
+}
+
 // Option is an option configuring a transaction.
 type Option func(cfg *txConfig)
 
 type txConfig struct {
-	fx fx.Effects
+	fx   fx.Effects
+	meta map[string]any
 }
 
 // WithEffects configures the effects for the receipt.
@@ -39,10 +47,19 @@ func WithEffects(fx fx.Effects) Option {
 	}
 }
 
+// WithMeta configures metadata for the receipt. Values are converted to IPLD
+// in the same way as receipt.WithMeta i.e. they should be pointers to data
+// that can be bound to an IPLD node.
+func WithMeta(meta map[string]any) Option {
+	return func(cfg *txConfig) {
+		cfg.meta = meta
+	}
+}
+
 func NewTransaction[O, X any](result result.Result[O, X], options ...Option) Transaction[O, X] {
 	cfg := txConfig{}
 	for _, opt := range options {
 		opt(&cfg)
 	}
-	return transaction[O, X]{out: result, fx: cfg.fx}
+	return transaction[O, X]{out: result, fx: cfg.fx, meta: cfg.meta}
 }
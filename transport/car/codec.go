@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	ucar "github.com/storacha/go-ucanto/core/car"
 	"github.com/storacha/go-ucanto/core/message"
 	"github.com/storacha/go-ucanto/transport"
 	"github.com/storacha/go-ucanto/transport/car/request"
@@ -27,7 +28,9 @@ func NewOutboundCodec() transport.OutboundCodec {
 	return &carOutbound{}
 }
 
-type carInboundAcceptCodec struct{}
+type carInboundAcceptCodec struct {
+	options []ucar.DecodeOption
+}
 
 func (cic *carInboundAcceptCodec) Encoder() transport.ResponseEncoder {
 	return cic
@@ -42,7 +45,7 @@ func (cic *carInboundAcceptCodec) Encode(msg message.AgentMessage) (transport.HT
 }
 
 func (cic *carInboundAcceptCodec) Decode(req transport.HTTPRequest) (message.AgentMessage, error) {
-	return request.Decode(req)
+	return request.Decode(req, cic.options...)
 }
 
 type carInbound struct {
@@ -82,6 +85,9 @@ func (ic *carInbound) Accept(req transport.HTTPRequest) (transport.InboundAccept
 
 var _ transport.InboundCodec = (*carInbound)(nil)
 
-func NewInboundCodec() transport.InboundCodec {
-	return &carInbound{codec: &carInboundAcceptCodec{}}
+// NewInboundCodec creates a codec that decodes requests and encodes responses
+// as CARs. Options limit the blocks that may be decoded from a request, see
+// [ucar.WithMaxBlocks] and [ucar.WithMaxBlockSize].
+func NewInboundCodec(options ...ucar.DecodeOption) transport.InboundCodec {
+	return &carInbound{codec: &carInboundAcceptCodec{options: options}}
 }
@@ -23,8 +23,11 @@ func Encode(message message.AgentMessage) (transport.HTTPRequest, error) {
 	return uhttp.NewRequest(reader, headers), nil
 }
 
-func Decode(req transport.HTTPRequest) (message.AgentMessage, error) {
-	roots, blocks, err := car.Decode(req.Body())
+// Decode decodes an agent message from the CAR in the request body. Options
+// limit the blocks that may be decoded, see [car.WithMaxBlocks] and
+// [car.WithMaxBlockSize].
+func Decode(req transport.HTTPRequest, options ...car.DecodeOption) (message.AgentMessage, error) {
+	roots, blocks, err := car.Decode(req.Body(), options...)
 	if err != nil {
 		return nil, fmt.Errorf("decoding CAR: %w", err)
 	}
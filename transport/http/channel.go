@@ -19,10 +19,11 @@ import (
 type Option func(cfg *chanConfig)
 
 type chanConfig struct {
-	client   *http.Client
-	method   string
-	statuses []int
-	headers  http.Header
+	client          *http.Client
+	method          string
+	statuses        []int
+	headers         http.Header
+	receiptStatuses bool
 }
 
 // WithClient configures the HTTP client the channel should use to make
@@ -49,6 +50,16 @@ func WithSuccessStatusCode(codes ...int) Option {
 	}
 }
 
+// WithReceiptStatusCodes configures the channel to accept responses with any
+// status code if they carry receipts, as indicated by the [ReceiptsHeader].
+// Servers configured with a status policy respond with a status reflecting the
+// outcome of the invocations, but the body still contains their receipts.
+func WithReceiptStatusCodes() Option {
+	return func(cfg *chanConfig) {
+		cfg.receiptStatuses = true
+	}
+}
+
 // WithHeaders configures additional HTTP headers to send with requests.
 func WithHeaders(h http.Header) Option {
 	return func(cfg *chanConfig) {
@@ -57,11 +68,12 @@ func WithHeaders(h http.Header) Option {
 }
 
 type Channel struct {
-	url      *url.URL
-	client   *http.Client
-	headers  http.Header
-	method   string
-	statuses []int
+	url             *url.URL
+	client          *http.Client
+	headers         http.Header
+	method          string
+	statuses        []int
+	receiptStatuses bool
 }
 
 func (c *Channel) Request(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
@@ -77,7 +89,7 @@ func (c *Channel) Request(ctx context.Context, req transport.HTTPRequest) (trans
 	if err != nil {
 		return nil, fmt.Errorf("doing HTTP request: %w", err)
 	}
-	if !slices.Contains(c.statuses, res.StatusCode) {
+	if !slices.Contains(c.statuses, res.StatusCode) && !(c.receiptStatuses && res.Header.Get(ReceiptsHeader) != "") {
 		const maxBodyLen = 4096
 		b, readErr := io.ReadAll(io.LimitReader(res.Body, maxBodyLen+1))
 		res.Body.Close()
@@ -141,10 +153,11 @@ func NewChannel(url *url.URL, options ...Option) *Channel {
 		cfg.statuses = append(cfg.statuses, http.StatusOK)
 	}
 	return &Channel{
-		url:      url,
-		client:   cfg.client,
-		headers:  cfg.headers,
-		method:   cfg.method,
-		statuses: cfg.statuses,
+		url:             url,
+		client:          cfg.client,
+		headers:         cfg.headers,
+		method:          cfg.method,
+		statuses:        cfg.statuses,
+		receiptStatuses: cfg.receiptStatuses,
 	}
 }
@@ -183,3 +183,42 @@ func setTraceContextPropagator() func() {
 		otel.SetTextMapPropagator(prev)
 	}
 }
+
+func TestChannelAcceptsReceiptStatusCodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("receipts") {
+			w.Header().Set(ReceiptsHeader, "1")
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+
+	endpoint, err := url.Parse(server.URL + "?receipts")
+	if err != nil {
+		t.Fatalf("parsing server URL: %v", err)
+	}
+
+	_, err = NewChannel(endpoint, WithClient(server.Client())).Request(context.Background(), NewRequest(http.NoBody, nil))
+	if err == nil {
+		t.Fatal("expected error without receipt status codes, got nil")
+	}
+
+	channel := NewChannel(endpoint, WithClient(server.Client()), WithReceiptStatusCodes())
+	res, err := channel.Request(context.Background(), NewRequest(http.NoBody, nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { res.Body().Close() })
+	if res.Status() != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, res.Status())
+	}
+
+	endpoint, err = url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parsing server URL: %v", err)
+	}
+	_, err = NewChannel(endpoint, WithClient(server.Client()), WithReceiptStatusCodes()).Request(context.Background(), NewRequest(http.NoBody, nil))
+	if err == nil {
+		t.Fatal("expected error for response without receipts, got nil")
+	}
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/storacha/go-ucanto/transport"
+)
+
+// DefaultMaxBodySize is the maximum size in bytes of a request body accepted
+// by a handler when not configured otherwise.
+const DefaultMaxBodySize int64 = 32 << 20
+
+// ErrorHandlerFunc is called when a handler fails to produce a response. It is
+// responsible for writing a response to the client.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+// HandlerOption is an option configuring a HTTP handler.
+type HandlerOption func(cfg *handlerConfig)
+
+type handlerConfig struct {
+	methods     []string
+	maxBodySize int64
+	catch       ErrorHandlerFunc
+}
+
+// WithAllowedMethods configures the HTTP methods the handler will accept.
+// Requests using any other method receive a 405 Method Not Allowed response.
+func WithAllowedMethods(methods ...string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.methods = methods
+	}
+}
+
+// WithMaxBodySize configures the maximum size in bytes of a request body. A
+// request with a larger body receives a 413 Request Entity Too Large
+// response. A value less than 1 means [DefaultMaxBodySize].
+func WithMaxBodySize(n int64) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.maxBodySize = n
+	}
+}
+
+// WithErrorHandler configures a function to be called when the channel
+// returns an error instead of a response. By default the error is written to
+// stderr and a 500 Internal Server Error response is sent.
+func WithErrorHandler(fn ErrorHandlerFunc) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.catch = fn
+	}
+}
+
+type Handler struct {
+	channel     transport.Channel
+	methods     []string
+	maxBodySize int64
+	catch       ErrorHandlerFunc
+}
+
+// NewHandler creates a [http.Handler] that passes requests to the passed
+// channel (typically a server) and writes the response it returns. The
+// response body is streamed to the client and always closed.
+//
+// By default only POST requests are accepted.
+func NewHandler(channel transport.Channel, options ...HandlerOption) *Handler {
+	cfg := handlerConfig{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	if len(cfg.methods) == 0 {
+		cfg.methods = append(cfg.methods, http.MethodPost)
+	}
+	if cfg.maxBodySize < 1 {
+		cfg.maxBodySize = DefaultMaxBodySize
+	}
+	if cfg.catch == nil {
+		cfg.catch = func(w http.ResponseWriter, r *http.Request, err error) {
+			fmt.Fprintf(os.Stderr, "error: %s %s: %s\n", r.Method, r.URL.Path, err.Error())
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+	return &Handler{
+		channel:     channel,
+		methods:     cfg.methods,
+		maxBodySize: cfg.maxBodySize,
+		catch:       cfg.catch,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if !slices.Contains(h.methods, r.Method) {
+		w.Header().Set("Allow", strings.Join(h.methods, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.ContentLength > h.maxBodySize {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := &maxBytesReader{r: http.MaxBytesReader(w, r.Body, h.maxBodySize)}
+	res, err := h.channel.Request(r.Context(), NewInboundRequest(r.URL, body, r.Header))
+	if body.exceeded {
+		// the body was truncated, so whatever the channel made of it cannot be
+		// trusted.
+		if res != nil && res.Body() != nil {
+			res.Body().Close()
+		}
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		h.catch(w, r, err)
+		return
+	}
+
+	resBody := res.Body()
+	if resBody != nil {
+		defer resBody.Close()
+	}
+
+	for name, values := range res.Headers() {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	status := res.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+
+	if resBody == nil || r.Method == http.MethodHead {
+		return
+	}
+	// Headers have been sent, so a failure here can only be observed by the
+	// client as a truncated body.
+	io.Copy(flushWriter{w}, resBody)
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+// maxBytesReader records whether the underlying [http.MaxBytesReader] limit
+// was hit, since decoders do not reliably wrap read errors.
+type maxBytesReader struct {
+	r        io.Reader
+	exceeded bool
+}
+
+func (mbr *maxBytesReader) Read(p []byte) (int, error) {
+	n, err := mbr.r.Read(p)
+	var mberr *http.MaxBytesError
+	if errors.As(err, &mberr) {
+		mbr.exceeded = true
+	}
+	return n, err
+}
+
+// flushWriter flushes after every write so that streamed response bodies are
+// delivered to the client as they are produced.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/storacha/go-ucanto/transport"
+)
+
+type channelFunc func(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error)
+
+func (fn channelFunc) Request(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
+	return fn(ctx, req)
+}
+
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("copies response", func(t *testing.T) {
+		body := &trackingBody{Reader: strings.NewReader("hello")}
+		var seenURL, seenHeader, seenBody string
+		h := NewHandler(channelFunc(func(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
+			seenURL = req.(transport.InboundHTTPRequest).URL().Path
+			seenHeader = req.Headers().Get("X-Test")
+			b, err := io.ReadAll(req.Body())
+			if err != nil {
+				return nil, err
+			}
+			seenBody = string(b)
+			return NewResponse(http.StatusCreated, body, http.Header{"X-Answer": []string{"a", "b"}}), nil
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/path", strings.NewReader("payload"))
+		req.Header.Set("X-Test", "yes")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d", http.StatusCreated, rec.Code)
+		}
+		if got := rec.Header().Values("X-Answer"); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+			t.Fatalf("unexpected response headers: %v", got)
+		}
+		if rec.Body.String() != "hello" {
+			t.Fatalf("unexpected response body: %q", rec.Body.String())
+		}
+		if !body.closed {
+			t.Fatal("expected response body to be closed")
+		}
+		if seenURL != "/path" || seenHeader != "yes" || seenBody != "payload" {
+			t.Fatalf("unexpected request: %q %q %q", seenURL, seenHeader, seenBody)
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		called := false
+		h := NewHandler(channelFunc(func(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
+			called = true
+			return NewResponse(http.StatusOK, http.NoBody, nil), nil
+		}), WithAllowedMethods(http.MethodGet, http.MethodHead))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+		if rec.Header().Get("Allow") != "GET, HEAD" {
+			t.Fatalf("unexpected Allow header: %q", rec.Header().Get("Allow"))
+		}
+		if called {
+			t.Fatal("expected channel not to be called")
+		}
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		h := NewHandler(channelFunc(func(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
+			// decoders do not necessarily propagate the read error
+			io.ReadAll(req.Body())
+			return NewResponse(http.StatusBadRequest, http.NoBody, nil), nil
+		}), WithMaxBodySize(4))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large"))
+		req.ContentLength = -1 // unknown, so the limit is enforced while reading
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+		}
+
+		req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large"))
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+		}
+	})
+
+	t.Run("head does not write body", func(t *testing.T) {
+		body := &trackingBody{Reader: strings.NewReader("hello")}
+		h := NewHandler(channelFunc(func(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
+			return NewResponse(http.StatusOK, body, nil), nil
+		}), WithAllowedMethods(http.MethodHead))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/", nil))
+
+		if rec.Body.Len() != 0 {
+			t.Fatalf("expected empty body, got %q", rec.Body.String())
+		}
+		if !body.closed {
+			t.Fatal("expected response body to be closed")
+		}
+	})
+
+	t.Run("error handler", func(t *testing.T) {
+		h := NewHandler(channelFunc(func(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
+			return nil, errors.New("boom")
+		}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", http.NoBody))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "boom") {
+			t.Fatal("expected error detail not to be sent to the client")
+		}
+
+		var caught error
+		h = NewHandler(channelFunc(func(ctx context.Context, req transport.HTTPRequest) (transport.HTTPResponse, error) {
+			return nil, errors.New("boom")
+		}), WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			caught = err
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", http.NoBody))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+		if caught == nil || caught.Error() != "boom" {
+			t.Fatalf("unexpected error: %v", caught)
+		}
+	})
+}
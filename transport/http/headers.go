@@ -0,0 +1,13 @@
+package http
+
+const (
+	// ReceiptsHeader is the response header that holds the number of receipts
+	// in the response body. It is set by servers configured with a status
+	// policy, whose responses may have a non-success status even though the
+	// body contains receipts.
+	ReceiptsHeader = "X-Ucanto-Receipts"
+	// FailuresHeader is the response header that summarises the failures in
+	// the receipts in the response body, as a comma separated list of failure
+	// names and counts, for example "Unauthorized=2, Timeout=1".
+	FailuresHeader = "X-Ucanto-Failures"
+)
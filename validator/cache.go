@@ -0,0 +1,190 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal"
+	"github.com/storacha/go-ucanto/principal/verifier"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// Verification is a successful verification of a delegation.
+type Verification struct {
+	// Attestation is the `ucan/attest` authorization of the session the
+	// delegation was issued in, or nil if its signature was verified.
+	Attestation Authorization[any]
+}
+
+// VerificationCache caches successful verifications of delegations, so that
+// the signatures of delegations that are presented repeatedly are not verified
+// every time. Verifications are identified by the CID of the delegation and
+// the DID of the verifier, which is the did:key of the key that verified the
+// signature or, for sessions, the DID of the authority.
+//
+// Only the signature of a delegation or its session attestation is cached. The
+// time bounds of delegations are validated every time they are presented, as
+// are the time bounds and revocation of cached attestations.
+type VerificationCache interface {
+	// Get returns the verification of the delegation by the verifier, or false
+	// if none is cached.
+	Get(ctx context.Context, delegation ucan.Link, verifier did.DID) (Verification, bool, error)
+	// Add caches a successful verification of the delegation by the verifier.
+	Add(ctx context.Context, delegation ucan.Link, verifier did.DID, verification Verification) error
+}
+
+// VerificationCacher is implemented by claim contexts that cache successful
+// verifications of delegations (see [WithVerificationCache]).
+type VerificationCacher interface {
+	// VerificationCache returns the cache of verifications, or nil if
+	// verifications are not cached.
+	VerificationCache() VerificationCache
+}
+
+type cachingValidationContext[Caveats any] struct {
+	ValidationContext[Caveats]
+	cache VerificationCache
+}
+
+func (cvc cachingValidationContext[Caveats]) VerificationCache() VerificationCache {
+	return cvc.cache
+}
+
+// WithVerificationCache returns a validation context that caches successful
+// verifications of delegations in the passed cache. The cache may be shared by
+// validation contexts with the same authority.
+func WithVerificationCache[Caveats any](vctx ValidationContext[Caveats], cache VerificationCache) ValidationContext[Caveats] {
+	return cachingValidationContext[Caveats]{vctx, cache}
+}
+
+func verificationCache(cctx ClaimContext) VerificationCache {
+	if vc, ok := cctx.(VerificationCacher); ok {
+		return vc.VerificationCache()
+	}
+	return nil
+}
+
+// verifierDID returns the DID of the key of the passed verifier.
+func verifierDID(vfr principal.Verifier) did.DID {
+	if u, ok := vfr.(verifier.Unwrapper); ok {
+		return u.Unwrap().DID()
+	}
+	return vfr.DID()
+}
+
+// verifyCachedSignature verifies the delegation was signed by the passed
+// verifier, unless a successful verification is cached by the claim context.
+// Failures of the cache are ignored, and the signature verified instead.
+func verifyCachedSignature(ctx context.Context, dlg delegation.Delegation, vfr principal.Verifier, cctx ClaimContext) (delegation.Delegation, BadSignature) {
+	cache := verificationCache(cctx)
+	if cache == nil {
+		return VerifySignature(dlg, vfr)
+	}
+	key := verifierDID(vfr)
+	if v, ok, err := cache.Get(ctx, dlg.Link(), key); err == nil && ok && v.Attestation == nil {
+		return dlg, nil
+	}
+	dlg, invalid := VerifySignature(dlg, vfr)
+	if invalid == nil {
+		_ = cache.Add(ctx, dlg.Link(), key, Verification{})
+	}
+	return dlg, invalid
+}
+
+// cachedSession returns the cached attestation of the session the delegation
+// was issued in. It is only returned if the attestation is in the passed
+// proofs, and is still within its time bounds and not revoked.
+func cachedSession(ctx context.Context, dlg delegation.Delegation, prfs []delegation.Delegation, cctx ClaimContext) (Authorization[any], bool) {
+	cache := verificationCache(cctx)
+	if cache == nil {
+		return nil, false
+	}
+	v, ok, err := cache.Get(ctx, dlg.Link(), cctx.Authority().DID())
+	if err != nil || !ok || v.Attestation == nil {
+		return nil, false
+	}
+	var included bool
+	for _, p := range prfs {
+		if p.Link().String() == v.Attestation.Delegation().Link().String() {
+			included = true
+			break
+		}
+	}
+	if !included || !withinTimeBounds(v.Attestation, cctx) {
+		return nil, false
+	}
+	if revoked := cctx.ValidateAuthorization(ctx, v.Attestation); revoked != nil {
+		return nil, false
+	}
+	return v.Attestation, true
+}
+
+// cacheSession caches the attestation of the session the delegation was
+// issued in.
+func cacheSession(ctx context.Context, dlg delegation.Delegation, attest Authorization[any], cctx ClaimContext) {
+	if cache := verificationCache(cctx); cache != nil {
+		_ = cache.Add(ctx, dlg.Link(), cctx.Authority().DID(), Verification{Attestation: attest})
+	}
+}
+
+// withinTimeBounds checks the time bounds of every delegation in the passed
+// authorization.
+func withinTimeBounds(auth Authorization[any], cctx ClaimContext) bool {
+	if invalid := cctx.ValidateTimeBounds(auth.Delegation()); invalid != nil {
+		return false
+	}
+	for _, p := range auth.Proofs() {
+		if !withinTimeBounds(p, cctx) {
+			return false
+		}
+	}
+	for _, a := range auth.Attestations() {
+		if !withinTimeBounds(a, cctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// MemoryVerificationCacheSize is the default maximum number of verifications
+// cached by a [MemoryVerificationCache].
+const MemoryVerificationCacheSize = 10_000
+
+// MemoryVerificationCache is an in-memory LRU [VerificationCache].
+type MemoryVerificationCache struct {
+	data *lru.Cache[string, Verification]
+}
+
+func (m *MemoryVerificationCache) Get(ctx context.Context, delegation ucan.Link, verifier did.DID) (Verification, bool, error) {
+	v, ok := m.data.Get(verificationKey(delegation, verifier))
+	return v, ok, nil
+}
+
+func (m *MemoryVerificationCache) Add(ctx context.Context, delegation ucan.Link, verifier did.DID, verification Verification) error {
+	m.data.Add(verificationKey(delegation, verifier), verification)
+	return nil
+}
+
+func verificationKey(delegation ucan.Link, verifier did.DID) string {
+	return delegation.String() + " " + verifier.String()
+}
+
+var _ VerificationCache = (*MemoryVerificationCache)(nil)
+
+// NewMemoryVerificationCache creates a new in-memory LRU cache of successful
+// verifications of delegations. The size parameter controls the maximum number
+// of verifications that can be cached. Pass a value less than 1 to use the
+// default size [MemoryVerificationCacheSize].
+func NewMemoryVerificationCache(size int) (*MemoryVerificationCache, error) {
+	if size <= 0 {
+		size = MemoryVerificationCacheSize
+	}
+	cache, err := lru.New[string, Verification](size)
+	if err != nil {
+		return nil, fmt.Errorf("creating verification LRU: %w", err)
+	}
+	return &MemoryVerificationCache{data: cache}, nil
+}
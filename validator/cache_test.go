@@ -0,0 +1,131 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/principal"
+	"github.com/storacha/go-ucanto/principal/absentee"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan/crypto/signature"
+	"github.com/stretchr/testify/require"
+)
+
+// countingVerifier counts the signatures it verifies.
+type countingVerifier struct {
+	principal.Verifier
+	count *int
+}
+
+func (cv countingVerifier) Verify(msg []byte, sig signature.Signature) bool {
+	*cv.count++
+	return cv.Verifier.Verify(msg, sig)
+}
+
+func TestVerificationCache(t *testing.T) {
+	t.Run("caches signature verifications", func(t *testing.T) {
+		cache := helpers.Must(NewMemoryVerificationCache(0))
+		var verified int
+		var expired bool
+		dlg := helpers.Must(storeAdd.Delegate(fixtures.Alice, fixtures.Bob, fixtures.Alice.DID().String(), storeAddCaveats{}))
+
+		authorize := func(t *testing.T) Unauthorized {
+			inv := helpers.Must(storeAdd.Invoke(fixtures.Bob, fixtures.Service, fixtures.Alice.DID().String(), storeAddCaveats{Link: helpers.RandomCID()}, delegation.WithProof(delegation.FromDelegation(dlg))))
+			vctx := NewValidationContext(
+				fixtures.Service.Verifier(),
+				storeAdd,
+				IsSelfIssued,
+				validateAuthOk,
+				ProofUnavailable,
+				func(str string) (principal.Verifier, error) {
+					vfr, err := parseEdPrincipal(str)
+					if err != nil {
+						return nil, err
+					}
+					return countingVerifier{vfr, &verified}, nil
+				},
+				FailDIDKeyResolution,
+				func(d delegation.Delegation) InvalidProof {
+					if expired && d.Link() == dlg.Link() {
+						return NewExpiredError(d)
+					}
+					return NotExpiredNotTooEarly(d)
+				},
+			)
+			_, err := Access(t.Context(), inv, WithVerificationCache(vctx, cache))
+			return err
+		}
+
+		require.Nil(t, authorize(t))
+		require.Equal(t, 2, verified)
+
+		// only the new invocation is verified
+		require.Nil(t, authorize(t))
+		require.Equal(t, 3, verified)
+
+		v, ok, err := cache.Get(t.Context(), dlg.Link(), fixtures.Alice.DID())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Nil(t, v.Attestation)
+
+		// time bounds are validated for cached verifications
+		expired = true
+		err = authorize(t)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "has expired")
+	})
+
+	t.Run("caches session attestations", func(t *testing.T) {
+		cache := helpers.Must(NewMemoryVerificationCache(0))
+		var revoked bool
+		agent := fixtures.Alice
+		account := absentee.From(helpers.Must(did.Parse("did:mailto:web.mail:alice")))
+		prf := helpers.Must(debugEcho.Delegate(account, agent, account.DID().String(), debugEchoCaveats{}))
+		session := helpers.Must(attest.Delegate(service, agent, service.DID().String(), attestCaveats{Proof: prf.Link()}))
+
+		authorize := func(t *testing.T, proofs ...delegation.Delegation) Unauthorized {
+			var prfs []delegation.Proof
+			for _, p := range proofs {
+				prfs = append(prfs, delegation.FromDelegation(p))
+			}
+			inv := helpers.Must(debugEcho.Invoke(agent, service, account.DID().String(), debugEchoCaveats{}, delegation.WithProof(prfs...)))
+			vctx := NewValidationContext(
+				service.Verifier(),
+				debugEcho,
+				IsSelfIssued,
+				func(ctx context.Context, auth Authorization[any]) Revoked {
+					if revoked && auth.Delegation().Link() == session.Link() {
+						return NewRevokedError(session)
+					}
+					return nil
+				},
+				ProofUnavailable,
+				parseEdPrincipal,
+				FailDIDKeyResolution,
+				NotExpiredNotTooEarly,
+			)
+			_, err := Access(t.Context(), inv, WithVerificationCache(vctx, cache))
+			return err
+		}
+
+		require.Nil(t, authorize(t, prf, session))
+
+		v, ok, err := cache.Get(t.Context(), prf.Link(), service.DID())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.NotNil(t, v.Attestation)
+		require.Equal(t, session.Link(), v.Attestation.Delegation().Link())
+
+		require.Nil(t, authorize(t, prf, session))
+
+		// the attestation must still be presented
+		require.NotNil(t, authorize(t, prf))
+
+		// and must not be revoked
+		revoked = true
+		require.NotNil(t, authorize(t, prf, session))
+	})
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	ipldschema "github.com/ipld/go-ipld-prime/schema"
 	"github.com/storacha/go-ucanto/core/delegation"
 	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
 	"github.com/storacha/go-ucanto/core/result/failure"
 	"github.com/storacha/go-ucanto/core/schema"
 	"github.com/storacha/go-ucanto/ucan"
@@ -121,6 +125,41 @@ type CapabilityParser[Caveats any] interface {
 	Invoke(issuer ucan.Signer, audience ucan.Principal, with ucan.Resource, nb Caveats, options ...delegation.Option) (invocation.IssuedInvocation, error)
 }
 
+// Describer is implemented by capability parsers that describe the capability
+// they parse, including the readers used to parse its resource and caveats.
+// Parsers created by [NewCapability], [Or] and [And] implement it.
+type Describer[Caveats any] interface {
+	Descriptor() Descriptor[Caveats]
+}
+
+// describe returns the descriptor of the passed parser, or a descriptor whose
+// readers reject all input and that derives nothing if it does not implement
+// [Describer].
+func describe[Caveats any](parser CapabilityParser[Caveats]) Descriptor[Caveats] {
+	if d, ok := parser.(Describer[Caveats]); ok {
+		return d.Descriptor()
+	}
+	err := schema.NewSchemaError(fmt.Sprintf("capability %s is not described", parser.Can()))
+	return descriptor[Caveats]{
+		can:  parser.Can(),
+		with: undescribed[string, ucan.Resource]{err},
+		nb:   undescribed[any, Caveats]{err},
+		derives: func(claimed, delegated ucan.Capability[Caveats]) failure.Failure {
+			return err
+		},
+	}
+}
+
+// undescribed is a reader that rejects all input.
+type undescribed[I, O any] struct {
+	err failure.Failure
+}
+
+func (u undescribed[I, O]) Read(input I) (O, failure.Failure) {
+	var o O
+	return o, u.err
+}
+
 type Derivable[Caveats any] interface {
 	// Derives determines if a capability is derivable from another. Return `nil`
 	// to indicate the delegated capability can be derived from the claimed
@@ -163,14 +202,55 @@ func (d descriptor[C]) Derives(parent, child ucan.Capability[C]) failure.Failure
 	return d.derives(parent, child)
 }
 
+// ResultTyped is implemented by capabilities that declare the schema types of
+// the results of their invocations (see [WithResultTypes]).
+type ResultTyped interface {
+	// OkType is the schema type of a successful result, nil if not declared.
+	OkType() ipldschema.Type
+	// ErrorType is the schema type of a failure result, nil if not declared.
+	ErrorType() ipldschema.Type
+}
+
+// CapabilityOption is an option configuring a capability.
+type CapabilityOption func(cfg *capabilityConfig)
+
+type capabilityConfig struct {
+	okType    ipldschema.Type
+	errorType ipldschema.Type
+}
+
+// WithResultTypes declares the schema types of the results of invocations of
+// the capability. Either type may be nil if it is not declared. Servers
+// validate the results of their handlers against declared types.
+func WithResultTypes(okType, errorType ipldschema.Type) CapabilityOption {
+	return func(cfg *capabilityConfig) {
+		cfg.okType = okType
+		cfg.errorType = errorType
+	}
+}
+
 type capability[Caveats any] struct {
 	descriptor Descriptor[Caveats]
+	okType     ipldschema.Type
+	errorType  ipldschema.Type
 }
 
 func (c capability[Caveats]) Can() ucan.Ability {
 	return c.descriptor.Can()
 }
 
+func (c capability[Caveats]) Descriptor() Descriptor[Caveats] {
+	return c.descriptor
+}
+
+func (c capability[Caveats]) OkType() ipldschema.Type {
+	return c.okType
+}
+
+func (c capability[Caveats]) ErrorType() ipldschema.Type {
+	return c.errorType
+}
+
 func (c capability[Caveats]) Select(capabilities []Source) ([]Match[Caveats], []DelegationError, []ucan.Capability[any]) {
 	return Select(c, capabilities)
 }
@@ -212,12 +292,17 @@ func NewCapability[Caveats any](
 	with schema.Reader[string, ucan.Resource],
 	nb schema.Reader[any, Caveats],
 	derives DerivesFunc[Caveats],
+	options ...CapabilityOption,
 ) CapabilityParser[Caveats] {
+	cfg := capabilityConfig{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
 	if derives == nil {
 		derives = DefaultDerives
 	}
 	d := descriptor[Caveats]{can, with, nb, derives}
-	return &capability[Caveats]{descriptor: d}
+	return &capability[Caveats]{descriptor: d, okType: cfg.okType, errorType: cfg.errorType}
 }
 
 func ParseCapability[O any](descriptor Descriptor[O], source Source) (ucan.Capability[O], InvalidCapability) {
@@ -262,8 +347,12 @@ func Select[Caveats any](matcher Matcher[Caveats], capabilities []Source) (match
 // capability using provided capability `parser`. It is similar to
 // [ParseCapability] except `source` here is treated as capability pattern which
 // is matched against the `claimed` capability. This means we resolve `can` and
-// `with` fields from the `claimed` capability and...
-// TODO: inherit all missing `nb` fields from the claimed capability.
+// `with` fields from the `claimed` capability and inherit all `nb` fields
+// missing from the delegated capability from the `claimed` capability. Fields
+// present in both keep their delegated value, so that they can be compared
+// with the claimed caveats when deriving (see [CaveatDerives]). If the caveats
+// cannot be merged, or the merged caveats cannot be read by the parser, the
+// delegated capability is malformed.
 func ResolveCapability[Caveats any](descriptor Descriptor[Caveats], claimed ucan.Capability[Caveats], source Source) (ucan.Capability[Caveats], InvalidCapability) {
 	can := ResolveAbility(source.Capability().Can(), claimed.Can())
 	if can == "" {
@@ -280,8 +369,11 @@ func ResolveCapability[Caveats any](descriptor Descriptor[Caveats], claimed ucan
 		return nil, NewMalformedCapabilityError(source.Capability(), err)
 	}
 
-	// TODO: inherit missing fields
-	nb, err := descriptor.Nb().Read(claimed.Nb())
+	merged, cerr := inheritCaveats(claimed.Nb(), source.Capability().Nb())
+	if cerr != nil {
+		return nil, NewMalformedCapabilityError(source.Capability(), cerr)
+	}
+	nb, err := descriptor.Nb().Read(merged)
 	if err != nil {
 		return nil, NewMalformedCapabilityError(source.Capability(), err)
 	}
@@ -289,6 +381,74 @@ func ResolveCapability[Caveats any](descriptor Descriptor[Caveats], claimed ucan
 	return ucan.NewCapability(can, uri, nb), nil
 }
 
+// inheritCaveats returns the delegated caveats with any fields missing from
+// them inherited from the claimed caveats, like `{...claimed.nb,
+// ...delegated.nb}`. Fields present in both keep their delegated value, so that
+// they can be compared with the claimed caveats when deriving. The claimed
+// caveats are returned if the delegated caveats do not constrain any fields.
+func inheritCaveats(claimed any, delegated any) (any, error) {
+	dnb, err := caveatsNode(delegated)
+	if err != nil {
+		return nil, fmt.Errorf("delegated caveats: %w", err)
+	}
+	if isAbsent(dnb) || dnb.Kind() != datamodel.Kind_Map || dnb.Length() == 0 {
+		return claimed, nil
+	}
+	cnb, err := caveatsNode(claimed)
+	if err != nil {
+		return nil, fmt.Errorf("claimed caveats: %w", err)
+	}
+	if isAbsent(cnb) {
+		return dnb, nil
+	}
+	if cnb.Kind() != datamodel.Kind_Map {
+		return nil, fmt.Errorf("claimed caveats: expected a map, got %s", cnb.Kind())
+	}
+
+	nb := basicnode.Prototype.Map.NewBuilder()
+	ma, err := nb.BeginMap(-1)
+	if err != nil {
+		return nil, err
+	}
+	copyEntries := func(n ipld.Node, skip func(key string) bool) error {
+		it := n.MapIterator()
+		for !it.Done() {
+			k, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			key, err := k.AsString()
+			if err != nil {
+				return err
+			}
+			if v.IsAbsent() || skip(key) {
+				continue
+			}
+			if err := ma.AssembleKey().AssignString(key); err != nil {
+				return err
+			}
+			if err := ma.AssembleValue().AssignNode(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	delegatedHas := func(key string) bool {
+		v, err := dnb.LookupByString(key)
+		return err == nil && !v.IsAbsent()
+	}
+	if err := copyEntries(cnb, delegatedHas); err != nil {
+		return nil, fmt.Errorf("claimed caveats: %w", err)
+	}
+	if err := copyEntries(dnb, func(string) bool { return false }); err != nil {
+		return nil, fmt.Errorf("delegated caveats: %w", err)
+	}
+	if err := ma.Finish(); err != nil {
+		return nil, err
+	}
+	return nb.Build(), nil
+}
+
 // ResolveAbility resolves ability `pattern` of the delegated capability from
 // the ability of the claimed capability. If pattern matches returns claimed
 // ability otherwise returns "".
@@ -324,6 +484,10 @@ func ResolveResource(source string, uri ucan.Resource) ucan.Resource {
 	return ""
 }
 
+// DefaultDerives checks that the claimed resource is the delegated resource, or
+// matches it if the delegated resource ends with "*". Caveats are not checked,
+// use [CaveatDerives] to also check that claimed caveats are within delegated
+// caveats.
 func DefaultDerives[Caveats any](claimed, delegated ucan.Capability[Caveats]) failure.Failure {
 	dres := delegated.With()
 	cres := claimed.With()
@@ -336,6 +500,5 @@ func DefaultDerives[Caveats any](claimed, delegated ucan.Capability[Caveats]) fa
 		return schema.NewSchemaError(fmt.Sprintf("Resource %s is not contained by %s", cres, dres))
 	}
 
-	// TODO: is it possible to ensure claimed caveats match delegated caveats?
 	return nil
 }
@@ -0,0 +1,146 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+// nodeCaveats are caveats that are an IPLD node.
+type nodeCaveats struct {
+	nd ipld.Node
+}
+
+func (c nodeCaveats) ToIPLD() (ipld.Node, error) {
+	return c.nd, nil
+}
+
+func TestResolveCapability(t *testing.T) {
+	link := helpers.RandomCID()
+	origin := helpers.RandomCID()
+	claimed := ucan.NewCapability("store/add", fixtures.Alice.DID().String(), storeAddCaveats{Link: link, Origin: origin})
+
+	resolve := func(t *testing.T, nb ipld.Node) (ucan.Capability[storeAddCaveats], InvalidCapability) {
+		dlg := helpers.Must(delegation.Delegate(
+			fixtures.Alice,
+			fixtures.Bob,
+			[]ucan.Capability[nodeCaveats]{ucan.NewCapability("store/add", fixtures.Alice.DID().String(), nodeCaveats{nb})},
+		))
+		return ResolveCapability(storeAdd.(Describer[storeAddCaveats]).Descriptor(), claimed, NewSource(dlg.Capabilities()[0], dlg))
+	}
+
+	t.Run("no delegated caveats", func(t *testing.T) {
+		cap, err := resolve(t, helpers.Must(ucan.NoCaveats{}.ToIPLD()))
+		require.Nil(t, err)
+		require.Equal(t, claimed.Nb(), cap.Nb())
+	})
+
+	t.Run("inherits missing caveats", func(t *testing.T) {
+		cap, err := resolve(t, buildMap(t, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "link", qp.Link(link))
+		}))
+		require.Nil(t, err)
+		require.Equal(t, link, cap.Nb().Link)
+		require.Equal(t, origin, cap.Nb().Origin)
+	})
+
+	t.Run("delegated values", func(t *testing.T) {
+		other := helpers.RandomCID()
+		cap, err := resolve(t, buildMap(t, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "link", qp.Link(other))
+		}))
+		require.Nil(t, err)
+		// values are compared when deriving, not when resolving
+		require.Equal(t, other, cap.Nb().Link)
+		require.Equal(t, origin, cap.Nb().Origin)
+	})
+
+	t.Run("conflicting caveats", func(t *testing.T) {
+		_, err := resolve(t, buildMap(t, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "link", qp.String("not a link"))
+		}))
+		require.NotNil(t, err)
+		_, ok := err.(MalformedCapability)
+		require.True(t, ok)
+	})
+
+	t.Run("claimed caveats not comparable", func(t *testing.T) {
+		_, err := inheritCaveats(struct{}{}, buildMap(t, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "link", qp.Link(link))
+		}))
+		require.ErrorContains(t, err, "claimed caveats")
+	})
+}
+
+func TestAccessNarrowedCaveats(t *testing.T) {
+	storeAdd := NewCapability(
+		storeAdd.Can(),
+		storeAdd.(Describer[storeAddCaveats]).Descriptor().With(),
+		storeAdd.(Describer[storeAddCaveats]).Descriptor().Nb(),
+		CaveatDerives[storeAddCaveats](),
+	)
+
+	link := helpers.RandomCID()
+	origin := helpers.RandomCID()
+
+	// each hop narrows a different caveat
+	bob := helpers.Must(delegation.Delegate(
+		fixtures.Alice,
+		fixtures.Bob,
+		[]ucan.Capability[nodeCaveats]{ucan.NewCapability("store/add", fixtures.Alice.DID().String(), nodeCaveats{buildMap(t, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "link", qp.Link(link))
+		})})},
+	))
+	mallory := helpers.Must(delegation.Delegate(
+		fixtures.Bob,
+		fixtures.Mallory,
+		[]ucan.Capability[nodeCaveats]{ucan.NewCapability("store/add", fixtures.Alice.DID().String(), nodeCaveats{buildMap(t, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "origin", qp.Link(origin))
+		})})},
+		delegation.WithProof(delegation.FromDelegation(bob)),
+	))
+
+	authorize := func(t *testing.T, nb storeAddCaveats) Unauthorized {
+		inv := helpers.Must(storeAdd.Invoke(fixtures.Mallory, fixtures.Service, fixtures.Alice.DID().String(), nb, delegation.WithProof(delegation.FromDelegation(mallory))))
+		vctx := NewValidationContext(
+			fixtures.Service.Verifier(),
+			storeAdd,
+			IsSelfIssued,
+			validateAuthOk,
+			ProofUnavailable,
+			parseEdPrincipal,
+			FailDIDKeyResolution,
+			NotExpiredNotTooEarly,
+		)
+		_, err := Access(t.Context(), inv, vctx)
+		return err
+	}
+
+	t.Run("within caveats of every hop", func(t *testing.T) {
+		require.Nil(t, authorize(t, storeAddCaveats{Link: link, Origin: origin}))
+	})
+
+	t.Run("escalates caveats of first hop", func(t *testing.T) {
+		err := authorize(t, storeAddCaveats{Link: helpers.RandomCID(), Origin: origin})
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "nb.link")
+	})
+
+	t.Run("escalates caveats of second hop", func(t *testing.T) {
+		err := authorize(t, storeAddCaveats{Link: link, Origin: helpers.RandomCID()})
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "nb.origin")
+	})
+
+	t.Run("omits constrained caveat", func(t *testing.T) {
+		err := authorize(t, storeAddCaveats{Link: link})
+		require.NotNil(t, err)
+	})
+}
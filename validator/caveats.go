@@ -0,0 +1,250 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// CaveatRule checks that a claimed caveat is within the delegated caveat of the
+// same field. The claimed caveat is nil if it is absent. Return `nil` to
+// indicate the claimed caveat is within the delegated caveat.
+type CaveatRule func(claimed, delegated ipld.Node) failure.Failure
+
+// CaveatsOption is an option configuring how caveats are compared by
+// [CaveatDerives] and [CheckCaveats].
+type CaveatsOption func(cfg *caveatsConfig)
+
+type caveatsConfig struct {
+	rules map[string]CaveatRule
+}
+
+// WithCaveatRule configures the rule used to compare the caveat at the passed
+// path, instead of the default comparison. The path is the names of the fields
+// from the root of the caveats separated by ".", for example "size" or
+// "blob.size". The rule is only called if the field is present in the
+// delegated caveats.
+func WithCaveatRule(path string, rule CaveatRule) CaveatsOption {
+	return func(cfg *caveatsConfig) {
+		if cfg.rules == nil {
+			cfg.rules = map[string]CaveatRule{}
+		}
+		cfg.rules[path] = rule
+	}
+}
+
+// MaxCaveat is a [CaveatRule] for numeric caveats, where the delegated caveat
+// is a ceiling that the claimed caveat must not exceed.
+func MaxCaveat(claimed, delegated ipld.Node) failure.Failure {
+	if claimed == nil {
+		return schema.NewSchemaError(fmt.Sprintf("missing, but delegated with a maximum of %s", formatNode(delegated)))
+	}
+	var within bool
+	switch {
+	case claimed.Kind() == datamodel.Kind_Int && delegated.Kind() == datamodel.Kind_Int:
+		c, _ := claimed.AsInt()
+		d, _ := delegated.AsInt()
+		within = c <= d
+	case isNumber(claimed) && isNumber(delegated):
+		within = asFloat(claimed) <= asFloat(delegated)
+	default:
+		return schema.NewSchemaError(fmt.Sprintf("%s and %s are not both numbers", formatNode(claimed), formatNode(delegated)))
+	}
+	if !within {
+		return schema.NewSchemaError(fmt.Sprintf("%s exceeds the maximum of %s", formatNode(claimed), formatNode(delegated)))
+	}
+	return nil
+}
+
+// PrefixCaveat is a [CaveatRule] for string caveats, where the claimed caveat
+// must start with the delegated caveat, for example to restrict paths or keys
+// to a namespace.
+func PrefixCaveat(claimed, delegated ipld.Node) failure.Failure {
+	if claimed == nil {
+		return schema.NewSchemaError(fmt.Sprintf("missing, but delegated with prefix %s", formatNode(delegated)))
+	}
+	c, cerr := claimed.AsString()
+	d, derr := delegated.AsString()
+	if cerr != nil || derr != nil {
+		return schema.NewSchemaError(fmt.Sprintf("%s and %s are not both strings", formatNode(claimed), formatNode(delegated)))
+	}
+	if !strings.HasPrefix(c, d) {
+		return schema.NewSchemaError(fmt.Sprintf("%s does not have prefix %s", formatNode(claimed), formatNode(delegated)))
+	}
+	return nil
+}
+
+// CaveatDerives creates a derives function for [NewCapability] that checks the
+// resource in the same way as [DefaultDerives], and that the claimed caveats
+// are within the delegated caveats (see [CheckCaveats]). The caveats must be
+// an IPLD node or implement [ipld.Builder].
+func CaveatDerives[Caveats any](options ...CaveatsOption) DerivesFunc[Caveats] {
+	return func(claimed, delegated ucan.Capability[Caveats]) failure.Failure {
+		if err := DefaultDerives(claimed, delegated); err != nil {
+			return err
+		}
+		cnb, err := caveatsNode(claimed.Nb())
+		if err != nil {
+			return schema.NewSchemaError(err.Error())
+		}
+		dnb, err := caveatsNode(delegated.Nb())
+		if err != nil {
+			return schema.NewSchemaError(err.Error())
+		}
+		return CheckCaveats(cnb, dnb, options...)
+	}
+}
+
+// CheckCaveats checks that the claimed caveats are within the delegated
+// caveats. By default:
+//
+//   - Fields absent from the delegated caveats are unconstrained.
+//   - Maps are compared field by field.
+//   - Delegated lists are sets of allowed values: a claimed list must only
+//     contain values in the delegated list and any other claimed value must be
+//     in it.
+//   - Any other value must be equal to the delegated value.
+//
+// Rules configured with [WithCaveatRule] replace the default comparison for
+// the fields they apply to.
+func CheckCaveats(claimed, delegated ipld.Node, options ...CaveatsOption) failure.Failure {
+	cfg := caveatsConfig{}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return checkCaveat(nil, claimed, delegated, cfg)
+}
+
+func checkCaveat(path []string, claimed, delegated ipld.Node, cfg caveatsConfig) failure.Failure {
+	if isAbsent(delegated) {
+		return nil
+	}
+	if isAbsent(claimed) {
+		claimed = nil
+	}
+	if rule, ok := cfg.rules[strings.Join(path, ".")]; ok {
+		if err := rule(claimed, delegated); err != nil {
+			return schema.NewSchemaError(fmt.Sprintf("%s: %s", caveatPath(path), err.Error()))
+		}
+		return nil
+	}
+	if claimed == nil {
+		return schema.NewSchemaError(fmt.Sprintf("%s: missing, but delegated as %s", caveatPath(path), formatNode(delegated)))
+	}
+
+	switch delegated.Kind() {
+	case datamodel.Kind_Map:
+		if claimed.Kind() != datamodel.Kind_Map {
+			return violation(path, claimed, delegated)
+		}
+		it := delegated.MapIterator()
+		for !it.Done() {
+			k, dv, err := it.Next()
+			if err != nil {
+				return schema.NewSchemaError(fmt.Sprintf("%s: iterating delegated caveats: %s", caveatPath(path), err.Error()))
+			}
+			key, err := k.AsString()
+			if err != nil {
+				return schema.NewSchemaError(fmt.Sprintf("%s: delegated caveats have a non-string key", caveatPath(path)))
+			}
+			cv, err := claimed.LookupByString(key)
+			if err != nil {
+				cv = nil
+			}
+			if err := checkCaveat(append(path[:len(path):len(path)], key), cv, dv, cfg); err != nil {
+				return err
+			}
+		}
+		return nil
+	case datamodel.Kind_List:
+		if claimed.Kind() != datamodel.Kind_List {
+			if !listContains(delegated, claimed) {
+				return violation(path, claimed, delegated)
+			}
+			return nil
+		}
+		it := claimed.ListIterator()
+		for !it.Done() {
+			_, cv, err := it.Next()
+			if err != nil {
+				return schema.NewSchemaError(fmt.Sprintf("%s: iterating claimed caveats: %s", caveatPath(path), err.Error()))
+			}
+			if !listContains(delegated, cv) {
+				return violation(path, claimed, delegated)
+			}
+		}
+		return nil
+	default:
+		if !datamodel.DeepEqual(claimed, delegated) {
+			return violation(path, claimed, delegated)
+		}
+		return nil
+	}
+}
+
+func violation(path []string, claimed, delegated ipld.Node) failure.Failure {
+	return schema.NewSchemaError(fmt.Sprintf("%s: %s violates %s", caveatPath(path), formatNode(claimed), formatNode(delegated)))
+}
+
+func caveatPath(path []string) string {
+	return strings.Join(append([]string{"nb"}, path...), ".")
+}
+
+func listContains(list, value ipld.Node) bool {
+	it := list.ListIterator()
+	for !it.Done() {
+		_, v, err := it.Next()
+		if err != nil {
+			return false
+		}
+		if datamodel.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAbsent(n ipld.Node) bool {
+	return n == nil || n.IsAbsent()
+}
+
+func isNumber(n ipld.Node) bool {
+	return n.Kind() == datamodel.Kind_Int || n.Kind() == datamodel.Kind_Float
+}
+
+func asFloat(n ipld.Node) float64 {
+	if n.Kind() == datamodel.Kind_Int {
+		i, _ := n.AsInt()
+		return float64(i)
+	}
+	f, _ := n.AsFloat()
+	return f
+}
+
+// caveatsNode returns the IPLD node of the passed caveats.
+func caveatsNode(nb any) (ipld.Node, error) {
+	switch nb := nb.(type) {
+	case nil:
+		return nil, nil
+	case ipld.Node:
+		return nb, nil
+	case ipld.Builder:
+		return nb.ToIPLD()
+	}
+	return nil, fmt.Errorf("caveats of type %T cannot be compared", nb)
+}
+
+// formatNode formats a node as DAG-JSON for error messages.
+func formatNode(n ipld.Node) string {
+	var sb strings.Builder
+	if err := dagjson.Encode(n, &sb); err != nil {
+		return n.Kind().String()
+	}
+	return sb.String()
+}
@@ -0,0 +1,361 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+func buildMap(t *testing.T, fn func(ma datamodel.MapAssembler)) ipld.Node {
+	t.Helper()
+	return helpers.Must(qp.BuildMap(basicnode.Prototype.Any, -1, fn))
+}
+
+func TestCheckCaveats(t *testing.T) {
+	link := helpers.RandomCID()
+	other := helpers.RandomCID()
+
+	testCases := []struct {
+		name      string
+		claimed   func(ma datamodel.MapAssembler)
+		delegated func(ma datamodel.MapAssembler)
+		options   []CaveatsOption
+		err       string
+	}{
+		{
+			name: "unconstrained",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "root", qp.Link(link))
+			},
+			delegated: func(ma datamodel.MapAssembler) {},
+		},
+		{
+			name: "equal link",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "root", qp.Link(link))
+				qp.MapEntry(ma, "size", qp.Int(1))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "root", qp.Link(link))
+			},
+		},
+		{
+			name: "different link",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "root", qp.Link(other))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "root", qp.Link(link))
+			},
+			err: "nb.root: ",
+		},
+		{
+			name:    "missing field",
+			claimed: func(ma datamodel.MapAssembler) {},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "root", qp.Link(link))
+			},
+			err: "nb.root: missing",
+		},
+		{
+			name: "nested map",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "blob", qp.Map(-1, func(ma datamodel.MapAssembler) {
+					qp.MapEntry(ma, "digest", qp.Bytes([]byte{1}))
+					qp.MapEntry(ma, "size", qp.Int(2))
+				}))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "blob", qp.Map(-1, func(ma datamodel.MapAssembler) {
+					qp.MapEntry(ma, "size", qp.Int(1))
+				}))
+			},
+			err: "nb.blob.size: 2 violates 1",
+		},
+		{
+			name: "list subset",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "tags", qp.List(-1, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.String("b"))
+				}))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "tags", qp.List(-1, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.String("a"))
+					qp.ListEntry(la, qp.String("b"))
+				}))
+			},
+		},
+		{
+			name: "list not subset",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "tags", qp.List(-1, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.String("b"))
+					qp.ListEntry(la, qp.String("c"))
+				}))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "tags", qp.List(-1, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.String("a"))
+					qp.ListEntry(la, qp.String("b"))
+				}))
+			},
+			err: "nb.tags: ",
+		},
+		{
+			name: "value in list",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "region", qp.String("eu"))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "region", qp.List(-1, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.String("us"))
+					qp.ListEntry(la, qp.String("eu"))
+				}))
+			},
+		},
+		{
+			name: "value not in list",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "region", qp.String("ap"))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "region", qp.List(-1, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.String("us"))
+				}))
+			},
+			err: `nb.region: "ap" violates ["us"]`,
+		},
+		{
+			name: "within maximum",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "size", qp.Int(100))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "size", qp.Int(100))
+			},
+			options: []CaveatsOption{WithCaveatRule("size", MaxCaveat)},
+		},
+		{
+			name: "exceeds maximum",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "size", qp.Int(101))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "size", qp.Int(100))
+			},
+			options: []CaveatsOption{WithCaveatRule("size", MaxCaveat)},
+			err:     "nb.size: 101 exceeds the maximum of 100",
+		},
+		{
+			name:    "missing maximum",
+			claimed: func(ma datamodel.MapAssembler) {},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "size", qp.Int(100))
+			},
+			options: []CaveatsOption{WithCaveatRule("size", MaxCaveat)},
+			err:     "nb.size: missing",
+		},
+		{
+			name: "has prefix",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "path", qp.String("/photos/cat.jpg"))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "path", qp.String("/photos/"))
+			},
+			options: []CaveatsOption{WithCaveatRule("path", PrefixCaveat)},
+		},
+		{
+			name: "does not have prefix",
+			claimed: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "path", qp.String("/docs/cv.pdf"))
+			},
+			delegated: func(ma datamodel.MapAssembler) {
+				qp.MapEntry(ma, "path", qp.String("/photos/"))
+			},
+			options: []CaveatsOption{WithCaveatRule("path", PrefixCaveat)},
+			err:     `nb.path: "/docs/cv.pdf" does not have prefix "/photos/"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckCaveats(buildMap(t, tc.claimed), buildMap(t, tc.delegated), tc.options...)
+			if tc.err == "" {
+				require.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestCaveatDerives(t *testing.T) {
+	storeAdd := NewCapability(
+		"store/add",
+		schema.DIDString(),
+		schema.Struct[storeAddCaveats](storeAddTyp.TypeByName("StoreAddCaveats"), nil),
+		CaveatDerives[storeAddCaveats](),
+	)
+
+	link := helpers.RandomCID()
+	dlg := helpers.Must(storeAdd.Delegate(
+		fixtures.Alice,
+		fixtures.Bob,
+		fixtures.Alice.DID().String(),
+		storeAddCaveats{Link: link},
+	))
+
+	authorize := func(t *testing.T, nb storeAddCaveats) (Authorization[storeAddCaveats], Unauthorized) {
+		inv := helpers.Must(storeAdd.Invoke(fixtures.Bob, fixtures.Service, fixtures.Alice.DID().String(), nb, delegation.WithProof(delegation.FromDelegation(dlg))))
+		vctx := NewValidationContext(
+			fixtures.Service.Verifier(),
+			storeAdd,
+			IsSelfIssued,
+			validateAuthOk,
+			ProofUnavailable,
+			parseEdPrincipal,
+			FailDIDKeyResolution,
+			NotExpiredNotTooEarly,
+		)
+		return Access(t.Context(), inv, vctx)
+	}
+
+	t.Run("within delegated caveats", func(t *testing.T) {
+		auth, err := authorize(t, storeAddCaveats{Link: link, Origin: helpers.RandomCID()})
+		require.Nil(t, err)
+		require.Equal(t, link, auth.Capability().Nb().Link)
+	})
+
+	t.Run("escalates delegated caveats", func(t *testing.T) {
+		_, err := authorize(t, storeAddCaveats{Link: helpers.RandomCID()})
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "nb.link")
+	})
+
+	t.Run("different resource", func(t *testing.T) {
+		err := CaveatDerives[storeAddCaveats]()(
+			ucan.NewCapability("store/add", fixtures.Bob.DID().String(), storeAddCaveats{Link: link}),
+			ucan.NewCapability("store/add", fixtures.Alice.DID().String(), storeAddCaveats{Link: link}),
+		)
+		require.NotNil(t, err)
+	})
+}
+
+type objectCaveats struct {
+	Size *int64
+	Path *string
+	Tags []string
+}
+
+func (c objectCaveats) ToIPLD() (ipld.Node, error) {
+	return qp.BuildMap(basicnode.Prototype.Any, -1, func(ma datamodel.MapAssembler) {
+		if c.Size != nil {
+			qp.MapEntry(ma, "size", qp.Int(*c.Size))
+		}
+		if c.Path != nil {
+			qp.MapEntry(ma, "path", qp.String(*c.Path))
+		}
+		if c.Tags != nil {
+			qp.MapEntry(ma, "tags", qp.List(-1, func(la datamodel.ListAssembler) {
+				for _, tag := range c.Tags {
+					qp.ListEntry(la, qp.String(tag))
+				}
+			}))
+		}
+	})
+}
+
+var objectTyp = helpers.Must(ipld.LoadSchemaBytes([]byte(`
+	type ObjectCaveats struct {
+		size optional Int
+		path optional String
+		tags optional [String]
+	}
+`)))
+
+func TestCaveatRulesAccess(t *testing.T) {
+	objectPut := NewCapability(
+		"object/put",
+		schema.DIDString(),
+		schema.Struct[objectCaveats](objectTyp.TypeByName("ObjectCaveats"), nil),
+		CaveatDerives[objectCaveats](WithCaveatRule("size", MaxCaveat), WithCaveatRule("path", PrefixCaveat)),
+	)
+	size := func(n int64) *int64 { return &n }
+	path := func(s string) *string { return &s }
+
+	space := fixtures.Alice.DID().String()
+	bob := helpers.Must(objectPut.Delegate(fixtures.Alice, fixtures.Bob, space, objectCaveats{
+		Size: size(100),
+		Path: path("photos/"),
+		Tags: []string{"cat", "dog"},
+	}))
+	// the second hop narrows the size further
+	mallory := helpers.Must(objectPut.Delegate(fixtures.Bob, fixtures.Mallory, space, objectCaveats{
+		Size: size(80),
+	}, delegation.WithProof(delegation.FromDelegation(bob))))
+
+	authorize := func(t *testing.T, issuer ucan.Signer, prf delegation.Delegation, nb objectCaveats) Unauthorized {
+		inv := helpers.Must(objectPut.Invoke(issuer, fixtures.Service, space, nb, delegation.WithProof(delegation.FromDelegation(prf))))
+		vctx := NewValidationContext(
+			fixtures.Service.Verifier(),
+			objectPut,
+			IsSelfIssued,
+			validateAuthOk,
+			ProofUnavailable,
+			parseEdPrincipal,
+			FailDIDKeyResolution,
+			NotExpiredNotTooEarly,
+		)
+		_, err := Access(t.Context(), inv, vctx)
+		return err
+	}
+
+	within := objectCaveats{Size: size(50), Path: path("photos/cat.jpg"), Tags: []string{"cat"}}
+
+	t.Run("within every rule", func(t *testing.T) {
+		require.Nil(t, authorize(t, fixtures.Bob, bob, within))
+	})
+
+	t.Run("equal to delegated caveats", func(t *testing.T) {
+		require.Nil(t, authorize(t, fixtures.Bob, bob, objectCaveats{Size: size(100), Path: path("photos/"), Tags: []string{"cat", "dog"}}))
+	})
+
+	testCases := []struct {
+		name  string
+		nb    objectCaveats
+		field string
+	}{
+		{"exceeds maximum", objectCaveats{Size: size(150), Path: within.Path, Tags: within.Tags}, "nb.size"},
+		{"outside prefix", objectCaveats{Size: within.Size, Path: path("docs/cat.jpg"), Tags: within.Tags}, "nb.path"},
+		{"not in allowed set", objectCaveats{Size: within.Size, Path: within.Path, Tags: []string{"cat", "bird"}}, "nb.tags"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := authorize(t, fixtures.Bob, bob, tc.nb)
+			require.NotNil(t, err)
+			require.Contains(t, err.Error(), tc.field)
+		})
+	}
+
+	t.Run("within every hop", func(t *testing.T) {
+		require.Nil(t, authorize(t, fixtures.Mallory, mallory, objectCaveats{Size: size(70), Path: within.Path, Tags: within.Tags}))
+	})
+
+	t.Run("exceeds maximum of second hop", func(t *testing.T) {
+		err := authorize(t, fixtures.Mallory, mallory, objectCaveats{Size: size(90), Path: within.Path, Tags: within.Tags})
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "nb.size")
+	})
+}
@@ -0,0 +1,565 @@
+package validator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/did"
+	"github.com/storacha/go-ucanto/ucan"
+)
+
+// ConvertUnknownCapability converts a capability parser to a parser of
+// capabilities with caveats of unknown type, so that parsers of capabilities
+// with different caveats can be combined with [Or] and [And]. The caveats of
+// the parsed capabilities are of the type of the passed parser.
+func ConvertUnknownCapability[Caveats any](parser CapabilityParser[Caveats]) CapabilityParser[any] {
+	if p, ok := any(parser).(CapabilityParser[any]); ok {
+		return p
+	}
+	return unknowncap[Caveats]{parser}
+}
+
+type unknowncap[Caveats any] struct {
+	parser CapabilityParser[Caveats]
+}
+
+func (c unknowncap[Caveats]) Can() ucan.Ability {
+	return c.parser.Can()
+}
+
+func (c unknowncap[Caveats]) Descriptor() Descriptor[any] {
+	d := describe(c.parser)
+	return descriptor[any]{
+		can:  d.Can(),
+		with: d.With(),
+		nb: schema.Mapped(d.Nb(), func(nb Caveats) (any, failure.Failure) {
+			return nb, nil
+		}),
+		derives: func(claimed, delegated ucan.Capability[any]) failure.Failure {
+			cnb, cok := claimed.Nb().(Caveats)
+			dnb, dok := delegated.Nb().(Caveats)
+			if !cok || !dok {
+				return schema.NewSchemaError(fmt.Sprintf("unexpected caveats of type %T and %T", claimed.Nb(), delegated.Nb()))
+			}
+			return d.Derives(
+				ucan.NewCapability(claimed.Can(), claimed.With(), cnb),
+				ucan.NewCapability(delegated.Can(), delegated.With(), dnb),
+			)
+		},
+	}
+}
+
+func (c unknowncap[Caveats]) Match(source Source) (Match[any], InvalidCapability) {
+	m, err := c.parser.Match(source)
+	if err != nil {
+		return nil, err
+	}
+	return unknownmatch[Caveats]{m}, nil
+}
+
+func (c unknowncap[Caveats]) Select(sources []Source) ([]Match[any], []DelegationError, []ucan.Capability[any]) {
+	matches, errors, unknowns := c.parser.Select(sources)
+	return convertUnknownMatches(matches), errors, unknowns
+}
+
+func (c unknowncap[Caveats]) New(with ucan.Resource, nb any) ucan.Capability[any] {
+	return ucan.NewCapability(c.Can(), with, nb)
+}
+
+func (c unknowncap[Caveats]) Delegate(issuer ucan.Signer, audience ucan.Principal, with ucan.Resource, nb any, options ...delegation.Option) (delegation.Delegation, error) {
+	cnb, ok := nb.(Caveats)
+	if !ok {
+		return nil, fmt.Errorf("unexpected caveats of type %T", nb)
+	}
+	return c.parser.Delegate(issuer, audience, with, cnb, options...)
+}
+
+func (c unknowncap[Caveats]) Invoke(issuer ucan.Signer, audience ucan.Principal, with ucan.Resource, nb any, options ...delegation.Option) (invocation.IssuedInvocation, error) {
+	cnb, ok := nb.(Caveats)
+	if !ok {
+		return nil, fmt.Errorf("unexpected caveats of type %T", nb)
+	}
+	return c.parser.Invoke(issuer, audience, with, cnb, options...)
+}
+
+func (c unknowncap[Caveats]) String() string {
+	return fmt.Sprint(c.parser)
+}
+
+type unknownmatch[Caveats any] struct {
+	match Match[Caveats]
+}
+
+func (m unknownmatch[Caveats]) Source() []Source {
+	return m.match.Source()
+}
+
+func (m unknownmatch[Caveats]) Value() ucan.Capability[any] {
+	v := m.match.Value()
+	return ucan.NewCapability[any](v.Can(), v.With(), v.Nb())
+}
+
+func (m unknownmatch[Caveats]) Proofs() []delegation.Delegation {
+	return m.match.Proofs()
+}
+
+func (m unknownmatch[Caveats]) Prune(context CanIssuer[any]) Match[any] {
+	pruned := m.match.Prune(canissuer[Caveats]{canIssue: context.CanIssue})
+	if pruned == nil {
+		return nil
+	}
+	return unknownmatch[Caveats]{pruned}
+}
+
+func (m unknownmatch[Caveats]) Select(sources []Source) ([]Match[any], []DelegationError, []ucan.Capability[any]) {
+	matches, errors, unknowns := m.match.Select(sources)
+	return convertUnknownMatches(matches), errors, unknowns
+}
+
+func (m unknownmatch[Caveats]) String() string {
+	return fmt.Sprint(m.match)
+}
+
+func convertUnknownMatches[Caveats any](matches []Match[Caveats]) []Match[any] {
+	converted := make([]Match[any], 0, len(matches))
+	for _, m := range matches {
+		converted = append(converted, unknownmatch[Caveats]{m})
+	}
+	return converted
+}
+
+// Or creates a parser of capabilities that are parsed by any of the passed
+// parsers, for example to accept a legacy ability alongside the ability that
+// replaced it. The parsers are tried in order and a capability is proven with
+// the parser that matched it, so that its proofs are only derived from
+// capabilities of that parser. Use [ConvertUnknownCapability] to combine
+// parsers of capabilities with different caveats.
+//
+// The ability of the parser is the abilities of the alternatives separated by
+// "|", so a service method provided with it must be registered for each of
+// the alternative abilities. New, Delegate and Invoke create capabilities of
+// the first alternative.
+func Or[Caveats any](parsers ...CapabilityParser[Caveats]) CapabilityParser[Caveats] {
+	return or[Caveats]{parsers}
+}
+
+type or[Caveats any] struct {
+	parsers []CapabilityParser[Caveats]
+}
+
+func (o or[Caveats]) Can() ucan.Ability {
+	abilities := make([]string, 0, len(o.parsers))
+	for _, p := range o.parsers {
+		abilities = append(abilities, p.Can())
+	}
+	return strings.Join(abilities, "|")
+}
+
+func (o or[Caveats]) Descriptor() Descriptor[Caveats] {
+	withs := make([]schema.Reader[string, ucan.Resource], 0, len(o.parsers))
+	nbs := make([]schema.Reader[any, Caveats], 0, len(o.parsers))
+	for _, p := range o.parsers {
+		withs = append(withs, describe(p).With())
+		nbs = append(nbs, describe(p).Nb())
+	}
+	return descriptor[Caveats]{o.Can(), schema.Or(withs...), schema.Or(nbs...), o.derives}
+}
+
+// derives derives the capability with the parser of its ability.
+func (o or[Caveats]) derives(claimed, delegated ucan.Capability[Caveats]) failure.Failure {
+	for _, p := range o.parsers {
+		if p.Can() == claimed.Can() {
+			return describe(p).Derives(claimed, delegated)
+		}
+	}
+	return describe(o.parsers[0]).Derives(claimed, delegated)
+}
+
+func (o or[Caveats]) Match(source Source) (Match[Caveats], InvalidCapability) {
+	var err InvalidCapability
+	for _, p := range o.parsers {
+		m, perr := p.Match(source)
+		if perr == nil {
+			return m, nil
+		}
+		// prefer reporting why the capability is malformed over it being unknown
+		if _, ok := err.(UnknownCapability); err == nil || ok {
+			err = perr
+		}
+	}
+	if err == nil {
+		err = NewUnknownCapabilityError(source.Capability())
+	}
+	return nil, err
+}
+
+func (o or[Caveats]) Select(sources []Source) ([]Match[Caveats], []DelegationError, []ucan.Capability[any]) {
+	return Select(o, sources)
+}
+
+func (o or[Caveats]) New(with ucan.Resource, nb Caveats) ucan.Capability[Caveats] {
+	return o.parsers[0].New(with, nb)
+}
+
+func (o or[Caveats]) Delegate(issuer ucan.Signer, audience ucan.Principal, with ucan.Resource, nb Caveats, options ...delegation.Option) (delegation.Delegation, error) {
+	return o.parsers[0].Delegate(issuer, audience, with, nb, options...)
+}
+
+func (o or[Caveats]) Invoke(issuer ucan.Signer, audience ucan.Principal, with ucan.Resource, nb Caveats, options ...delegation.Option) (invocation.IssuedInvocation, error) {
+	return o.parsers[0].Invoke(issuer, audience, with, nb, options...)
+}
+
+func (o or[Caveats]) String() string {
+	parts := make([]string, 0, len(o.parsers))
+	for _, p := range o.parsers {
+		parts = append(parts, fmt.Sprint(p))
+	}
+	return strings.Join(parts, "|")
+}
+
+// Group is the caveats of a capability group claimed with [And]. It holds the
+// capabilities of the members of the group in the order of their parsers.
+type Group []ucan.Capability[any]
+
+// GroupAbility returns the ability of a group of capabilities with the passed
+// abilities: the abilities in lexical order separated by "&", so that it does
+// not depend on the order of the parsers of the group or of the capabilities
+// of an invocation of it.
+func GroupAbility(abilities ...ucan.Ability) ucan.Ability {
+	sorted := slices.Clone(abilities)
+	slices.Sort(sorted)
+	return strings.Join(sorted, "&")
+}
+
+// GroupDerivesFunc determines if a capability group can be derived from a
+// single delegated capability. Return `nil` to indicate the group can be
+// derived from the delegated capability.
+type GroupDerivesFunc func(claimed ucan.Capability[Group], delegated ucan.Capability[any]) failure.Failure
+
+// GroupParser is a parser of capability groups created with [And].
+type GroupParser interface {
+	CapabilityParser[Group]
+	// Derive returns a parser of the group that can also be derived from a
+	// single delegated capability parsed by `from`, if `derives` allows it. The
+	// delegated capability is then proven as a group of one.
+	Derive(from CapabilityParser[any], derives GroupDerivesFunc) GroupParser
+}
+
+// And creates a parser of a group of capabilities on the same resource that
+// must all be proven, for example an invocation of both `store/add` and
+// `upload/add`. A single capability only matches the group if it matches all
+// of its members. Use [ConvertUnknownCapability] to create the parsers of the
+// members.
+//
+// The members may be proven by different capabilities, or by one capability
+// that all of them can be derived from, such as a wildcard, but at each step
+// of the proof chain they must be proven by the same delegation. To derive the
+// group from a capability of a different ability use [GroupParser.Derive].
+//
+// The capability of the group has the ability of its members (see
+// [GroupAbility]), the resource of its members and the [Group] of their
+// capabilities as caveats. A service method provided with it is registered for
+// that ability, for example "store/add&upload/add", and handles invocations of
+// all of the capabilities of the group, in any order.
+func And(parsers ...CapabilityParser[any]) GroupParser {
+	return group{parsers: parsers}
+}
+
+type groupDerive struct {
+	from    CapabilityParser[any]
+	derives GroupDerivesFunc
+}
+
+type group struct {
+	parsers []CapabilityParser[any]
+	derive  *groupDerive
+}
+
+func (g group) Derive(from CapabilityParser[any], derives GroupDerivesFunc) GroupParser {
+	return group{g.parsers, &groupDerive{from, derives}}
+}
+
+func (g group) Can() ucan.Ability {
+	abilities := make([]ucan.Ability, 0, len(g.parsers))
+	for _, p := range g.parsers {
+		abilities = append(abilities, p.Can())
+	}
+	return GroupAbility(abilities...)
+}
+
+// Descriptor describes the group. The resource is read with the reader of the
+// first member.
+func (g group) Descriptor() Descriptor[Group] {
+	return descriptor[Group]{g.Can(), describe(g.parsers[0]).With(), groupReader{}, g.derives}
+}
+
+// derives derives each member of the group with the parser of the member.
+func (g group) derives(claimed, delegated ucan.Capability[Group]) failure.Failure {
+	if len(claimed.Nb()) != len(g.parsers) || len(delegated.Nb()) != len(g.parsers) {
+		return schema.NewSchemaError(fmt.Sprintf("expected a group of %d capabilities", len(g.parsers)))
+	}
+	for i, p := range g.parsers {
+		if err := describe(p).Derives(claimed.Nb()[i], delegated.Nb()[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g group) Match(source Source) (Match[Group], InvalidCapability) {
+	matches := make([]Match[any], 0, len(g.parsers))
+	for _, p := range g.parsers {
+		m, err := p.Match(source)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return groupMatch{matches, g.derive}, nil
+}
+
+func (g group) Select(sources []Source) (matches []Match[Group], errors []DelegationError, unknowns []ucan.Capability[any]) {
+	selected := make([][]Match[any], 0, len(g.parsers))
+	for i, p := range g.parsers {
+		ms, errs, unks := p.Select(sources)
+		selected = append(selected, ms)
+		errors = append(errors, errs...)
+		if i == 0 {
+			unknowns = unks
+		} else {
+			unknowns = intersectCapabilities(unknowns, unks)
+		}
+	}
+	return combineGroup(selected, g.derive), errors, unknowns
+}
+
+func (g group) New(with ucan.Resource, nb Group) ucan.Capability[Group] {
+	return ucan.NewCapability(g.Can(), with, nb)
+}
+
+func (g group) Delegate(issuer ucan.Signer, audience ucan.Principal, with ucan.Resource, nb Group, options ...delegation.Option) (delegation.Delegation, error) {
+	caps, err := g.capabilities(with, nb)
+	if err != nil {
+		return nil, err
+	}
+	return delegation.Delegate(issuer, audience, caps, options...)
+}
+
+func (g group) Invoke(issuer ucan.Signer, audience ucan.Principal, with ucan.Resource, nb Group, options ...delegation.Option) (invocation.IssuedInvocation, error) {
+	caps, err := g.capabilities(with, nb)
+	if err != nil {
+		return nil, err
+	}
+	return invocation.InvokeCapabilities(issuer, audience, caps, options...)
+}
+
+// capabilities returns the capabilities of the members of the group for
+// delegation or invocation.
+func (g group) capabilities(with ucan.Resource, nb Group) ([]ucan.Capability[ucan.CaveatBuilder], error) {
+	if len(nb) != len(g.parsers) {
+		return nil, fmt.Errorf("expected a group of %d capabilities, got %d", len(g.parsers), len(nb))
+	}
+	caps := make([]ucan.Capability[ucan.CaveatBuilder], 0, len(nb))
+	for _, c := range nb {
+		if c.With() != with {
+			return nil, fmt.Errorf("capability %s is on resource %s, not %s", c.Can(), c.With(), with)
+		}
+		bc, ok := c.Nb().(ucan.CaveatBuilder)
+		if !ok {
+			return nil, fmt.Errorf("not an IPLD builder: %v", c.Nb())
+		}
+		caps = append(caps, ucan.NewCapability(c.Can(), c.With(), bc))
+	}
+	return caps, nil
+}
+
+func (g group) String() string {
+	parts := make([]string, 0, len(g.parsers))
+	for _, p := range g.parsers {
+		parts = append(parts, fmt.Sprint(p))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(parts, ", "))
+}
+
+type groupReader struct{}
+
+func (groupReader) Read(input any) (Group, failure.Failure) {
+	if g, ok := input.(Group); ok {
+		return g, nil
+	}
+	return nil, schema.NewSchemaError(fmt.Sprintf("expected a capability group, got %T", input))
+}
+
+type groupMatch struct {
+	matches []Match[any]
+	derive  *groupDerive
+}
+
+func (m groupMatch) Source() []Source {
+	var sources []Source
+	for _, member := range m.matches {
+		sources = append(sources, member.Source()...)
+	}
+	return sources
+}
+
+func (m groupMatch) Value() ucan.Capability[Group] {
+	caps := make(Group, 0, len(m.matches))
+	for _, member := range m.matches {
+		caps = append(caps, member.Value())
+	}
+	return groupCapability(caps)
+}
+
+func (m groupMatch) Proofs() []delegation.Delegation {
+	var proofs []delegation.Delegation
+	includes := map[string]struct{}{}
+	for _, member := range m.matches {
+		for _, p := range member.Proofs() {
+			if _, ok := includes[p.Link().String()]; !ok {
+				includes[p.Link().String()] = struct{}{}
+				proofs = append(proofs, p)
+			}
+		}
+	}
+	return proofs
+}
+
+// Prune removes the members of the group that can be issued, returning nil if
+// all of them can.
+func (m groupMatch) Prune(context CanIssuer[Group]) Match[Group] {
+	issuer := memberIssuer{context}
+	var matches []Match[any]
+	for _, member := range m.matches {
+		if pruned := member.Prune(issuer); pruned != nil {
+			matches = append(matches, pruned)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	return groupMatch{matches, m.derive}
+}
+
+func (m groupMatch) Select(sources []Source) (matches []Match[Group], errors []DelegationError, unknowns []ucan.Capability[any]) {
+	selected := make([][]Match[any], 0, len(m.matches))
+	for i, member := range m.matches {
+		ms, errs, unks := member.Select(sources)
+		selected = append(selected, ms)
+		errors = append(errors, errs...)
+		if i == 0 {
+			unknowns = unks
+		} else {
+			unknowns = intersectCapabilities(unknowns, unks)
+		}
+	}
+	matches = combineGroup(selected, m.derive)
+
+	if m.derive != nil {
+		ms, errs, unks := m.derive.from.Select(sources)
+		errors = append(errors, errs...)
+		unknowns = intersectCapabilities(unknowns, unks)
+		claimed := m.Value()
+		for _, dm := range ms {
+			if err := m.derive.derives(claimed, dm.Value()); err != nil {
+				delegated := groupCapability(Group{dm.Value()})
+				errors = append(errors, NewDelegationError([]DelegationSubError{NewEscalatedCapabilityError(claimed, delegated, err)}, m))
+				continue
+			}
+			matches = append(matches, groupMatch{matches: []Match[any]{dm}})
+		}
+	}
+	return
+}
+
+func (m groupMatch) String() string {
+	parts := make([]string, 0, len(m.matches))
+	for _, member := range m.matches {
+		parts = append(parts, fmt.Sprint(member))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(parts, ", "))
+}
+
+// memberIssuer informs the validator whether a member of a group can be
+// issued.
+type memberIssuer struct {
+	context CanIssuer[Group]
+}
+
+func (mi memberIssuer) CanIssue(capability ucan.Capability[any], issuer did.DID) bool {
+	if ci, ok := mi.context.(canissuer[Group]); ok {
+		return ci.canIssue(capability, issuer)
+	}
+	return mi.context.CanIssue(groupCapability(Group{capability}), issuer)
+}
+
+func groupCapability(caps Group) ucan.Capability[Group] {
+	abilities := make([]ucan.Ability, 0, len(caps))
+	for _, c := range caps {
+		abilities = append(abilities, c.Can())
+	}
+	return ucan.NewCapability(GroupAbility(abilities...), caps[0].With(), caps)
+}
+
+// combineGroup combines the matches of each member of a group into matches of
+// the group. Members are only combined if they are matched in the same
+// delegation and on the same resource.
+func combineGroup(selected [][]Match[any], derive *groupDerive) []Match[Group] {
+	if len(selected) == 0 {
+		return nil
+	}
+	var matches []Match[Group]
+	seen := map[string]struct{}{}
+	for _, first := range selected[0] {
+		dlg := first.Source()[0].Delegation().Link().String()
+		if _, ok := seen[dlg]; ok {
+			continue
+		}
+		seen[dlg] = struct{}{}
+
+		combos := [][]Match[any]{nil}
+		for _, ms := range selected {
+			var next [][]Match[any]
+			for _, combo := range combos {
+				for _, m := range ms {
+					if m.Source()[0].Delegation().Link().String() != dlg {
+						continue
+					}
+					if len(combo) > 0 && combo[0].Value().With() != m.Value().With() {
+						continue
+					}
+					next = append(next, append(combo[:len(combo):len(combo)], m))
+				}
+			}
+			combos = next
+		}
+		for _, combo := range combos {
+			matches = append(matches, groupMatch{combo, derive})
+		}
+	}
+	return matches
+}
+
+// intersectCapabilities returns the capabilities of a that are also in b.
+func intersectCapabilities(a, b []ucan.Capability[any]) []ucan.Capability[any] {
+	var capabilities []ucan.Capability[any]
+	for _, ca := range a {
+		ja, _ := ca.MarshalJSON()
+		for _, cb := range b {
+			if jb, _ := cb.MarshalJSON(); string(ja) == string(jb) {
+				capabilities = append(capabilities, ca)
+				break
+			}
+		}
+	}
+	return capabilities
+}
+
+var _ Describer[any] = unknowncap[any]{}
+var _ Describer[any] = or[any]{}
+var _ Describer[Group] = group{}
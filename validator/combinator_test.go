@@ -0,0 +1,242 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/result/failure"
+	"github.com/storacha/go-ucanto/core/schema"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/storacha/go-ucanto/testing/helpers"
+	"github.com/storacha/go-ucanto/ucan"
+	"github.com/stretchr/testify/require"
+)
+
+var noCaveatsTyp = helpers.Must(ipld.LoadSchemaBytes([]byte(`
+	type NoCaveats struct {}
+`)))
+
+func TestOr(t *testing.T) {
+	blobAdd := NewCapability(
+		"blob/add",
+		schema.DIDString(),
+		schema.Struct[storeAddCaveats](storeAddTyp.TypeByName("StoreAddCaveats"), nil),
+		nil,
+	)
+	add := Or(blobAdd, storeAdd)
+
+	authorize := func(t *testing.T, capability CapabilityParser[storeAddCaveats], issuer ucan.Signer, proofs ...delegation.Delegation) (Authorization[storeAddCaveats], Unauthorized) {
+		var prfs []delegation.Proof
+		for _, p := range proofs {
+			prfs = append(prfs, delegation.FromDelegation(p))
+		}
+		nb := storeAddCaveats{Link: helpers.RandomCID()}
+		inv := helpers.Must(capability.Invoke(issuer, fixtures.Service, fixtures.Alice.DID().String(), nb, delegation.WithProof(prfs...)))
+		vctx := NewValidationContext(
+			fixtures.Service.Verifier(),
+			add,
+			IsSelfIssued,
+			validateAuthOk,
+			ProofUnavailable,
+			parseEdPrincipal,
+			FailDIDKeyResolution,
+			NotExpiredNotTooEarly,
+		)
+		return Access(t.Context(), inv, vctx)
+	}
+
+	t.Run("first alternative", func(t *testing.T) {
+		auth, err := authorize(t, blobAdd, fixtures.Alice)
+		require.Nil(t, err)
+		require.Equal(t, "blob/add", auth.Capability().Can())
+	})
+
+	t.Run("second alternative", func(t *testing.T) {
+		dlg := helpers.Must(storeAdd.Delegate(fixtures.Alice, fixtures.Bob, fixtures.Alice.DID().String(), storeAddCaveats{}))
+		auth, err := authorize(t, storeAdd, fixtures.Bob, dlg)
+		require.Nil(t, err)
+		require.Equal(t, "store/add", auth.Capability().Can())
+	})
+
+	t.Run("not derived from other alternative", func(t *testing.T) {
+		dlg := helpers.Must(blobAdd.Delegate(fixtures.Alice, fixtures.Bob, fixtures.Alice.DID().String(), storeAddCaveats{}))
+		_, err := authorize(t, storeAdd, fixtures.Bob, dlg)
+		require.NotNil(t, err)
+	})
+
+	t.Run("unknown capability", func(t *testing.T) {
+		pin := NewCapability(
+			"store/pin",
+			schema.DIDString(),
+			schema.Struct[storeAddCaveats](storeAddTyp.TypeByName("StoreAddCaveats"), nil),
+			nil,
+		)
+		_, err := authorize(t, pin, fixtures.Alice)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), `Claim {can:"blob/add"}|{can:"store/add"} is not authorized`)
+		require.Len(t, err.UnknownCapabilities(), 1)
+	})
+
+	t.Run("malformed capability", func(t *testing.T) {
+		cap := ucan.NewCapability("store/add", fixtures.Alice.DID().String(), ucan.NoCaveats{})
+		dlg := helpers.Must(delegation.Delegate(fixtures.Alice, fixtures.Service, []ucan.Capability[ucan.NoCaveats]{cap}))
+		_, err := add.Match(NewSource(dlg.Capabilities()[0], dlg))
+		require.NotNil(t, err)
+		_, ok := err.(MalformedCapability)
+		require.True(t, ok)
+	})
+
+	t.Run("different caveats", func(t *testing.T) {
+		pin := NewCapability(
+			"store/pin",
+			schema.DIDString(),
+			schema.Struct[ucan.NoCaveats](noCaveatsTyp.TypeByName("NoCaveats"), nil),
+			nil,
+		)
+		add := Or(ConvertUnknownCapability(storeAdd), ConvertUnknownCapability(pin))
+
+		inv := helpers.Must(pin.Invoke(fixtures.Alice, fixtures.Service, fixtures.Alice.DID().String(), ucan.NoCaveats{}))
+		vctx := NewValidationContext(
+			fixtures.Service.Verifier(),
+			add,
+			IsSelfIssued,
+			validateAuthOk,
+			ProofUnavailable,
+			parseEdPrincipal,
+			FailDIDKeyResolution,
+			NotExpiredNotTooEarly,
+		)
+		auth, err := Access(t.Context(), inv, vctx)
+		require.Nil(t, err)
+		require.Equal(t, "store/pin", auth.Capability().Can())
+		require.IsType(t, ucan.NoCaveats{}, auth.Capability().Nb())
+	})
+}
+
+func TestAnd(t *testing.T) {
+	uploadAdd := NewCapability(
+		"upload/add",
+		schema.DIDString(),
+		schema.Struct[ucan.NoCaveats](noCaveatsTyp.TypeByName("NoCaveats"), nil),
+		nil,
+	)
+	spaceWrite := NewCapability(
+		"space/write",
+		schema.DIDString(),
+		schema.Struct[ucan.NoCaveats](noCaveatsTyp.TypeByName("NoCaveats"), nil),
+		nil,
+	)
+	add := And(ConvertUnknownCapability(storeAdd), ConvertUnknownCapability(uploadAdd))
+
+	space := fixtures.Alice.DID().String()
+	link := helpers.RandomCID()
+	nb := Group{
+		ucan.NewCapability[any]("store/add", space, storeAddCaveats{Link: link}),
+		ucan.NewCapability[any]("upload/add", space, ucan.NoCaveats{}),
+	}
+
+	delegate := func(t *testing.T, abilities ...string) delegation.Delegation {
+		var caps []ucan.Capability[ucan.NoCaveats]
+		for _, can := range abilities {
+			caps = append(caps, ucan.NewCapability(can, space, ucan.NoCaveats{}))
+		}
+		return helpers.Must(delegation.Delegate(fixtures.Alice, fixtures.Bob, caps))
+	}
+
+	authorize := func(t *testing.T, capability GroupParser, issuer ucan.Signer, proofs ...delegation.Delegation) (Authorization[Group], Unauthorized) {
+		var prfs []delegation.Proof
+		for _, p := range proofs {
+			prfs = append(prfs, delegation.FromDelegation(p))
+		}
+		inv := helpers.Must(capability.Invoke(issuer, fixtures.Service, space, nb, delegation.WithProof(prfs...)))
+		vctx := NewValidationContext(
+			fixtures.Service.Verifier(),
+			capability,
+			IsSelfIssued,
+			validateAuthOk,
+			ProofUnavailable,
+			parseEdPrincipal,
+			FailDIDKeyResolution,
+			NotExpiredNotTooEarly,
+		)
+		return Access(t.Context(), inv, vctx)
+	}
+
+	t.Run("self issued", func(t *testing.T) {
+		auth, err := authorize(t, add, fixtures.Alice)
+		require.Nil(t, err)
+		require.Equal(t, "store/add&upload/add", auth.Capability().Can())
+		require.Equal(t, space, auth.Capability().With())
+		require.Len(t, auth.Capability().Nb(), 2)
+		require.Equal(t, link, auth.Capability().Nb()[0].Nb().(storeAddCaveats).Link)
+	})
+
+	t.Run("delegated together", func(t *testing.T) {
+		auth, err := authorize(t, add, fixtures.Bob, delegate(t, "store/add", "upload/add"))
+		require.Nil(t, err)
+		require.Len(t, auth.Proofs(), 1)
+	})
+
+	t.Run("delegated wildcard", func(t *testing.T) {
+		_, err := authorize(t, add, fixtures.Bob, delegate(t, "*"))
+		require.Nil(t, err)
+	})
+
+	t.Run("member not delegated", func(t *testing.T) {
+		_, err := authorize(t, add, fixtures.Bob, delegate(t, "store/add"))
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), `Claim [{can:"store/add"}, {can:"upload/add"}] is not authorized`)
+	})
+
+	t.Run("delegated separately", func(t *testing.T) {
+		_, err := authorize(t, add, fixtures.Bob, delegate(t, "store/add"), delegate(t, "upload/add"))
+		require.NotNil(t, err)
+	})
+
+	t.Run("escalated member", func(t *testing.T) {
+		dlg := helpers.Must(delegation.Delegate(
+			fixtures.Alice,
+			fixtures.Bob,
+			[]ucan.Capability[ucan.CaveatBuilder]{
+				ucan.NewCapability[ucan.CaveatBuilder]("store/add", space, storeAddCaveats{Link: helpers.RandomCID()}),
+				ucan.NewCapability[ucan.CaveatBuilder]("upload/add", space, ucan.NoCaveats{}),
+			},
+		))
+		_, err := authorize(t, add, fixtures.Bob, dlg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "Constraint violation")
+	})
+
+	t.Run("derived from delegated capability", func(t *testing.T) {
+		_, err := authorize(t, add, fixtures.Bob, delegate(t, "space/write"))
+		require.NotNil(t, err)
+
+		derived := add.Derive(ConvertUnknownCapability(spaceWrite), func(claimed ucan.Capability[Group], delegated ucan.Capability[any]) failure.Failure {
+			return DefaultDerives(ucan.NewCapability[any](claimed.Can(), claimed.With(), claimed.Nb()), delegated)
+		})
+		auth, err := authorize(t, derived, fixtures.Bob, delegate(t, "space/write"))
+		require.Nil(t, err)
+		require.Equal(t, "space/write", auth.Proofs()[0].Capability().Can())
+	})
+
+	t.Run("derivation denied", func(t *testing.T) {
+		derived := add.Derive(ConvertUnknownCapability(spaceWrite), func(claimed ucan.Capability[Group], delegated ucan.Capability[any]) failure.Failure {
+			return schema.NewSchemaError("not allowed")
+		})
+		_, err := authorize(t, derived, fixtures.Bob, delegate(t, "space/write"))
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "not allowed")
+	})
+
+	t.Run("single capability does not match", func(t *testing.T) {
+		dlg := delegate(t, "store/add")
+		_, err := add.Match(NewSource(dlg.Capabilities()[0], dlg))
+		require.NotNil(t, err)
+	})
+
+	t.Run("resource mismatch", func(t *testing.T) {
+		_, err := add.Invoke(fixtures.Alice, fixtures.Service, fixtures.Bob.DID().String(), nb)
+		require.Error(t, err)
+	})
+}
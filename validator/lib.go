@@ -19,6 +19,7 @@ import (
 	"github.com/ucan-wg/go-ucan/capability/policy"
 	"github.com/ucan-wg/go-ucan/capability/policy/literal"
 	"github.com/ucan-wg/go-ucan/capability/policy/selector"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func IsSelfIssued[Caveats any](capability ucan.Capability[Caveats], issuer did.DID) bool {
@@ -247,8 +248,20 @@ func (vc validationContext[Caveats]) Capability() CapabilityParser[Caveats] {
 // valid path is found [Unauthorized] error is returned detailing all explored
 // paths and where they proved to fail.
 func Access[Caveats any](ctx context.Context, invocation invocation.Invocation, vctx ValidationContext[Caveats]) (Authorization[Caveats], Unauthorized) {
+	ctx, span := tracer.Start(ctx, "ucanto.validator.Access", trace.WithAttributes(
+		abilityKey.String(vctx.Capability().Can()),
+		issuerKey.String(invocation.Issuer().DID().String()),
+		delegationKey.String(invocation.Link().String()),
+	))
+	defer span.End()
+
 	prf := []delegation.Proof{delegation.FromDelegation(invocation)}
-	return Claim(ctx, vctx.Capability(), prf, vctx)
+	auth, err := Claim(ctx, vctx.Capability(), prf, vctx)
+	if err != nil {
+		recordFailure(span, err)
+		return nil, err
+	}
+	return auth, nil
 }
 
 // Claim attempts to find a valid proof chain for the claimed [CapabilityParser]
@@ -327,11 +340,15 @@ func ResolveProofs(ctx context.Context, proofs []delegation.Proof, resolver Proo
 		if ok {
 			dels = append(dels, d)
 		} else {
+			_, span := tracer.Start(ctx, "ucanto.validator.ResolveProof", trace.WithAttributes(proofKey.String(p.Link().String())))
 			d, err := resolver.ResolveProof(ctx, p.Link())
 			if err != nil {
+				recordFailure(span, err)
+				span.End()
 				errs = append(errs, err)
 				continue
 			}
+			span.End()
 			dels = append(dels, d)
 		}
 	}
@@ -358,6 +375,10 @@ func Validate(ctx context.Context, dlg delegation.Delegation, prfs []delegation.
 // found falls back to resolving did:key for the issuer and verifying its
 // signature. The second return value is the ucan/attest Authorization when one
 // was used, or nil otherwise.
+//
+// If the claim context caches verifications (see [VerificationCacher]), cached
+// signature verifications and session attestations are used instead of
+// verifying them again.
 func VerifyAuthorization(ctx context.Context, dlg delegation.Delegation, prfs []delegation.Delegation, cctx ClaimContext) (delegation.Delegation, Authorization[any], InvalidProof) {
 	issuer := dlg.Issuer().DID()
 	// If the issuer is a did:key we just verify a signature
@@ -366,15 +387,19 @@ func VerifyAuthorization(ctx context.Context, dlg delegation.Delegation, prfs []
 		if err != nil {
 			return nil, nil, NewUnverifiableSignatureError(dlg, err)
 		}
-		dlg, invalid := VerifySignature(dlg, vfr)
+		dlg, invalid := verifyCachedSignature(ctx, dlg, vfr, cctx)
 		return dlg, nil, invalid
 	}
 
 	if dlg.Issuer().DID() == cctx.Authority().DID() {
-		dlg, invalid := VerifySignature(dlg, cctx.Authority())
+		dlg, invalid := verifyCachedSignature(ctx, dlg, cctx.Authority(), cctx)
 		return dlg, nil, invalid
 	}
 
+	if attest, ok := cachedSession(ctx, dlg, prfs, cctx); ok {
+		return dlg, attest, nil
+	}
+
 	// If issuer is not a did:key principal nor configured authority, we
 	// attempt to resolve embedded authorization session from the authority
 	attest, err := VerifySession(ctx, dlg, prfs, cctx)
@@ -400,11 +425,13 @@ func VerifyAuthorization(ctx context.Context, dlg delegation.Delegation, prfs []
 			return nil, nil, NewUnverifiableSignatureError(dlg, perr)
 		}
 
-		dlg, invalid := VerifySignature(dlg, wvfr)
+		dlg, invalid := verifyCachedSignature(ctx, dlg, wvfr, cctx)
 		return dlg, nil, invalid
 	}
 
-	return dlg, ConvertUnknownAuthorization(attest), nil
+	auth := ConvertUnknownAuthorization(attest)
+	cacheSession(ctx, dlg, auth, cctx)
+	return dlg, auth, nil
 }
 
 // VerifySignature verifies the delegation was signed by the passed verifier.
@@ -425,6 +452,12 @@ func VerifySignature(dlg delegation.Delegation, vfr principal.Verifier) (delegat
 //
 // https://github.com/storacha-network/specs/blob/main/w3-session.md#authorization-session
 func VerifySession(ctx context.Context, dlg delegation.Delegation, prfs []delegation.Delegation, cctx ClaimContext) (Authorization[vdm.AttestationModel], Unauthorized) {
+	ctx, span := tracer.Start(ctx, "ucanto.validator.VerifySession", trace.WithAttributes(
+		issuerKey.String(dlg.Issuer().DID().String()),
+		delegationKey.String(dlg.Link().String()),
+	))
+	defer span.End()
+
 	// Recognize attestations from all authorized principals, not just authority
 	var withSchemas []schema.Reader[string, string]
 	for _, p := range cctx.AuthorityProofs() {
@@ -476,11 +509,35 @@ func VerifySession(ctx context.Context, dlg delegation.Delegation, prfs []delega
 		}
 	}
 
-	return Claim(ctx, attestation, aprfs, cctx)
+	auth, err := Claim(ctx, attestation, aprfs, cctx)
+	if err != nil {
+		recordFailure(span, err)
+		return nil, err
+	}
+	return auth, nil
 }
 
 // Authorize verifies whether any of the delegated proofs grant capability.
-func Authorize[Caveats any](ctx context.Context, match Match[Caveats], cctx ClaimContext) (Authorization[Caveats], InvalidClaim) {
+func Authorize[Caveats any](ctx context.Context, match Match[Caveats], cctx ClaimContext) (auth Authorization[Caveats], invalid InvalidClaim) {
+	ctx, span := tracer.Start(ctx, "ucanto.validator.Authorize", trace.WithAttributes(
+		abilityKey.String(match.Value().Can()),
+		delegationKey.String(match.Source()[0].Delegation().Link().String()),
+	))
+	defer func() {
+		if invalid != nil {
+			recordFailure(span, invalid)
+		}
+		span.End()
+	}()
+
+	depth := proofDepth(ctx) + 1
+	if limit := ProofLimitsFromContext(ctx).MaxDepth; limit > 0 && depth > limit {
+		dlg := match.Source()[0].Delegation()
+		invalidprf := []ProofError{NewProofError(dlg.Link(), NewProofDepthExceededError(dlg, limit))}
+		return nil, NewInvalidClaimError(match, nil, nil, invalidprf, nil)
+	}
+	ctx = withProofDepth(ctx, depth)
+
 	// load proofs from all delegations
 	sources, attestations, invalidprf := ResolveMatch(ctx, match, cctx)
 
@@ -549,6 +606,11 @@ func ResolveSources(ctx context.Context, source Source, cctx ClaimContext) (sour
 	dlg := source.Delegation()
 	var prfs []delegation.Delegation
 
+	if limit := ProofLimitsFromContext(ctx).MaxWidth; limit > 0 && len(dlg.Proofs()) > limit {
+		errors = append(errors, NewProofError(dlg.Link(), NewProofWidthExceededError(dlg, limit)))
+		return
+	}
+
 	br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(dlg.Blocks()))
 	if err != nil {
 		errors = append(errors, NewProofError(dlg.Link(), err))
@@ -0,0 +1,87 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/result/failure"
+)
+
+// ProofLimits bound the proof chains explored when authorizing an invocation.
+// Zero values mean no limit.
+type ProofLimits struct {
+	// MaxDepth is the maximum number of delegations in a proof chain, from the
+	// invocation to the root authority.
+	MaxDepth int
+	// MaxWidth is the maximum number of proofs of a single delegation (or the
+	// invocation) that are explored.
+	MaxWidth int
+}
+
+type proofLimitsKey struct{}
+
+type proofDepthKey struct{}
+
+// WithProofLimits returns a context that bounds the proof chains explored by
+// [Access] and [Claim] with the passed limits. Proof chains that exceed a limit
+// are not explored and fail with a [ProofLimitExceeded] error.
+func WithProofLimits(ctx context.Context, limits ProofLimits) context.Context {
+	return context.WithValue(ctx, proofLimitsKey{}, limits)
+}
+
+// ProofLimitsFromContext returns the proof limits of the passed context,
+// configured with [WithProofLimits].
+func ProofLimitsFromContext(ctx context.Context) ProofLimits {
+	limits, _ := ctx.Value(proofLimitsKey{}).(ProofLimits)
+	return limits
+}
+
+// proofDepth returns the depth of the proof chain being explored.
+func proofDepth(ctx context.Context) int {
+	depth, _ := ctx.Value(proofDepthKey{}).(int)
+	return depth
+}
+
+func withProofDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, proofDepthKey{}, depth)
+}
+
+// ProofLimitExceeded is the error produced when a proof chain exceeds the
+// limits configured with [WithProofLimits].
+type ProofLimitExceeded interface {
+	InvalidProof
+	Delegation() delegation.Delegation
+	isProofLimitExceeded()
+}
+
+type ProofLimitExceededError struct {
+	failure.NamedWithStackTrace
+	delegation delegation.Delegation
+	message    string
+}
+
+// NewProofDepthExceededError creates an error for a delegation whose proofs
+// were not explored because they are deeper than the passed limit.
+func NewProofDepthExceededError(delegation delegation.Delegation, limit int) ProofLimitExceeded {
+	msg := fmt.Sprintf("Proofs of %s exceed the maximum proof chain depth of %d", delegation.Link(), limit)
+	return ProofLimitExceededError{failure.NamedWithCurrentStackTrace("ProofLimitExceeded"), delegation, msg}
+}
+
+// NewProofWidthExceededError creates an error for a delegation whose proofs
+// were not explored because there are more than the passed limit.
+func NewProofWidthExceededError(delegation delegation.Delegation, limit int) ProofLimitExceeded {
+	msg := fmt.Sprintf("Delegation %s has %d proofs, exceeding the maximum of %d", delegation.Link(), len(delegation.Proofs()), limit)
+	return ProofLimitExceededError{failure.NamedWithCurrentStackTrace("ProofLimitExceeded"), delegation, msg}
+}
+
+func (plee ProofLimitExceededError) Delegation() delegation.Delegation {
+	return plee.delegation
+}
+
+func (plee ProofLimitExceededError) Error() string {
+	return plee.message
+}
+
+func (plee ProofLimitExceededError) isProofLimitExceeded() {}
+func (plee ProofLimitExceededError) isInvalidProof()       {}
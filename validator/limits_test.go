@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/testing/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofLimits(t *testing.T) {
+	alice2bob, err := storeAdd.Delegate(
+		fixtures.Alice,
+		fixtures.Bob,
+		fixtures.Alice.DID().String(),
+		storeAddCaveats{},
+	)
+	require.NoError(t, err)
+
+	bob2mallory, err := storeAdd.Delegate(
+		fixtures.Bob,
+		fixtures.Mallory,
+		fixtures.Alice.DID().String(),
+		storeAddCaveats{},
+		delegation.WithProof(delegation.FromDelegation(alice2bob)),
+	)
+	require.NoError(t, err)
+
+	bob2malloryLink, err := storeAdd.Delegate(
+		fixtures.Bob,
+		fixtures.Mallory,
+		fixtures.Alice.DID().String(),
+		storeAddCaveats{Link: testLink},
+		delegation.WithProof(delegation.FromDelegation(alice2bob)),
+	)
+	require.NoError(t, err)
+
+	inv, err := storeAdd.Invoke(
+		fixtures.Mallory,
+		fixtures.Service,
+		fixtures.Alice.DID().String(),
+		storeAddCaveats{Link: testLink},
+		delegation.WithProof(delegation.FromDelegation(bob2mallory), delegation.FromDelegation(bob2malloryLink)),
+	)
+	require.NoError(t, err)
+
+	vctx := NewValidationContext(
+		fixtures.Service.Verifier(),
+		storeAdd,
+		IsSelfIssued,
+		validateAuthOk,
+		ProofUnavailable,
+		parseEdPrincipal,
+		FailDIDKeyResolution,
+		NotExpiredNotTooEarly,
+	)
+
+	t.Run("within limits", func(t *testing.T) {
+		ctx := WithProofLimits(t.Context(), ProofLimits{MaxDepth: 2, MaxWidth: 2})
+		a, x := Access(ctx, inv, vctx)
+		require.NoError(t, x)
+		require.Equal(t, fixtures.Mallory.DID(), a.Issuer().DID())
+	})
+
+	t.Run("depth exceeded", func(t *testing.T) {
+		ctx := WithProofLimits(t.Context(), ProofLimits{MaxDepth: 1})
+		_, x := Access(ctx, inv, vctx)
+		require.Error(t, x)
+		require.Equal(t, "Unauthorized", x.Name())
+		require.Contains(t, x.Error(), "exceed the maximum proof chain depth of 1")
+	})
+
+	t.Run("width exceeded", func(t *testing.T) {
+		ctx := WithProofLimits(t.Context(), ProofLimits{MaxWidth: 1})
+		_, x := Access(ctx, inv, vctx)
+		require.Error(t, x)
+		require.Contains(t, x.Error(), "has 2 proofs, exceeding the maximum of 1")
+	})
+}
@@ -0,0 +1,24 @@
+package validator
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/storacha/go-ucanto/validator")
+
+const (
+	abilityKey    = attribute.Key("ucanto.ability")
+	issuerKey     = attribute.Key("ucanto.issuer")
+	delegationKey = attribute.Key("ucanto.delegation")
+	proofKey      = attribute.Key("ucanto.proof")
+)
+
+// recordFailure records a validation failure on the span and marks it as
+// failed.
+func recordFailure(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}